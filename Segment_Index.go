@@ -2,11 +2,19 @@ package mpd
 
 import (
 	"fmt"
+	"sort"
+	"time"
 )
 
 type SegmentIndex struct {
-	References          []*SegmentReference
-	TimestampCorrection int
+	References []*SegmentReference
+
+	/**
+	 * The offset which has been added to every SegmentReference's times.
+	 * @see SegmentIndex.Correct
+	 * @type {time.Duration}
+	 */
+	TimestampCorrection time.Duration
 }
 
 /**
@@ -14,14 +22,13 @@ type SegmentIndex struct {
  *
  * @param {!Array.<!SegmentReference>} references Sorted by time in
  *     ascending order with no gaps.
+ * @see SegmentIndex.Validate
  */
 func NewSegmentIndex(references []*SegmentReference) SegmentIndex {
 	return SegmentIndex{
 		References:          references,
 		TimestampCorrection: 0,
 	}
-
-	// assertCorrectReferences()
 }
 
 /**
@@ -36,15 +43,182 @@ func (segmentIndex SegmentIndex) Length() int {
 /**
  * Gets the last SegmentReference.
  *
- * @return {!SegmentReference} The last SegmentReference.
- * @throws {RangeError} when there are no SegmentReferences.
+ * @return {SegmentReference} The last SegmentReference, or nil if there are
+ *     no SegmentReferences.
  */
 func (segmentIndex SegmentIndex) Last() *SegmentReference {
 	if len(segmentIndex.References) == 0 {
-		fmt.Println("SegmentIndex: There is no last SegmentReference.")
-		// throw new RangeError('SegmentIndex: There is no last SegmentReference.')
+		// There is no last SegmentReference.
 		return nil
 	}
 
 	return segmentIndex.References[len(segmentIndex.References)-1]
 }
+
+/**
+ * Finds the first SegmentReference which ends after |t|, in O(log n).
+ *
+ * @param {time.Duration} t
+ * @return {number} The index of that SegmentReference, or Length() if there
+ *     is none.
+ * @private
+ */
+func (segmentIndex SegmentIndex) search(t time.Duration) int {
+	references := segmentIndex.References
+	return sort.Search(len(references), func(i int) bool {
+		return references[i].EndTime > t
+	})
+}
+
+/**
+ * Finds the SegmentReference which contains |t|.
+ *
+ * @param {time.Duration} t
+ * @return {SegmentReference} The SegmentReference, or nil if |t| is before
+ *     the first SegmentReference, after the last one or in a gap.
+ */
+func (segmentIndex SegmentIndex) Find(t time.Duration) *SegmentReference {
+	i := segmentIndex.search(t)
+	if i == len(segmentIndex.References) || segmentIndex.References[i].StartTime > t {
+		return nil
+	}
+
+	return segmentIndex.References[i]
+}
+
+/**
+ * Gets the SegmentReferences which overlap the interval [start, end). The
+ * returned slice shares the SegmentIndex's storage.
+ *
+ * @param {time.Duration} start
+ * @param {time.Duration} end
+ * @return {!Array.<!SegmentReference>}
+ */
+func (segmentIndex SegmentIndex) Between(start time.Duration, end time.Duration) []*SegmentReference {
+	references := segmentIndex.References
+	first := segmentIndex.search(start)
+	last := first + sort.Search(len(references)-first, func(i int) bool {
+		return references[first+i].StartTime >= end
+	})
+
+	return references[first:last]
+}
+
+/**
+ * Checks that the SegmentReferences are sorted by time in ascending order,
+ * with no gaps or overlaps, and that only the last one is unbounded.
+ *
+ * @return {error} The first problem found, or nil.
+ */
+func (segmentIndex SegmentIndex) Validate() error {
+	for i, reference := range segmentIndex.References {
+		if reference.EndTime <= reference.StartTime {
+			return fmt.Errorf("segment reference %d ends at %s, before it starts at %s", i, reference.EndTime, reference.StartTime)
+		}
+		if i == 0 {
+			continue
+		}
+
+		previous := segmentIndex.References[i-1]
+		if previous.EndTime == UNBOUNDED_END_TIME {
+			return fmt.Errorf("segment reference %d follows an unbounded segment reference", i)
+		}
+		if previous.EndTime != reference.StartTime {
+			return fmt.Errorf("segment reference %d starts at %s, but the previous one ends at %s", i, reference.StartTime, previous.EndTime)
+		}
+	}
+
+	return nil
+}
+
+/**
+ * Shifts every SegmentReference so that their times include
+ * |timestampCorrection|, instead of the current TimestampCorrection. The
+ * SegmentReferences are replaced, not modified, since they may be shared.
+ *
+ * @param {time.Duration} timestampCorrection
+ * @return {time.Duration} The amount the SegmentReferences were shifted by.
+ */
+func (segmentIndex *SegmentIndex) Correct(timestampCorrection time.Duration) time.Duration {
+	delta := timestampCorrection - segmentIndex.TimestampCorrection
+	if delta == 0 {
+		return 0
+	}
+
+	segmentIndex.References = shiftReferences(segmentIndex.References, delta)
+	segmentIndex.TimestampCorrection = timestampCorrection
+	return delta
+}
+
+/**
+ * Merges |newer|, a more recent SegmentIndex of the same stream, into this
+ * one. |newer| is first brought to this SegmentIndex's TimestampCorrection.
+ * Its SegmentReferences then replace those which start within the time it
+ * covers; the SegmentReferences before and after it are kept.
+ *
+ * @param {!SegmentIndex} newer
+ */
+func (segmentIndex *SegmentIndex) Merge(newer SegmentIndex) {
+	if len(newer.References) == 0 {
+		return
+	}
+
+	newReferences := newer.References
+	if delta := segmentIndex.TimestampCorrection - newer.TimestampCorrection; delta != 0 {
+		newReferences = shiftReferences(newReferences, delta)
+	}
+
+	firstStartTime := newReferences[0].StartTime
+	lastEndTime := newReferences[len(newReferences)-1].EndTime
+
+	references := make([]*SegmentReference, 0, len(segmentIndex.References)+len(newReferences))
+	for _, reference := range segmentIndex.References {
+		if reference.StartTime >= firstStartTime {
+			break
+		}
+		references = append(references, reference)
+	}
+	references = append(references, newReferences...)
+
+	if lastEndTime != UNBOUNDED_END_TIME {
+		for _, reference := range segmentIndex.References {
+			if reference.StartTime >= lastEndTime {
+				references = append(references, reference)
+			}
+		}
+	}
+
+	segmentIndex.References = references
+}
+
+/**
+ * Removes the SegmentReferences which end at or before |t|.
+ *
+ * @param {time.Duration} t
+ * @return {number} The number of SegmentReferences removed.
+ */
+func (segmentIndex *SegmentIndex) Evict(t time.Duration) int {
+	i := segmentIndex.search(t)
+	segmentIndex.References = segmentIndex.References[i:]
+	return i
+}
+
+/**
+ * @param {!Array.<!SegmentReference>} references
+ * @param {time.Duration} delta
+ * @return {!Array.<!SegmentReference>} Copies of |references| whose times are
+ *     moved by |delta|. Unbounded end times stay unbounded.
+ */
+func shiftReferences(references []*SegmentReference, delta time.Duration) []*SegmentReference {
+	shifted := make([]*SegmentReference, 0, len(references))
+	for _, reference := range references {
+		segmentReference := *reference
+		segmentReference.StartTime += delta
+		if segmentReference.EndTime != UNBOUNDED_END_TIME {
+			segmentReference.EndTime += delta
+		}
+		shifted = append(shifted, &segmentReference)
+	}
+
+	return shifted
+}
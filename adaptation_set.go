@@ -4,13 +4,19 @@ import (
 	"strings"
 
 	mapset "github.com/deckarep/golang-set"
-	"github.com/moovweb/gokogiri/xml"
 )
 
 type AdaptationSet struct {
 	/** @type {?string} */
 	Id string
 
+	/**
+	 * The remote AdaptationSet this AdaptationSet is a placeholder for, if it
+	 * has not been resolved.
+	 * @type {Xlink}
+	 */
+	Xlink *Xlink
+
 	/**
 	 * The language.
 	 * @type {?string}
@@ -41,11 +47,35 @@ type AdaptationSet struct {
 	/** @type {?string} */
 	Codecs string
 
-	/** @type {!bool}*/
+	/**
+	 * True if one of the Roles is "main".
+	 * @type {!bool}
+	 */
 	Main bool
 
-	/** @type {*BaseUrl} */
-	BaseUrl *BaseUrl
+	/** @type {!Array.<!Descriptor>} */
+	Roles []*Descriptor
+
+	/** @type {!Array.<!Descriptor>} */
+	Accessibilities []*Descriptor
+
+	/** @type {!Array.<!Descriptor>} */
+	Ratings []*Descriptor
+
+	/** @type {!Array.<!Descriptor>} */
+	Viewpoints []*Descriptor
+
+	/** @type {!Array.<!Descriptor>} */
+	EssentialProperties []*Descriptor
+
+	/** @type {!Array.<!Descriptor>} */
+	SupplementalProperties []*Descriptor
+
+	/**
+	 * The BaseURLs, in document order, or the parent's if there are none.
+	 * @type {!Array.<!BaseUrl>}
+	 */
+	BaseUrls []*BaseUrl
 
 	/** @type {SegmentBase} */
 	SegmentBase *SegmentBase
@@ -57,7 +87,10 @@ type AdaptationSet struct {
 	SegmentTemplate *SegmentTemplate
 
 	/** @type {!Array.<!ContentProtection>} */
-	// ContentProtections []ContentProtection
+	ContentProtections []*ContentProtection
+
+	/** @type {!Array.<!EventStream>} */
+	InbandEventStreams []*EventStream
 
 	/** @type {!Array.<!Representation>} */
 	Representations []*Representation
@@ -68,25 +101,46 @@ type AdaptationSet struct {
  * @param {!Period} parent The parent Period.
  * @param {!Node} elem The AdaptationSet XML element.
  */
-func (adaptationSet *AdaptationSet) Parse(parent Node, elem xml.Node) {
+func (adaptationSet *AdaptationSet) Parse(state *parseState, parent Node, elem element) error {
 	var err error
-	var p *Period = parent.(*Period)
 	var contentComponents []*ContentComponent
-	var role *Role
+	var child Node
 
-	ok := false
+	p, ok := parent.(*Period)
+	if !ok {
+		return state.errorf("an AdaptationSet must be a child of a Period")
+	}
 
-	children := parseChildren(adaptationSet, elem, ContentComponent_TAG_NAME)
+	children, err := parseChildren(state, adaptationSet, elem, ContentComponent_TAG_NAME)
+	if err != nil {
+		return err
+	}
 	for _, child := range children {
 		contentComponents = append(contentComponents, child.(*ContentComponent))
 	}
 
-	if role, ok = parseChild(adaptationSet, elem, Role_TAG_NAME).(*Role); ok == false {
-		role = nil
+	if adaptationSet.Roles, err = parseDescriptors(state, adaptationSet, elem, Role_TAG_NAME); err != nil {
+		return err
+	}
+	if adaptationSet.Accessibilities, err = parseDescriptors(state, adaptationSet, elem, Accessibility_TAG_NAME); err != nil {
+		return err
+	}
+	if adaptationSet.Ratings, err = parseDescriptors(state, adaptationSet, elem, Rating_TAG_NAME); err != nil {
+		return err
+	}
+	if adaptationSet.Viewpoints, err = parseDescriptors(state, adaptationSet, elem, Viewpoint_TAG_NAME); err != nil {
+		return err
+	}
+	if adaptationSet.EssentialProperties, err = parseDescriptors(state, adaptationSet, elem, EssentialProperty_TAG_NAME); err != nil {
+		return err
+	}
+	if adaptationSet.SupplementalProperties, err = parseDescriptors(state, adaptationSet, elem, SupplementalProperty_TAG_NAME); err != nil {
+		return err
 	}
 
 	// Parse attributes.
 	adaptationSet.Id, _ = parseAttrAsString(elem, "id")
+	adaptationSet.Xlink = parseXlink(elem)
 	adaptationSet.Width, _ = parseAttrAsPositiveInt(elem, "width")
 	adaptationSet.Height, _ = parseAttrAsPositiveInt(elem, "height")
 	adaptationSet.MimeType, _ = parseAttrAsString(elem, "mimeType")
@@ -113,18 +167,32 @@ func (adaptationSet *AdaptationSet) Parse(parent Node, elem xml.Node) {
 		adaptationSet.ContentType.Add(tmp)
 	}
 
-	adaptationSet.Main = (role != nil && role.Value == "main")
+	adaptationSet.Main = hasMainRole(adaptationSet.Roles)
 
 	// Normalize the language tag.
 	// TODO: normalize language.
 	// if (this.lang) this.lang = shaka.util.LanguageUtils.normalize(this.lang);
 
 	// Parse simple child elements.
-	if adaptationSet.BaseUrl, ok = parseChild(adaptationSet, elem, BaseUrl_TAG_NAME).(*BaseUrl); ok == false {
-		adaptationSet.BaseUrl = p.BaseUrl
+	if adaptationSet.BaseUrls, err = parseBaseUrls(state, adaptationSet, elem, p.BaseUrls); err != nil {
+		return err
+	}
+
+	if children, err = parseChildren(state, adaptationSet, elem, ContentProtection_TAG_NAME); err != nil {
+		return err
+	}
+	adaptationSet.ContentProtections = make([]*ContentProtection, len(children))
+	for i, child := range children {
+		adaptationSet.ContentProtections[i] = child.(*ContentProtection)
 	}
 
-	// adaptationSet.ContentProtections = parseChildren(adaptationSet, elem, ContentProtection_TAG_NAME)
+	if children, err = parseChildren(state, adaptationSet, elem, InbandEventStream_TAG_NAME); err != nil {
+		return err
+	}
+	adaptationSet.InbandEventStreams = make([]*EventStream, len(children))
+	for i, child := range children {
+		adaptationSet.InbandEventStreams[i] = child.(*EventStream)
+	}
 
 	if adaptationSet.ContentType.Contains("") && (len(adaptationSet.MimeType) != 0) {
 		// Infer contentType from mimeType. This must be done before parsing any
@@ -135,30 +203,44 @@ func (adaptationSet *AdaptationSet) Parse(parent Node, elem xml.Node) {
 
 	// Parse hierarchical children.
 	if p.SegmentBase != nil {
-		adaptationSet.SegmentBase = mergeChild(adaptationSet, elem, p.SegmentBase, SegmentBase_TAG_NAME).(*SegmentBase)
+		child, err = mergeChild(state, adaptationSet, elem, p.SegmentBase, SegmentBase_TAG_NAME)
 	} else {
-		if adaptationSet.SegmentBase, ok = parseChild(adaptationSet, elem, SegmentBase_TAG_NAME).(*SegmentBase); ok == false {
-			adaptationSet.SegmentBase = nil
-		}
+		child, err = parseChild(state, adaptationSet, elem, SegmentBase_TAG_NAME)
+	}
+	if err != nil {
+		return err
+	}
+	if adaptationSet.SegmentBase, ok = child.(*SegmentBase); ok == false {
+		adaptationSet.SegmentBase = nil
 	}
 
 	if p.SegmentList != nil {
-		adaptationSet.SegmentList = mergeChild(adaptationSet, elem, p.SegmentList, SegmentList_TAG_NAME).(*SegmentList)
+		child, err = mergeChild(state, adaptationSet, elem, p.SegmentList, SegmentList_TAG_NAME)
 	} else {
-		if adaptationSet.SegmentList, ok = parseChild(adaptationSet, elem, SegmentList_TAG_NAME).(*SegmentList); ok == false {
-			adaptationSet.SegmentList = nil
-		}
+		child, err = parseChild(state, adaptationSet, elem, SegmentList_TAG_NAME)
+	}
+	if err != nil {
+		return err
+	}
+	if adaptationSet.SegmentList, ok = child.(*SegmentList); ok == false {
+		adaptationSet.SegmentList = nil
 	}
 
 	if p.SegmentTemplate != nil {
-		adaptationSet.SegmentTemplate = mergeChild(adaptationSet, elem, p.SegmentTemplate, SegmentTemplate_TAG_NAME).(*SegmentTemplate)
+		child, err = mergeChild(state, adaptationSet, elem, p.SegmentTemplate, SegmentTemplate_TAG_NAME)
 	} else {
-		if adaptationSet.SegmentTemplate, ok = parseChild(adaptationSet, elem, SegmentTemplate_TAG_NAME).(*SegmentTemplate); ok == false {
-			adaptationSet.SegmentTemplate = nil
-		}
+		child, err = parseChild(state, adaptationSet, elem, SegmentTemplate_TAG_NAME)
+	}
+	if err != nil {
+		return err
+	}
+	if adaptationSet.SegmentTemplate, ok = child.(*SegmentTemplate); ok == false {
+		adaptationSet.SegmentTemplate = nil
 	}
 
-	children = parseChildren(adaptationSet, elem, Representation_TAG_NAME)
+	if children, err = parseChildren(state, adaptationSet, elem, Representation_TAG_NAME); err != nil {
+		return err
+	}
 	adaptationSet.Representations = make([]*Representation, len(children))
 	for i, child := range children {
 		adaptationSet.Representations[i] = child.(*Representation)
@@ -173,6 +255,8 @@ func (adaptationSet *AdaptationSet) Parse(parent Node, elem xml.Node) {
 			adaptationSet.ContentType.Add(strings.Split(adaptationSet.MimeType, "/")[0])
 		}
 	}
+
+	return nil
 }
 
 func NewAdaptationSet() Node {
@@ -1,11 +1,11 @@
 package mpd
 
-import (
-	"fmt"
-)
-
-func assert(exp bool) {
+/**
+ * Checks an internal invariant. A violated invariant is reported as a
+ * Diagnostic instead of being printed.
+ */
+func (reporter *diagnosticReporter) assert(exp bool, path string, message string) {
 	if !exp {
-		fmt.Println("Assert evaluated to False")
+		reporter.report(SEVERITY_ERROR, DIAGNOSTIC_ASSERTION_FAILED, path, "%s", message)
 	}
 }
@@ -1,16 +1,79 @@
 package mpd
 
 import (
-	"github.com/moovweb/gokogiri/xml"
+	"errors"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	/**
+	 * The namespace of the dvb:priority and dvb:weight attributes.
+	 * @const {string}
+	 */
+	DVB_NAMESPACE = "urn:dvb:dash:profile:dvb-dash:2014"
+
+	/**
+	 * The default dvb:priority and dvb:weight.
+	 * @const {number}
+	 */
+	DEFAULT_DVB_PRIORITY = 1
+	DEFAULT_DVB_WEIGHT   = 1
+
+	/**
+	 * The maximum number of BaseURLs an element may have once each is resolved
+	 * against its parent's. Every relative BaseURL is resolved against every
+	 * BaseURL of the parent, so without a limit the count grows multiplicatively
+	 * with each level of the MPD.
+	 * @const {number}
+	 */
+	MAX_BASE_URLS = 32
 )
 
 type BaseUrl struct {
 	/** @type {?string} */
 	Url string
+
+	/**
+	 * Identifies the CDN. BaseURLs with the same serviceLocation are served by
+	 * the same CDN, so a client should not fail over between them.
+	 * @type {?string}
+	 */
+	ServiceLocation string
+
+	/** @type {?string} */
+	ByteRange string
+
+	/**
+	 * How much earlier than announced by the MPD segments are available, or
+	 * math.MaxInt64 for "INF".
+	 * @type {time.Duration}
+	 */
+	AvailabilityTimeOffset time.Duration
+
+	/**
+	 * The dvb:priority. BaseURLs with a lower value are preferred.
+	 * @type {number}
+	 */
+	Priority int
+
+	/**
+	 * The dvb:weight, used to balance the load between BaseURLs of equal
+	 * priority.
+	 * @type {number}
+	 */
+	Weight int
 }
 
 func NewBaseUrl() Node {
-	return &BaseUrl{}
+	return &BaseUrl{
+		Priority: DEFAULT_DVB_PRIORITY,
+		Weight:   DEFAULT_DVB_WEIGHT,
+	}
 }
 
 /**
@@ -18,6 +81,206 @@ func NewBaseUrl() Node {
  * @param {*} parent The parent object.
  * @param {!Node} elem The BaseURL XML element.
  */
-func (baseUrl *BaseUrl) Parse(parent Node, elem xml.Node) {
+func (baseUrl *BaseUrl) Parse(state *parseState, parent Node, elem element) error {
 	baseUrl.Url, _ = getContents(elem)
+	baseUrl.Url = strings.TrimSpace(baseUrl.Url)
+	if _, err := url.Parse(baseUrl.Url); err != nil {
+		state.report(SEVERITY_WARNING, DIAGNOSTIC_INVALID_URL, "invalid BaseURL %q: %s", baseUrl.Url, err)
+	}
+
+	// Parse attributes.
+	baseUrl.ServiceLocation, _ = parseAttrAsString(elem, "serviceLocation")
+	baseUrl.ByteRange, _ = parseAttrAsString(elem, "byteRange")
+
+	if availabilityTimeOffset, err := parseAttrAsAvailabilityTimeOffset(elem); err == nil {
+		baseUrl.AvailabilityTimeOffset = availabilityTimeOffset
+	}
+
+	if value, ok := elem.AttributeNS(DVB_NAMESPACE, "priority"); ok {
+		if priority, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && priority >= 0 {
+			baseUrl.Priority = priority
+		}
+	}
+
+	if value, ok := elem.AttributeNS(DVB_NAMESPACE, "weight"); ok {
+		if weight, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && weight >= 0 {
+			baseUrl.Weight = weight
+		}
+	}
+
+	return nil
+}
+
+/**
+ * Parses an @availabilityTimeOffset attribute, a number of seconds or "INF".
+ * @param {!Node} elem The XML element.
+ * @return {time.Duration} The offset, or math.MaxInt64 for "INF".
+ */
+func parseAttrAsAvailabilityTimeOffset(elem element) (time.Duration, error) {
+	value, ok := elem.Attribute("availabilityTimeOffset")
+	if !ok {
+		return 0, errors.New("missing attribute")
+	}
+
+	value = strings.TrimSpace(value)
+	if value == "INF" {
+		return time.Duration(math.MaxInt64), nil
+	}
+
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	if seconds < 0 || seconds >= math.MaxInt64/float64(time.Second) {
+		return 0, errors.New("availabilityTimeOffset is out of range")
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+/**
+ * Parses the BaseURL children of an element, falling back to the parent's.
+ * Each BaseURL is resolved against the parent's, so the returned URLs are
+ * absolute whenever the MPD's location is.
+ * @see ISO/IEC 23009-1:2014 section 5.6.4
+ * @param {*} parent The parent MPD node object.
+ * @param {!Node} elem The parent XML element.
+ * @param {!Array.<!BaseUrl>} parentBaseUrls The BaseURLs of the parent.
+ * @return {!Array.<!BaseUrl>}
+ */
+func parseBaseUrls(state *parseState, parent Node, elem element, parentBaseUrls []*BaseUrl) ([]*BaseUrl, error) {
+	children, err := parseChildren(state, parent, elem, BaseUrl_TAG_NAME)
+	if err != nil {
+		return nil, err
+	}
+	if len(children) == 0 {
+		return parentBaseUrls, nil
+	}
+
+	baseUrls := make([]*BaseUrl, 0, Min(len(children)*Max(len(parentBaseUrls), 1), MAX_BASE_URLS))
+	for _, child := range children {
+		baseUrls = append(baseUrls, child.(*BaseUrl).resolve(parentBaseUrls)...)
+		if len(baseUrls) > MAX_BASE_URLS {
+			state.report(SEVERITY_WARNING, DIAGNOSTIC_TOO_MANY_BASE_URLS,
+				"the BaseURLs resolve to more than %d locations; only the first %d are used", MAX_BASE_URLS, MAX_BASE_URLS)
+			return baseUrls[:MAX_BASE_URLS], nil
+		}
+	}
+	return baseUrls, nil
+}
+
+/**
+ * Resolves a BaseURL against each of its parent's BaseURLs. An absolute
+ * BaseURL names its own location and is returned as is. A relative BaseURL
+ * yields one BaseURL per parent, served from the parent's location and so
+ * with the parent's serviceLocation, dvb:priority and dvb:weight.
+ * @param {!Array.<!BaseUrl>} parentBaseUrls
+ * @return {!Array.<!BaseUrl>}
+ */
+func (baseUrl *BaseUrl) resolve(parentBaseUrls []*BaseUrl) []*BaseUrl {
+	if len(parentBaseUrls) == 0 || isAbsoluteUrl(baseUrl.Url) {
+		return []*BaseUrl{baseUrl}
+	}
+
+	resolved := make([]*BaseUrl, 0, len(parentBaseUrls))
+	for _, parentBaseUrl := range parentBaseUrls {
+		clone := *baseUrl
+		clone.Url = resolveUrl(parentBaseUrl.Url, baseUrl.Url)
+		if clone.ServiceLocation == "" {
+			clone.ServiceLocation = parentBaseUrl.ServiceLocation
+		}
+		if clone.AvailabilityTimeOffset == 0 {
+			clone.AvailabilityTimeOffset = parentBaseUrl.AvailabilityTimeOffset
+		}
+		clone.Priority = parentBaseUrl.Priority
+		clone.Weight = parentBaseUrl.Weight
+		resolved = append(resolved, &clone)
+	}
+	return resolved
+}
+
+/**
+ * Orders BaseURLs by the DVB selection rules: a lower dvb:priority first and,
+ * within a priority, a higher dvb:weight first. BaseURLs which are equal in
+ * both keep their document order. A client which balances load picks among
+ * the BaseURLs of the first priority at random, in proportion to their
+ * weights.
+ * @see ETSI TS 103 285 section 10.8.2.1
+ * @param {!Array.<!BaseUrl>} baseUrls
+ * @return {!Array.<!BaseUrl>} A sorted copy of |baseUrls|.
+ */
+func SortBaseUrls(baseUrls []*BaseUrl) []*BaseUrl {
+	sorted := append([]*BaseUrl(nil), baseUrls...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Priority != sorted[j].Priority {
+			return sorted[i].Priority < sorted[j].Priority
+		}
+		return sorted[i].Weight > sorted[j].Weight
+	})
+	return sorted
+}
+
+/**
+ * Builds the candidate URLs of a resource, one per BaseURL, in DVB order.
+ * Duplicates, e.g., from an absolute |ref|, are dropped.
+ * @param {!Array.<!BaseUrl>} baseUrls
+ * @param {string} ref The resource's URL, relative to the BaseURLs.
+ * @return {!Array.<string>}
+ */
+func buildUrls(baseUrls []*BaseUrl, ref string) []string {
+	if len(baseUrls) == 0 {
+		return []string{ref}
+	}
+
+	urls := make([]string, 0, len(baseUrls))
+	seen := make(map[string]bool)
+	for _, baseUrl := range SortBaseUrls(baseUrls) {
+		resolved := resolveUrl(baseUrl.Url, ref)
+		if !seen[resolved] {
+			seen[resolved] = true
+			urls = append(urls, resolved)
+		}
+	}
+	return urls
+}
+
+/**
+ * Resolves |ref| against |base| as per RFC 3986. If either cannot be parsed
+ * they are concatenated instead. A relative |base|, e.g., when the MPD's
+ * location is a relative path, yields a relative result.
+ * @param {string} base
+ * @param {string} ref
+ * @return {string}
+ */
+func resolveUrl(base string, ref string) string {
+	baseUrl, err := url.Parse(base)
+	if err != nil {
+		return base + ref
+	}
+	refUrl, err := url.Parse(ref)
+	if err != nil {
+		return base + ref
+	}
+
+	// ResolveReference always returns a rooted path, so root a relative base
+	// and unroot the result.
+	relative := !baseUrl.IsAbs() && baseUrl.Host == "" && !strings.HasPrefix(baseUrl.Path, "/") &&
+		!refUrl.IsAbs() && refUrl.Host == "" && !strings.HasPrefix(refUrl.Path, "/")
+	if relative {
+		baseUrl.Path = "/" + baseUrl.Path
+	}
+
+	resolved := baseUrl.ResolveReference(refUrl).String()
+	if relative {
+		resolved = strings.TrimPrefix(resolved, "/")
+	}
+	return resolved
+}
+
+/**
+ * @param {string} ref
+ * @return {boolean} True if |ref| has a scheme.
+ */
+func isAbsoluteUrl(ref string) bool {
+	refUrl, err := url.Parse(ref)
+	return err == nil && refUrl.IsAbs()
 }
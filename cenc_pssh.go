@@ -1,16 +1,177 @@
 package mpd
 
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	/**
+	 * @const {string}
+	 */
+	PSSH_BOX_TYPE = "pssh"
+)
+
 type CencPssh struct {
 	/**
+	 * The PSSH box, decoded from base64.
 	 * @type {Uint8Array}
 	 * @expose
 	 */
-	PsshBox []uint
+	PsshBox []byte
 
 	/**
+	 * The parsed PSSH box, or null if it could not be parsed.
 	 * @type {Pssh}
 	 * @expose
 	 */
-	// TODO: define Pssh
-	// ParsedPssh Pssh
+	ParsedPssh *Pssh
+}
+
+/**
+ * Parses a "cenc:pssh" tag. A malformed PSSH box is reported but does not
+ * fail the MPD.
+ * @param {!ContentProtection} parent The parent ContentProtection.
+ * @param {!Node} elem The cenc:pssh XML element.
+ */
+func (cencPssh *CencPssh) Parse(state *parseState, parent Node, elem element) error {
+	contents, err := getContents(elem)
+	if err != nil {
+		state.report(SEVERITY_WARNING, DIAGNOSTIC_INVALID_PSSH, "cenc:pssh is empty")
+		return nil
+	}
+
+	// Whitespace within base64 content is allowed.
+	contents = strings.Join(strings.Fields(contents), "")
+
+	if cencPssh.PsshBox, err = base64.StdEncoding.DecodeString(contents); err != nil {
+		state.report(SEVERITY_WARNING, DIAGNOSTIC_INVALID_PSSH, "cenc:pssh is not valid base64: %s", err)
+		cencPssh.PsshBox = nil
+		return nil
+	}
+
+	if cencPssh.ParsedPssh, err = ParsePssh(cencPssh.PsshBox); err != nil {
+		state.report(SEVERITY_WARNING, DIAGNOSTIC_INVALID_PSSH, "%s", err)
+	}
+
+	return nil
+}
+
+func NewCencPssh() Node {
+	return &CencPssh{}
+}
+
+/**
+ * A Protection System Specific Header box.
+ * @see ISO/IEC 23001-7 section 8.1
+ */
+type Pssh struct {
+	/** @type {number} */
+	Version uint8
+
+	/** @type {number} */
+	Flags uint32
+
+	/**
+	 * The protection system's ID, formatted as a lower-case UUID, e.g.,
+	 * "edef8ba9-79d6-4ace-a3c8-27dcd51d21ed".
+	 * @type {string}
+	 */
+	SystemId string
+
+	/**
+	 * The key IDs, formatted as lower-case UUIDs. Only version 1 boxes list
+	 * key IDs.
+	 * @type {!Array.<string>}
+	 */
+	KeyIds []string
+
+	/**
+	 * The protection system specific data.
+	 * @type {Uint8Array}
+	 */
+	Data []byte
+}
+
+/**
+ * Gets the key system the PSSH box is for, e.g., KEY_SYSTEM_WIDEVINE.
+ * Returns false if the SystemID is not a known one.
+ */
+func (pssh *Pssh) KeySystem() (string, bool) {
+	return KeySystemForSystemId(pssh.SystemId)
+}
+
+/**
+ * Parses a single PSSH box, including its header.
+ * @param {Uint8Array} box
+ * @return {Pssh}
+ */
+func ParsePssh(box []byte) (*Pssh, error) {
+	// size (4) + type (4) + version (1) + flags (3) + SystemID (16)
+	if len(box) < 28 {
+		return nil, errors.New("PSSH box is truncated")
+	}
+
+	size := binary.BigEndian.Uint32(box[0:4])
+	if uint64(size) != uint64(len(box)) {
+		return nil, fmt.Errorf("PSSH box size is %d, but %d bytes are available", size, len(box))
+	}
+
+	if string(box[4:8]) != PSSH_BOX_TYPE {
+		return nil, fmt.Errorf("box type is %q, not %q", box[4:8], PSSH_BOX_TYPE)
+	}
+
+	pssh := &Pssh{
+		Version:  box[8],
+		Flags:    uint32(box[9])<<16 | uint32(box[10])<<8 | uint32(box[11]),
+		SystemId: formatUuid(box[12:28]),
+		KeyIds:   make([]string, 0),
+	}
+
+	if pssh.Version > 1 {
+		return nil, fmt.Errorf("PSSH box version %d is not supported", pssh.Version)
+	}
+
+	offset := 28
+
+	if pssh.Version == 1 {
+		if len(box) < offset+4 {
+			return nil, errors.New("PSSH box is truncated")
+		}
+		count := binary.BigEndian.Uint32(box[offset : offset+4])
+		offset += 4
+
+		if uint64(count)*16 > uint64(len(box)-offset) {
+			return nil, errors.New("PSSH box is truncated")
+		}
+		for i := uint32(0); i < count; i++ {
+			pssh.KeyIds = append(pssh.KeyIds, formatUuid(box[offset:offset+16]))
+			offset += 16
+		}
+	}
+
+	if len(box) < offset+4 {
+		return nil, errors.New("PSSH box is truncated")
+	}
+	dataSize := binary.BigEndian.Uint32(box[offset : offset+4])
+	offset += 4
+
+	if uint64(dataSize) != uint64(len(box)-offset) {
+		return nil, fmt.Errorf("PSSH data size is %d, but %d bytes are available", dataSize, len(box)-offset)
+	}
+	pssh.Data = box[offset:]
+
+	return pssh, nil
+}
+
+/**
+ * Formats 16 bytes as a lower-case UUID.
+ */
+func formatUuid(b []byte) string {
+	s := hex.EncodeToString(b)
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:32]
 }
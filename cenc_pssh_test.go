@@ -0,0 +1,135 @@
+package mpd
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+)
+
+func makePsshBox(version uint8, systemId []byte, keyIds [][]byte, data []byte) []byte {
+	box := make([]byte, 0)
+	box = append(box, 0, 0, 0, 0)
+	box = append(box, PSSH_BOX_TYPE...)
+	box = append(box, version, 0, 0, 0)
+	box = append(box, systemId...)
+	if version == 1 {
+		box = binary.BigEndian.AppendUint32(box, uint32(len(keyIds)))
+		for _, keyId := range keyIds {
+			box = append(box, keyId...)
+		}
+	}
+	box = binary.BigEndian.AppendUint32(box, uint32(len(data)))
+	box = append(box, data...)
+	binary.BigEndian.PutUint32(box[0:4], uint32(len(box)))
+	return box
+}
+
+func TestParsePssh(t *testing.T) {
+	widevine, _ := hex.DecodeString("edef8ba979d64acea3c827dcd51d21ed")
+	unknown, _ := hex.DecodeString("00112233445566778899aabbccddeeff")
+	keyId, _ := hex.DecodeString("10000000100010001000100000000001")
+
+	pssh, err := ParsePssh(makePsshBox(0, widevine, nil, []byte{1, 2, 3}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pssh.Version != 0 || pssh.SystemId != "edef8ba9-79d6-4ace-a3c8-27dcd51d21ed" || len(pssh.KeyIds) != 0 || len(pssh.Data) != 3 {
+		t.Errorf("unexpected version 0 PSSH box %+v", pssh)
+	}
+	if keySystem, ok := pssh.KeySystem(); !ok || keySystem != KEY_SYSTEM_WIDEVINE {
+		t.Errorf("expecting widevine, got %s", keySystem)
+	}
+
+	pssh, err = ParsePssh(makePsshBox(1, unknown, [][]byte{keyId}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pssh.KeyIds) != 1 || pssh.KeyIds[0] != "10000000-1000-1000-1000-100000000001" || len(pssh.Data) != 0 {
+		t.Errorf("unexpected version 1 PSSH box %+v", pssh)
+	}
+	if _, ok := pssh.KeySystem(); ok {
+		t.Errorf("expecting an unknown SystemID to have no key system")
+	}
+
+	box := makePsshBox(1, widevine, [][]byte{keyId}, []byte{1})
+	invalid := [][]byte{
+		box[:20],
+		box[:len(box)-1],
+		makePsshBox(2, widevine, nil, nil),
+		append([]byte{0, 0, 0, 28}, []byte("moov")...),
+	}
+	for _, b := range invalid {
+		if _, err := ParsePssh(b); err == nil {
+			t.Errorf("expecting an error for PSSH box %x", b)
+		}
+	}
+}
+
+func TestParseCencPssh(t *testing.T) {
+	playready, _ := hex.DecodeString("9a04f07998404286ab92e65be0885f95")
+	box := base64.StdEncoding.EncodeToString(makePsshBox(0, playready, nil, []byte("pro")))
+
+	content := `<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" xmlns:cenc="urn:mpeg:cenc:2013" type="static" mediaPresentationDuration="PT10S">
+  <Period>
+    <AdaptationSet mimeType="video/mp4">
+      <ContentProtection schemeIdUri="urn:mpeg:dash:mp4protection:2011" value="cenc"/>
+      <ContentProtection schemeIdUri="urn:uuid:9A04F079-9840-4286-AB92-E65BE0885F95">
+        <cenc:pssh>` + box[:10] + "\n        " + box[10:] + `</cenc:pssh>
+      </ContentProtection>
+      <ContentProtection schemeIdUri="urn:uuid:edef8ba9-79d6-4ace-a3c8-27dcd51d21ed">
+        <cenc:pssh>not base64!</cenc:pssh>
+      </ContentProtection>
+      <Representation id="1" bandwidth="100000"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+
+	mpd, diagnostics, err := ParseMpdBytes([]byte(content), "http://example.com/manifest.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contentProtections := mpd.Periods[0].AdaptationSets[0].ContentProtections
+	if contentProtections[0].Pssh != nil {
+		t.Errorf("expecting no cenc:pssh for mp4protection")
+	}
+	if keySystem, ok := contentProtections[0].KeySystem(); ok {
+		t.Errorf("expecting no key system for mp4protection, got %s", keySystem)
+	}
+
+	pssh := contentProtections[1].Pssh
+	if pssh == nil || pssh.ParsedPssh == nil || string(pssh.ParsedPssh.Data) != "pro" {
+		t.Fatalf("expecting the PlayReady PSSH box to be decoded, got %+v", pssh)
+	}
+	if keySystem, _ := contentProtections[1].KeySystem(); keySystem != KEY_SYSTEM_PLAYREADY {
+		t.Errorf("expecting playready, got %s", keySystem)
+	}
+
+	if pssh = contentProtections[2].Pssh; pssh == nil || pssh.PsshBox != nil || pssh.ParsedPssh != nil {
+		t.Errorf("expecting the invalid PSSH box to be dropped, got %+v", pssh)
+	}
+	if keySystem, _ := contentProtections[2].KeySystem(); keySystem != KEY_SYSTEM_WIDEVINE {
+		t.Errorf("expecting widevine from the schemeIdUri, got %s", keySystem)
+	}
+
+	found := false
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Code == DIAGNOSTIC_INVALID_PSSH {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expecting an %s diagnostic, got %v", DIAGNOSTIC_INVALID_PSSH, diagnostics)
+	}
+}
+
+func FuzzParsePssh(f *testing.F) {
+	systemId := make([]byte, 16)
+	f.Add(makePsshBox(0, systemId, nil, []byte{1, 2, 3}))
+	f.Add(makePsshBox(1, systemId, [][]byte{make([]byte, 16)}, nil))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ParsePssh(data)
+	})
+}
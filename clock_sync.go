@@ -0,0 +1,223 @@
+package mpd
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	/**
+	 * The UTCTiming@schemeIdUri values. The 2012 variants are accepted too.
+	 * @see ISO/IEC 23009-1:2014 section 5.8.5.7
+	 * @const {string}
+	 */
+	UTC_TIMING_HTTP_XSDATE = "urn:mpeg:dash:utc:http-xsdate:2014"
+	UTC_TIMING_HTTP_ISO    = "urn:mpeg:dash:utc:http-iso:2014"
+	UTC_TIMING_HTTP_HEAD   = "urn:mpeg:dash:utc:http-head:2014"
+	UTC_TIMING_DIRECT      = "urn:mpeg:dash:utc:direct:2014"
+	UTC_TIMING_NTP         = "urn:mpeg:dash:utc:ntp:2014"
+
+	/**
+	 * How long to wait for an NTP server if the context has no deadline.
+	 * @const {time.Duration}
+	 */
+	DEFAULT_NTP_TIMEOUT = 5 * time.Second
+)
+
+/**
+ * The start of the NTP timescale.
+ * @const {time.Time}
+ */
+var ntpEpoch = time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+/**
+ * A ClockSync aligns the local clock to the server's, as described by an MPD's
+ * UTCTiming elements.
+ */
+type ClockSync struct {
+	/**
+	 * Retrieves the time sources of the HTTP schemes.
+	 * @type {Fetcher}
+	 */
+	Fetcher Fetcher
+
+	/**
+	 * The local clock.
+	 * @type {function():time.Time}
+	 */
+	Now func() time.Time
+
+	/**
+	 * The difference between the server's clock and the local clock, as of
+	 * the last successful Synchronize.
+	 * @type {time.Duration}
+	 */
+	Offset time.Duration
+}
+
+/**
+ * Creates a ClockSync. If |fetcher| is nil an HttpFetcher is used.
+ */
+func NewClockSync(fetcher Fetcher) *ClockSync {
+	if fetcher == nil {
+		fetcher = NewHttpFetcher(nil)
+	}
+
+	return &ClockSync{
+		Fetcher: fetcher,
+		Now:     time.Now,
+		Offset:  0,
+	}
+}
+
+/**
+ * Evaluates |mpd|'s UTCTiming elements in order and sets Offset from the first
+ * one which succeeds. Relative URLs are resolved against the MPD's URL. Offset
+ * is left unchanged if none succeeds.
+ * @param {!Mpd} mpd
+ * @return {time.Duration} The new Offset.
+ */
+func (clockSync *ClockSync) Synchronize(ctx context.Context, mpd *Mpd) (time.Duration, error) {
+	if len(mpd.UtcTimings) == 0 {
+		return clockSync.Offset, errors.New("mpd has no UTCTiming")
+	}
+
+	errs := make([]string, 0, len(mpd.UtcTimings))
+	for _, utcTiming := range mpd.UtcTimings {
+		offset, err := clockSync.evaluate(ctx, mpd.Url, utcTiming)
+		if err == nil {
+			clockSync.Offset = offset
+			return offset, nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %s", utcTiming.SchemeIdUri, err))
+	}
+
+	return clockSync.Offset, fmt.Errorf("clock synchronization failed: %s", strings.Join(errs, "; "))
+}
+
+/**
+ * @param {string} mpdUrl
+ * @param {!Descriptor} utcTiming
+ * @return {time.Duration} The offset given by |utcTiming|.
+ */
+func (clockSync *ClockSync) evaluate(ctx context.Context, mpdUrl string, utcTiming *Descriptor) (time.Duration, error) {
+	scheme := strings.Replace(strings.TrimSpace(utcTiming.SchemeIdUri), ":2012", ":2014", 1)
+
+	if scheme == UTC_TIMING_DIRECT {
+		serverTime, err := ParseDateTime(utcTiming.Value)
+		if err != nil {
+			return 0, err
+		}
+		return serverTime.Sub(clockSync.Now()), nil
+	}
+
+	if scheme != UTC_TIMING_HTTP_XSDATE && scheme != UTC_TIMING_HTTP_ISO && scheme != UTC_TIMING_HTTP_HEAD && scheme != UTC_TIMING_NTP {
+		return 0, errors.New("unsupported scheme")
+	}
+
+	// The value is a whitespace separated list of sources, to try in order.
+	var err error = errors.New("no time source")
+	for _, source := range strings.Fields(utcTiming.Value) {
+		var serverTime time.Time
+
+		requestTime := clockSync.Now()
+		switch scheme {
+		case UTC_TIMING_NTP:
+			serverTime, err = clockSync.queryNtp(ctx, source)
+		case UTC_TIMING_HTTP_HEAD:
+			serverTime, err = clockSync.queryHttp(ctx, resolveUrl(mpdUrl, source), true)
+		default:
+			serverTime, err = clockSync.queryHttp(ctx, resolveUrl(mpdUrl, source), false)
+		}
+		responseTime := clockSync.Now()
+
+		if err == nil {
+			// Assume the server's time was taken half way through the round trip.
+			return serverTime.Sub(requestTime.Add(responseTime.Sub(requestTime) / 2)), nil
+		}
+	}
+
+	return 0, err
+}
+
+/**
+ * Retrieves the time from an HTTP time source. The http-head scheme's time is
+ * taken from the Date header of the response.
+ * @param {string} url
+ * @param {boolean} useDateHeader
+ * @return {time.Time}
+ */
+func (clockSync *ClockSync) queryHttp(ctx context.Context, url string, useDateHeader bool) (time.Time, error) {
+	res, err := clockSync.Fetcher.Fetch(ctx, url, nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if useDateHeader {
+		return http.ParseTime(res.Header.Get("Date"))
+	}
+
+	// xs:dateTime is a profile of ISO 8601, which covers the common forms.
+	value := strings.TrimSpace(string(res.Data))
+	if serverTime, err := ParseDateTime(value); err == nil {
+		return serverTime, nil
+	}
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+/**
+ * Retrieves the time from an NTP server with a single SNTP request.
+ * @see IETF RFC 4330
+ * @param {string} server A host, with an optional port.
+ * @return {time.Time}
+ */
+func (clockSync *ClockSync) queryNtp(ctx context.Context, server string) (time.Time, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "123")
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", server)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(DEFAULT_NTP_TIMEOUT)
+	}
+	conn.SetDeadline(deadline)
+
+	// LI = 0, VN = 4, Mode = 3 (client).
+	request := make([]byte, 48)
+	request[0] = 0x23
+	if _, err = conn.Write(request); err != nil {
+		return time.Time{}, err
+	}
+
+	response := make([]byte, 48)
+	n, err := conn.Read(response)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if n < 48 || response[0]&0x07 != 4 {
+		return time.Time{}, errors.New("invalid NTP response")
+	}
+
+	// The Transmit Timestamp, in seconds and fractions of a second since
+	// ntpEpoch.
+	seconds := binary.BigEndian.Uint32(response[40:])
+	fraction := binary.BigEndian.Uint32(response[44:])
+	if seconds == 0 {
+		return time.Time{}, errors.New("NTP server is not synchronized")
+	}
+
+	return ntpEpoch.Add(time.Duration(seconds)*time.Second + time.Duration((uint64(fraction)*uint64(time.Second))>>32)), nil
+}
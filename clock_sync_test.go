@@ -0,0 +1,76 @@
+package mpd
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClockSync(t *testing.T) {
+	localTime := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	serverTime := localTime.Add(time.Hour)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/xsdate":
+			w.Write([]byte(serverTime.Format("2006-01-02T15:04:05Z")))
+		case "/head":
+			w.Header().Set("Date", serverTime.Format(http.TimeFormat))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ntpServer, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ntpServer.Close()
+	go func() {
+		request := make([]byte, 48)
+		for {
+			_, addr, err := ntpServer.ReadFrom(request)
+			if err != nil {
+				return
+			}
+			response := make([]byte, 48)
+			response[0] = 0x24 // LI = 0, VN = 4, Mode = 4 (server).
+			binary.BigEndian.PutUint32(response[40:], uint32(serverTime.Sub(ntpEpoch)/time.Second))
+			ntpServer.WriteTo(response, addr)
+		}
+	}()
+
+	clockSync := NewClockSync(NewHttpFetcher(server.Client()))
+	clockSync.Now = func() time.Time { return localTime }
+
+	tests := []struct {
+		utcTimings []*Descriptor
+		offset     time.Duration
+		fails      bool
+	}{
+		{utcTimings: []*Descriptor{
+			{SchemeIdUri: "urn:example:unknown", Value: "x"},
+			{SchemeIdUri: UTC_TIMING_HTTP_XSDATE, Value: "missing " + server.URL + "/xsdate"},
+		}, offset: time.Hour},
+		{utcTimings: []*Descriptor{{SchemeIdUri: UTC_TIMING_HTTP_HEAD, Value: "/head"}}, offset: time.Hour},
+		{utcTimings: []*Descriptor{{SchemeIdUri: UTC_TIMING_DIRECT, Value: "2020-01-01T00:30:00Z"}}, offset: 30 * time.Minute},
+		{utcTimings: []*Descriptor{{SchemeIdUri: UTC_TIMING_NTP, Value: ntpServer.LocalAddr().String()}}, offset: time.Hour},
+		{utcTimings: []*Descriptor{{SchemeIdUri: UTC_TIMING_HTTP_ISO, Value: "/missing"}}, offset: time.Hour, fails: true},
+	}
+
+	for i, test := range tests {
+		mpd := &Mpd{Url: server.URL + "/manifest.mpd", UtcTimings: test.utcTimings}
+		offset, err := clockSync.Synchronize(context.Background(), mpd)
+		if (err != nil) != test.fails {
+			t.Errorf("test %d: unexpected error %v", i, err)
+		}
+		if offset != test.offset || clockSync.Offset != test.offset {
+			t.Errorf("test %d: expecting offset %s, got %s", i, test.offset, offset)
+		}
+	}
+}
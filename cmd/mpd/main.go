@@ -0,0 +1,633 @@
+/**
+ * Command mpd inspects, validates and mirrors MPEG-DASH presentations.
+ *
+ *   mpd dump [-manifest] [-json] <url|file>
+ *   mpd validate [-severity warning] [-sidx] [-init] <url|file>
+ *   mpd segments [-json] <url|file>
+ *   mpd download [-dir .] [-concurrency 4] [-retries 3] <url|file>
+ */
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vgunning/mpd"
+)
+
+const usage = `usage: mpd <command> [flags] <url|file>
+
+commands:
+  dump      print the MPD tree, or its ManifestInfo with -manifest
+  validate  report problems; exits with 1 if any is at least -severity
+  segments  list the segments of every representation
+  download  mirror the presentation into -dir
+
+Run "mpd <command> -h" for the flags of a command.
+`
+
+/**
+ * Exit codes.
+ * @const {number}
+ */
+const (
+	EXIT_OK       = 0
+	EXIT_PROBLEMS = 1
+	EXIT_USAGE    = 2
+)
+
+func main() {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+/**
+ * Runs a command.
+ * @param {!Array.<string>} args The command and its arguments.
+ * @return {number} The exit code.
+ */
+func run(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return EXIT_USAGE
+	}
+
+	command := &command{
+		ctx:    ctx,
+		stdout: stdout,
+		stderr: stderr,
+		flags:  flag.NewFlagSet("mpd "+args[0], flag.ContinueOnError),
+	}
+	command.flags.SetOutput(stderr)
+	command.flags.StringVar(&command.baseUrl, "base", "", "resolve relative URLs against this URL instead of the MPD's location")
+	command.flags.DurationVar(&command.timeout, "timeout", mpd.DEFAULT_FETCH_TIMEOUT, "the timeout of each HTTP request")
+
+	switch args[0] {
+	case "dump":
+		return command.dump(args[1:])
+	case "validate":
+		return command.validate(args[1:])
+	case "segments":
+		return command.segments(args[1:])
+	case "download":
+		return command.download(args[1:])
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return EXIT_OK
+	}
+
+	fmt.Fprintf(stderr, "mpd: unknown command %q\n\n%s", args[0], usage)
+	return EXIT_USAGE
+}
+
+/**
+ * The state shared by all commands.
+ */
+type command struct {
+	/** @type {context.Context} */
+	ctx context.Context
+
+	/** @type {io.Writer} */
+	stdout io.Writer
+
+	/** @type {io.Writer} */
+	stderr io.Writer
+
+	/** @type {*flag.FlagSet} */
+	flags *flag.FlagSet
+
+	/** @type {string} */
+	baseUrl string
+
+	/** @type {time.Duration} */
+	timeout time.Duration
+
+	/**
+	 * Reads file URLs from disk, and downloads any other URL.
+	 * @type {*localFetcher}
+	 */
+	fetcher *localFetcher
+}
+
+/**
+ * Parses the command's flags, which must be followed by exactly one URL or
+ * file.
+ * @return {string} The URL or file.
+ */
+func (command *command) parse(args []string) (string, bool) {
+	if err := command.flags.Parse(args); err != nil {
+		return "", false
+	}
+	if command.flags.NArg() != 1 {
+		fmt.Fprintf(command.stderr, "%s: expecting one URL or file\n", command.flags.Name())
+		command.flags.Usage()
+		return "", false
+	}
+
+	command.fetcher = &localFetcher{
+		httpFetcher: mpd.NewHttpFetcher(&http.Client{Timeout: command.timeout}),
+	}
+	return command.flags.Arg(0), true
+}
+
+/**
+ * Retrieves and parses an MPD from a URL or a file.
+ * @param {string} location
+ * @return {mpd.Mpd}
+ * @return {ArrayBuffer} The MPD as it was retrieved.
+ */
+func (command *command) load(location string) (*mpd.Mpd, []byte, []mpd.Diagnostic, error) {
+	if !strings.Contains(location, "://") {
+		path, err := filepath.Abs(location)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		location = (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+	}
+
+	// A remote MPD must not make the tool read local files, e.g., through a
+	// file URL in its BaseURL or xlink:href.
+	parsedUrl, err := url.Parse(location)
+	command.fetcher.allowFiles = err == nil && parsedUrl.Scheme == "file"
+
+	res, err := command.fetcher.Fetch(command.ctx, location, nil)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// As with MpdParser.ParseMpd, relative URLs are resolved against the URL
+	// the MPD was retrieved from, unless a base URL is given.
+	baseUrl := command.baseUrl
+	if baseUrl == "" {
+		baseUrl = res.Url
+	}
+	if baseUrl == "" {
+		baseUrl = location
+	}
+
+	mpdParser := mpd.NewMpdParser()
+	mpdParser.Fetcher = command.fetcher
+	m, diagnostics, err := mpdParser.ParseMpdBytes(res.Data, baseUrl)
+	return m, res.Data, diagnostics, err
+}
+
+/**
+ * Processes an MPD, against the server's clock if it is live.
+ * @param {!mpd.Mpd} m
+ * @return {mpd.MpdProcessor}
+ */
+func (command *command) process(m *mpd.Mpd) (*mpd.MpdProcessor, []mpd.Diagnostic) {
+	mpdProcessor := mpd.NewMpdProcessor()
+
+	if m.Type == "dynamic" && len(m.UtcTimings) > 0 {
+		clockSync := mpd.NewClockSync(command.fetcher)
+		if _, err := clockSync.Synchronize(command.ctx, m); err != nil {
+			fmt.Fprintf(command.stderr, "mpd: %s\n", err)
+		}
+		mpdProcessor.ClockOffset = clockSync.Offset
+	}
+
+	diagnostics := mpdProcessor.Process(m)
+	return &mpdProcessor, diagnostics
+}
+
+/**
+ * Reports an error which prevents the command from running.
+ * @return {number} The exit code.
+ */
+func (command *command) fail(err error) int {
+	fmt.Fprintf(command.stderr, "mpd: %s\n", err)
+	return EXIT_PROBLEMS
+}
+
+/**
+ * Prints the MPD tree, or the ManifestInfo.
+ */
+func (command *command) dump(args []string) int {
+	manifest := command.flags.Bool("manifest", false, "print the ManifestInfo instead of the MPD tree")
+	asJson := command.flags.Bool("json", false, "print JSON")
+	location, ok := command.parse(args)
+	if !ok {
+		return EXIT_USAGE
+	}
+
+	m, _, _, err := command.load(location)
+	if err != nil {
+		return command.fail(err)
+	}
+
+	if !*manifest {
+		if *asJson {
+			return command.printJson(m)
+		}
+		mpd.FprintMPD(command.stdout, m, 0)
+		return EXIT_OK
+	}
+
+	mpdProcessor, _ := command.process(m)
+	if *asJson {
+		return command.printJson(mpdProcessor.ManifestInfo)
+	}
+
+	manifestInfo := mpdProcessor.ManifestInfo
+	fmt.Fprintf(command.stdout, "ManifestInfo live=%t minBufferTime=%s\n", manifestInfo.Live, manifestInfo.MinBufferTime)
+	forEachStreamInfo(manifestInfo, func(i int, periodInfo mpd.PeriodInfo, j int, streamSetInfo mpd.StreamSetInfo, k int, streamInfo *mpd.StreamInfo) {
+		if j == 0 && k == 0 {
+			fmt.Fprintf(command.stdout, "%s start=%s duration=%s events=%d\n", name("Period", i, periodInfo.Id), periodInfo.Start, periodInfo.Duration, len(periodInfo.Events))
+		}
+		if k == 0 {
+			fmt.Fprintf(command.stdout, "  %s lang=%q drmSchemes=%d\n", name("AdaptationSet", j, streamSetInfo.Id), streamSetInfo.Lang, len(streamSetInfo.DrmSchemes))
+		}
+
+		segments := 0
+		if streamInfo.SegmentIndex != nil {
+			segments = streamInfo.SegmentIndex.Length()
+		}
+		fmt.Fprintf(command.stdout, "    %s mimeType=%q codecs=%q bandwidth=%d size=%dx%d segments=%d\n",
+			name("Representation", k, streamInfo.Id), streamInfo.MimeType, streamInfo.Codecs, streamInfo.Bandwidth, streamInfo.Width, streamInfo.Height, segments)
+	})
+	return EXIT_OK
+}
+
+/**
+ * Prints the problems found while parsing and processing the MPD and,
+ * optionally, while loading segment indexes and initialization segments.
+ */
+func (command *command) validate(args []string) int {
+	severity := command.flags.String("severity", "warning", "the least severe problem which fails validation: info, warning or error")
+	loadSegmentIndexes := command.flags.Bool("sidx", false, "also load the sidx boxes of SegmentBase representations")
+	loadInitInfos := command.flags.Bool("init", false, "also compare the initialization segments with the MPD")
+	location, ok := command.parse(args)
+	if !ok {
+		return EXIT_USAGE
+	}
+
+	minSeverity, err := parseSeverity(*severity)
+	if err != nil {
+		fmt.Fprintf(command.stderr, "mpd: %s\n", err)
+		return EXIT_USAGE
+	}
+
+	m, _, diagnostics, err := command.load(location)
+	if err == nil {
+		mpdProcessor, processDiagnostics := command.process(m)
+		diagnostics = append(diagnostics, processDiagnostics...)
+		if *loadSegmentIndexes {
+			diagnostics = append(diagnostics, mpdProcessor.LoadSegmentIndexes(command.ctx, command.fetcher)...)
+		}
+		if *loadInitInfos {
+			diagnostics = append(diagnostics, mpdProcessor.LoadInitInfos(command.ctx, command.fetcher)...)
+		}
+	} else if len(diagnostics) == 0 {
+		return command.fail(err)
+	}
+
+	problems := 0
+	for _, diagnostic := range diagnostics {
+		fmt.Fprintln(command.stdout, diagnostic)
+		if diagnostic.Severity >= minSeverity {
+			problems++
+		}
+	}
+
+	if problems > 0 {
+		fmt.Fprintf(command.stderr, "mpd: %d problem(s) at least as severe as %s\n", problems, minSeverity)
+		return EXIT_PROBLEMS
+	}
+	return EXIT_OK
+}
+
+/**
+ * @param {string} value
+ * @return {mpd.Severity}
+ */
+func parseSeverity(value string) (mpd.Severity, error) {
+	for _, severity := range []mpd.Severity{mpd.SEVERITY_INFO, mpd.SEVERITY_WARNING, mpd.SEVERITY_ERROR} {
+		if severity.String() == value {
+			return severity, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity %q", value)
+}
+
+/**
+ * A segment, as printed by the segments command.
+ */
+type segment struct {
+	/**
+	 * The start time, in seconds.
+	 * @type {number}
+	 */
+	Start float64 `json:"start"`
+
+	/**
+	 * The end time, in seconds, or nil if the segment continues to the end of
+	 * the stream.
+	 * @type {?number}
+	 */
+	End *float64 `json:"end"`
+
+	/** @type {number} */
+	StartByte int `json:"startByte"`
+
+	/**
+	 * The position of the last byte, inclusive, or -1 for the end of the file.
+	 * @type {number}
+	 */
+	EndByte int `json:"endByte"`
+
+	/** @type {!Array.<string>} */
+	Urls []string `json:"urls"`
+}
+
+/**
+ * A representation, as printed by the segments command.
+ */
+type representation struct {
+	/**
+	 * e.g., "Period[0]/AdaptationSet[1]/Representation[id=video]"
+	 * @type {string}
+	 */
+	Path string `json:"path"`
+
+	/** @type {string} */
+	MimeType string `json:"mimeType"`
+
+	/** @type {string} */
+	Codecs string `json:"codecs"`
+
+	/** @type {number} */
+	Bandwidth uint32 `json:"bandwidth"`
+
+	/** @type {segment} */
+	Initialization *segment `json:"initialization,omitempty"`
+
+	/** @type {!Array.<!segment>} */
+	Segments []segment `json:"segments"`
+}
+
+/**
+ * Lists the resolved URL, time and byte range of every segment, per
+ * representation.
+ */
+func (command *command) segments(args []string) int {
+	asJson := command.flags.Bool("json", false, "print JSON")
+	location, ok := command.parse(args)
+	if !ok {
+		return EXIT_USAGE
+	}
+
+	m, _, _, err := command.load(location)
+	if err != nil {
+		return command.fail(err)
+	}
+	mpdProcessor, _ := command.process(m)
+	for _, diagnostic := range mpdProcessor.LoadSegmentIndexes(command.ctx, command.fetcher) {
+		fmt.Fprintln(command.stderr, diagnostic)
+	}
+
+	representations := make([]representation, 0)
+	forEachStreamInfo(mpdProcessor.ManifestInfo, func(i int, periodInfo mpd.PeriodInfo, j int, streamSetInfo mpd.StreamSetInfo, k int, streamInfo *mpd.StreamInfo) {
+		r := representation{
+			Path:      strings.Join([]string{name("Period", i, periodInfo.Id), name("AdaptationSet", j, streamSetInfo.Id), name("Representation", k, streamInfo.Id)}, "/"),
+			MimeType:  streamInfo.MimeType,
+			Codecs:    streamInfo.Codecs,
+			Bandwidth: streamInfo.Bandwidth,
+			Segments:  make([]segment, 0),
+		}
+		if info := streamInfo.SegmentInitializationInfo; info != nil && len(info.Urls) > 0 {
+			r.Initialization = &segment{StartByte: info.StartByte, EndByte: info.EndByte, Urls: info.Urls}
+		}
+		if streamInfo.SegmentIndex != nil {
+			for _, reference := range streamInfo.SegmentIndex.References {
+				s := segment{
+					Start:     reference.StartTime.Seconds(),
+					StartByte: reference.StartByte,
+					EndByte:   reference.EndByte,
+					Urls:      reference.Urls,
+				}
+				if reference.EndTime != mpd.UNBOUNDED_END_TIME {
+					end := reference.EndTime.Seconds()
+					s.End = &end
+				}
+				r.Segments = append(r.Segments, s)
+			}
+		}
+		representations = append(representations, r)
+	})
+
+	if *asJson {
+		return command.printJson(representations)
+	}
+
+	for _, r := range representations {
+		fmt.Fprintf(command.stdout, "%s mimeType=%q codecs=%q bandwidth=%d\n", r.Path, r.MimeType, r.Codecs, r.Bandwidth)
+		if r.Initialization != nil {
+			fmt.Fprintf(command.stdout, "  init%s %s\n", formatBytes(r.Initialization), strings.Join(r.Initialization.Urls, " "))
+		}
+		for _, s := range r.Segments {
+			end := "end"
+			if s.End != nil {
+				end = fmt.Sprintf("%gs", *s.End)
+			}
+			fmt.Fprintf(command.stdout, "  %gs-%s%s %s\n", s.Start, end, formatBytes(&s), strings.Join(s.Urls, " "))
+		}
+	}
+	return EXIT_OK
+}
+
+/**
+ * @param {!segment} s
+ * @return {string} The segment's byte range, e.g., " bytes=0-99", or empty if
+ *     it is the whole file.
+ */
+func formatBytes(s *segment) string {
+	if s.EndByte >= 0 {
+		return fmt.Sprintf(" bytes=%d-%d", s.StartByte, s.EndByte)
+	}
+	if s.StartByte > 0 {
+		return fmt.Sprintf(" bytes=%d-", s.StartByte)
+	}
+	return ""
+}
+
+/**
+ * Downloads the MPD and the initialization segment and segments of every
+ * representation into a directory tree, one directory per representation.
+ */
+func (command *command) download(args []string) int {
+	dir := command.flags.String("dir", ".", "the directory to mirror the presentation into")
+	concurrency := command.flags.Int("concurrency", mpd.DEFAULT_DOWNLOAD_CONCURRENCY, "how many segments to download at once")
+	retries := command.flags.Int("retries", mpd.DEFAULT_DOWNLOAD_RETRIES, "how many times to retry a failed segment")
+	verbose := command.flags.Bool("v", false, "print each file as it is downloaded")
+	location, ok := command.parse(args)
+	if !ok {
+		return EXIT_USAGE
+	}
+
+	m, data, _, err := command.load(location)
+	if err != nil {
+		return command.fail(err)
+	}
+
+	// Save the MPD which was parsed, since a live one may have changed since.
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		return command.fail(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(*dir, "manifest.mpd"), data, 0644); err != nil {
+		return command.fail(err)
+	}
+
+	mpdProcessor, _ := command.process(m)
+	for _, diagnostic := range mpdProcessor.LoadSegmentIndexes(command.ctx, command.fetcher) {
+		fmt.Fprintln(command.stderr, diagnostic)
+	}
+
+	downloader := mpd.NewDownloader(command.fetcher)
+	downloader.Concurrency = *concurrency
+	downloader.Retries = *retries
+
+	exitCode := EXIT_OK
+	forEachStreamInfo(mpdProcessor.ManifestInfo, func(i int, periodInfo mpd.PeriodInfo, j int, streamSetInfo mpd.StreamSetInfo, k int, streamInfo *mpd.StreamInfo) {
+		if command.ctx.Err() != nil || streamInfo.SegmentIndex == nil {
+			return
+		}
+
+		streamDir := filepath.Join(*dir, fileName(name("Period", i, periodInfo.Id)), fileName(name("AdaptationSet", j, streamSetInfo.Id)), fileName(name("Representation", k, streamInfo.Id)))
+		var progress mpd.DownloadProgress
+		downloader.OnProgress = func(p mpd.DownloadProgress) {
+			progress = p
+			if *verbose {
+				fmt.Fprintf(command.stderr, "%d/%d %s\n", p.Completed, p.Total, p.File)
+			}
+		}
+
+		if err := downloader.Download(command.ctx, streamInfo, streamDir); err != nil {
+			fmt.Fprintf(command.stderr, "mpd: %s: %s\n", streamDir, err)
+			exitCode = EXIT_PROBLEMS
+			return
+		}
+		fmt.Fprintf(command.stdout, "%s: %d files, %d bytes downloaded\n", streamDir, progress.Total, progress.Bytes)
+	})
+
+	if command.ctx.Err() != nil {
+		return command.fail(command.ctx.Err())
+	}
+	return exitCode
+}
+
+/**
+ * Calls |callback| for each StreamInfo of |manifestInfo|, with the positions
+ * of its Period, AdaptationSet and Representation.
+ */
+func forEachStreamInfo(manifestInfo mpd.ManifestInfo, callback func(i int, periodInfo mpd.PeriodInfo, j int, streamSetInfo mpd.StreamSetInfo, k int, streamInfo *mpd.StreamInfo)) {
+	for i, periodInfo := range manifestInfo.PeriodInfos {
+		for j, streamSetInfo := range periodInfo.StreamSetInfos {
+			for k, streamInfo := range streamSetInfo.StreamInfos {
+				callback(i, periodInfo, j, streamSetInfo, k, streamInfo)
+			}
+		}
+	}
+}
+
+/**
+ * Names an element the way Diagnostic paths do, by its id if it has one.
+ * @return {string} e.g., "Representation[id=video]" or "Period[0]".
+ */
+func name(tagName string, index int, id string) string {
+	if id != "" {
+		return fmt.Sprintf("%s[id=%s]", tagName, id)
+	}
+	return fmt.Sprintf("%s[%d]", tagName, index)
+}
+
+/**
+ * @param {string} s
+ * @return {string} |s| with the characters which are unsafe in file names
+ *     replaced, e.g., "Representation[id=video]" becomes
+ *     "Representation-id-video".
+ */
+func fileName(s string) string {
+	return strings.Trim(strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' {
+			return r
+		}
+		return '-'
+	}, s), "-.")
+}
+
+/**
+ * Prints |v| as indented JSON.
+ * @return {number} The exit code.
+ */
+func (command *command) printJson(v interface{}) int {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return command.fail(err)
+	}
+	fmt.Fprintln(command.stdout, string(data))
+	return EXIT_OK
+}
+
+/**
+ * A Fetcher which reads file URLs from the local file system and fetches
+ * everything else over HTTP, so that local MPDs and the segments they refer
+ * to can be inspected.
+ */
+type localFetcher struct {
+	/** @type {mpd.Fetcher} */
+	httpFetcher mpd.Fetcher
+
+	/**
+	 * Whether file URLs are read. Only an MPD which was itself loaded from a
+	 * file may refer to files.
+	 * @type {boolean}
+	 */
+	allowFiles bool
+}
+
+func (localFetcher *localFetcher) Fetch(ctx context.Context, rawUrl string, byteRange *mpd.Range) (*mpd.FetchResponse, error) {
+	parsedUrl, err := url.Parse(rawUrl)
+	if err != nil || parsedUrl.Scheme != "file" {
+		return localFetcher.httpFetcher.Fetch(ctx, rawUrl, byteRange)
+	}
+	if !localFetcher.allowFiles {
+		return nil, fmt.Errorf("not reading %s: only an MPD loaded from a file may refer to files", rawUrl)
+	}
+
+	data, err := ioutil.ReadFile(filepath.FromSlash(parsedUrl.Path))
+	if err != nil {
+		return nil, err
+	}
+	if byteRange != nil {
+		if byteRange.Begin < 0 || byteRange.Begin > byteRange.End || byteRange.Begin >= len(data) {
+			return nil, errors.New("byte range is out of bounds")
+		}
+		data = data[byteRange.Begin:mpd.Min(byteRange.End+1, len(data))]
+	}
+
+	return &mpd.FetchResponse{
+		Url:    rawUrl,
+		Header: http.Header{},
+		Data:   data,
+	}, nil
+}
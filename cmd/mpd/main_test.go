@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testMpd = `<MPD type="static" mediaPresentationDuration="PT4S">
+  <Period>
+    <AdaptationSet mimeType="video/mp4">
+      <SegmentTemplate timescale="1000" duration="2000" initialization="init.mp4" media="$Number$.m4s"/>
+      <Representation id="video" bandwidth="1000000"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	valid := filepath.Join(dir, "valid.mpd")
+	invalid := filepath.Join(dir, "invalid.mpd")
+	ioutil.WriteFile(valid, []byte(testMpd), 0644)
+	ioutil.WriteFile(invalid, []byte("<MPD"), 0644)
+	for _, file := range []string{"init.mp4", "1.m4s", "2.m4s"} {
+		ioutil.WriteFile(filepath.Join(dir, file), []byte("local"), 0644)
+	}
+	fileDir := (&url.URL{Scheme: "file", Path: filepath.ToSlash(dir) + "/"}).String()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/manifest.mpd" {
+			w.Write([]byte(testMpd))
+			return
+		}
+		if r.URL.Path == "/remote.mpd" {
+			w.Write([]byte(strings.Replace(testMpd, "<Period>", "<BaseURL>"+fileDir+"</BaseURL><Period>", 1)))
+			return
+		}
+		w.Write([]byte(r.URL.Path))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		args     []string
+		exitCode int
+	}{
+		{[]string{}, EXIT_USAGE},
+		{[]string{"unknown"}, EXIT_USAGE},
+		{[]string{"validate"}, EXIT_USAGE},
+		{[]string{"validate", valid}, EXIT_OK},
+		{[]string{"validate", invalid}, EXIT_PROBLEMS},
+		{[]string{"validate", "-severity", "fatal", valid}, EXIT_USAGE},
+		{[]string{"dump", "-manifest", server.URL + "/manifest.mpd"}, EXIT_OK},
+	}
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		if exitCode := run(context.Background(), test.args, &stdout, &stderr); exitCode != test.exitCode {
+			t.Errorf("%v: expecting exit code %d, got %d: %s", test.args, test.exitCode, exitCode, stderr.String())
+		}
+	}
+
+	// Relative URLs of a local MPD are resolved against its location.
+	var stdout, stderr bytes.Buffer
+	if exitCode := run(context.Background(), []string{"segments", "-json", valid}, &stdout, &stderr); exitCode != EXIT_OK {
+		t.Fatalf("segments failed: %s", stderr.String())
+	}
+	var representations []representation
+	if err := json.Unmarshal(stdout.Bytes(), &representations); err != nil {
+		t.Fatal(err)
+	}
+	if len(representations) != 1 || len(representations[0].Segments) != 2 || representations[0].Segments[1].Start != 2 ||
+		!strings.HasPrefix(representations[0].Segments[1].Urls[0], "file://") || !strings.HasSuffix(representations[0].Segments[1].Urls[0], "/2.m4s") {
+		t.Errorf("unexpected segments %s", stdout.String())
+	}
+
+	mirror := filepath.Join(dir, "mirror")
+	if exitCode := run(context.Background(), []string{"download", "-dir", mirror, server.URL + "/manifest.mpd"}, &stdout, &stderr); exitCode != EXIT_OK {
+		t.Fatalf("download failed: %s", stderr.String())
+	}
+	for _, file := range []string{"manifest.mpd", "Period-0/AdaptationSet-0/Representation-id-video/init.mp4", "Period-0/AdaptationSet-0/Representation-id-video/segment-2000.m4s"} {
+		if _, err := os.Stat(filepath.Join(mirror, filepath.FromSlash(file))); err != nil {
+			t.Errorf("missing %s: %s", file, err)
+		}
+	}
+
+	// The MPD which was parsed is saved, not the base URL.
+	mirror = filepath.Join(dir, "based")
+	if exitCode := run(context.Background(), []string{"download", "-dir", mirror, "-base", server.URL + "/media/", server.URL + "/manifest.mpd"}, &stdout, &stderr); exitCode != EXIT_OK {
+		t.Fatalf("download failed: %s", stderr.String())
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(mirror, "manifest.mpd")); err != nil || string(data) != testMpd {
+		t.Errorf("unexpected manifest.mpd %q, %v", data, err)
+	}
+
+	// The text dump goes to the command's output.
+	stdout.Reset()
+	if exitCode := run(context.Background(), []string{"dump", valid}, &stdout, &stderr); exitCode != EXIT_OK {
+		t.Fatalf("dump failed: %s", stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "mpd.Period") {
+		t.Errorf("unexpected dump %q", stdout.String())
+	}
+
+	// A local MPD may refer to local files.
+	mirror = filepath.Join(dir, "local")
+	if exitCode := run(context.Background(), []string{"download", "-dir", mirror, valid}, &stdout, &stderr); exitCode != EXIT_OK {
+		t.Fatalf("download failed: %s", stderr.String())
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(mirror, "Period-0", "AdaptationSet-0", "Representation-id-video", "init.mp4")); err != nil || string(data) != "local" {
+		t.Errorf("unexpected init.mp4 %q, %v", data, err)
+	}
+
+	// A remote MPD may not.
+	mirror = filepath.Join(dir, "remote")
+	stderr.Reset()
+	if exitCode := run(context.Background(), []string{"download", "-dir", mirror, server.URL + "/remote.mpd"}, &stdout, &stderr); exitCode == EXIT_OK {
+		t.Errorf("download of file URLs from a remote MPD succeeded")
+	}
+	if _, err := os.Stat(filepath.Join(mirror, "Period-0", "AdaptationSet-0", "Representation-id-video", "init.mp4")); err == nil {
+		t.Errorf("init.mp4 was copied from a local file")
+	}
+}
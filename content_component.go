@@ -1,9 +1,5 @@
 package mpd
 
-import (
-	"github.com/moovweb/gokogiri/xml"
-)
-
 type ContentComponent struct {
 	/** @type {?string} */
 	Id string
@@ -28,7 +24,7 @@ type ContentComponent struct {
  * @param {!AdaptationSet} parent The parent AdaptationSet.
  * @param {!Node} elem The ContentComponent XML element.
  */
-func (contentComponent *ContentComponent) Parse(parent Node, elem xml.Node) {
+func (contentComponent *ContentComponent) Parse(state *parseState, parent Node, elem element) error {
 
 	// Parse attributes.
 	contentComponent.Id, _ = parseAttrAsString(elem, "id")
@@ -38,6 +34,8 @@ func (contentComponent *ContentComponent) Parse(parent Node, elem xml.Node) {
 	// Normalize the language tag.
 	// TODO: Normalize the language tag.
 	// if (this.lang) this.lang = shaka.util.LanguageUtils.normalize(this.lang);
+
+	return nil
 }
 
 func NewContentComponent() Node {
@@ -1,7 +1,15 @@
 package mpd
 
 import (
-// "github.com/moovweb/gokogiri/xml"
+	"strings"
+)
+
+const (
+	/**
+	 * The namespace of the cenc:default_KID attribute and the cenc:pssh element.
+	 * @const {string}
+	 */
+	CENC_NAMESPACE = "urn:mpeg:cenc:2013"
 )
 
 type ContentProtection struct {
@@ -18,16 +26,33 @@ type ContentProtection struct {
 	Value string
 
 	/**
-	 * @type {!Array.<!Node>}
+	 * The cenc:default_KID attribute, lower-cased, e.g.,
+	 * "10000000-1000-1000-1000-100000000001".
+	 * @type {?string}
+	 * @expose
+	 */
+	DefaultKid string
+
+	/**
+	 * The child elements, kept verbatim.
+	 * @type {!Array.<!RawElement>}
 	 * @expose
 	 */
-	Children []Node
+	Children []*RawElement
 
 	/**
+	 * The cenc:pssh child element, or null if there is none.
 	 * @type {CencPssh}
 	 * @expose
 	 */
-	Pssh CencPssh
+	Pssh *CencPssh
+
+	/**
+	 * The mspr:pro child element, or null if there is none.
+	 * @type {MsprPro}
+	 * @expose
+	 */
+	Pro *MsprPro
 }
 
 /**
@@ -35,21 +60,92 @@ type ContentProtection struct {
  * @param {*} parent The parent object.
  * @param {!Node} elem The ContentProtection XML element.
  */
-// func (contentProtection *ContentProtection) Parse(parent Node, elem xml.Node) {
+func (contentProtection *ContentProtection) Parse(state *parseState, parent Node, elem element) error {
+	ok := false
+
+	// Parse attributes.
+	contentProtection.SchemeIdUri, _ = parseAttrAsString(elem, "schemeIdUri")
+	contentProtection.Value, _ = parseAttrAsString(elem, "value")
 
-// 	// Parse attributes.
-// 	contentProtection.SchemeIdUri = parseAttrAsString(elem, "schemeIdUri")
-// 	contentProtection.Value = parseAttrAsString(elem, "value")
+	if defaultKid, ok := elem.AttributeNS(CENC_NAMESPACE, "default_KID"); ok {
+		contentProtection.DefaultKid = strings.ToLower(strings.TrimSpace(defaultKid))
+	}
 
-// 	// Parse simple child elements.
-// 	// TODO: Parse CencPssh
-// 	// contentProtection.Pssh = parseChild(contentProtection, elem, CencPssh_TAG_NAME)
+	// Parse simple child elements.
+	child, err := parseChild(state, contentProtection, elem, CencPssh_TAG_NAME)
+	if err != nil {
+		return err
+	}
+	if contentProtection.Pssh, ok = child.(*CencPssh); ok == false {
+		contentProtection.Pssh = nil
+	}
 
-// 	// NOTE: A given ContentProtection tag could contain anything, and a scheme
-// 	// could be application-specific.  Therefore we must capture whatever it
-// 	// contains, and let the application choose a scheme and map it to a key
-// 	// system.
+	if child, err = parseChild(state, contentProtection, elem, MsprPro_TAG_NAME); err != nil {
+		return err
+	}
+	if contentProtection.Pro, ok = child.(*MsprPro); ok == false {
+		contentProtection.Pro = nil
+	}
 
-// 	// TODO: Address line below.
-// 	// contentProtection.Children = Array.prototype.slice.call(elem.childNodes);
-// }
+	// NOTE: A given ContentProtection tag could contain anything, and a scheme
+	// could be application-specific.  Therefore we must capture whatever it
+	// contains, and let the application choose a scheme and map it to a key
+	// system.
+	contentProtection.Children = make([]*RawElement, 0, len(elem.Children()))
+	for _, child := range elem.Children() {
+		contentProtection.Children = append(contentProtection.Children, newRawElement(child))
+	}
+
+	return nil
+}
+
+/**
+ * Gets the DRM SystemID, either from a "urn:uuid:" schemeIdUri or from the
+ * cenc:pssh box. Returns false for schemes that are not specific to a
+ * protection system, e.g., "urn:mpeg:dash:mp4protection:2011".
+ */
+func (contentProtection *ContentProtection) SystemId() (string, bool) {
+	if systemId, ok := SystemIdForSchemeIdUri(contentProtection.SchemeIdUri); ok {
+		return systemId, true
+	}
+	if contentProtection.Pssh != nil && contentProtection.Pssh.ParsedPssh != nil {
+		return contentProtection.Pssh.ParsedPssh.SystemId, true
+	}
+	return "", false
+}
+
+/**
+ * Gets the key system, e.g., KEY_SYSTEM_WIDEVINE. Returns false if the
+ * ContentProtection is not for a known protection system.
+ */
+func (contentProtection *ContentProtection) KeySystem() (string, bool) {
+	systemId, ok := contentProtection.SystemId()
+	if !ok {
+		return "", false
+	}
+	return KeySystemForSystemId(systemId)
+}
+
+/**
+ * Gets the PlayReady Header, either from the mspr:pro element or from a
+ * PlayReady cenc:pssh box. Returns null if there is neither.
+ * @return {PlayReadyHeader}
+ */
+func (contentProtection *ContentProtection) PlayReadyHeader() *PlayReadyHeader {
+	if contentProtection.Pro != nil && contentProtection.Pro.Header != nil {
+		return contentProtection.Pro.Header
+	}
+
+	if contentProtection.Pssh == nil || contentProtection.Pssh.ParsedPssh == nil {
+		return nil
+	}
+	if keySystem, _ := contentProtection.Pssh.ParsedPssh.KeySystem(); keySystem != KEY_SYSTEM_PLAYREADY {
+		return nil
+	}
+	playReadyHeader, _ := ParsePlayReadyObject(contentProtection.Pssh.ParsedPssh.Data)
+	return playReadyHeader
+}
+
+func NewContentProtection() Node {
+	return &ContentProtection{}
+}
@@ -0,0 +1,83 @@
+package mpd
+
+/**
+ * A generic DASH descriptor, i.e., a Role, Accessibility, Rating, Viewpoint,
+ * EssentialProperty or SupplementalProperty element.
+ */
+type Descriptor struct {
+	/** @type {?string} */
+	SchemeIdUri string
+
+	/** @type {?string} */
+	Value string
+
+	/**
+	 * Descriptors with the same @id are equivalent, e.g., alternative
+	 * EssentialProperty schemes of which only one needs to be understood.
+	 * @type {?string}
+	 */
+	Id string
+
+	/**
+	 * The child elements, kept verbatim.
+	 * @type {!Array.<!RawElement>}
+	 */
+	Children []*RawElement
+}
+
+/**
+ * Parses a descriptor tag, e.g., "Role".
+ * @param {*} parent The parent object.
+ * @param {!Node} elem The descriptor XML element.
+ */
+func (descriptor *Descriptor) Parse(state *parseState, parent Node, elem element) error {
+	// Parse attributes.
+	descriptor.SchemeIdUri, _ = parseAttrAsString(elem, "schemeIdUri")
+	descriptor.Value, _ = parseAttrAsString(elem, "value")
+	descriptor.Id, _ = parseAttrAsString(elem, "id")
+
+	descriptor.Children = make([]*RawElement, 0, len(elem.Children()))
+	for _, child := range elem.Children() {
+		descriptor.Children = append(descriptor.Children, newRawElement(child))
+	}
+
+	return nil
+}
+
+func NewDescriptor() Node {
+	return &Descriptor{}
+}
+
+/**
+ * Parses every child XML element with the given descriptor tag name.
+ * @param {*} parent The parent MPD node object.
+ * @param {!Node} elem The parent XML element.
+ * @param {string} name The descriptor tag name, e.g., Role_TAG_NAME.
+ * @return {!Array.<!Descriptor>}
+ */
+func parseDescriptors(state *parseState, parent Node, elem element, name string) ([]*Descriptor, error) {
+	children, err := parseChildren(state, parent, elem, name)
+	if err != nil {
+		return nil, err
+	}
+
+	descriptors := make([]*Descriptor, len(children))
+	for i, child := range children {
+		descriptors[i] = child.(*Descriptor)
+	}
+	return descriptors, nil
+}
+
+/**
+ * Checks if any of the given Roles is "main".
+ * @param {!Array.<!Descriptor>} roles
+ * @return {boolean}
+ */
+func hasMainRole(roles []*Descriptor) bool {
+	for _, role := range roles {
+		if role.Value == "main" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,209 @@
+package mpd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+type Severity int
+
+const (
+	SEVERITY_INFO Severity = iota
+
+	SEVERITY_WARNING
+
+	SEVERITY_ERROR
+)
+
+func (severity Severity) String() string {
+	switch severity {
+	case SEVERITY_INFO:
+		return "info"
+	case SEVERITY_WARNING:
+		return "warning"
+	case SEVERITY_ERROR:
+		return "error"
+	default:
+		return fmt.Sprintf("severity(%d)", int(severity))
+	}
+}
+
+// Diagnostic codes ------------------------------------------------------------
+const (
+	DIAGNOSTIC_DOWNLOAD_FAILED = "download-failed"
+
+	DIAGNOSTIC_INVALID_XML = "invalid-xml"
+
+	DIAGNOSTIC_MISSING_ELEMENT = "missing-element"
+
+	DIAGNOSTIC_INVALID_ELEMENT = "invalid-element"
+
+	DIAGNOSTIC_DUPLICATE_ELEMENT = "duplicate-element"
+
+	DIAGNOSTIC_UNKNOWN_ELEMENT = "unknown-element"
+
+	DIAGNOSTIC_MISSING_SEGMENT_INFO = "missing-segment-info"
+
+	DIAGNOSTIC_MULTIPLE_SEGMENT_INFO = "multiple-segment-info"
+
+	DIAGNOSTIC_DURATION_MISMATCH = "duration-mismatch"
+
+	DIAGNOSTIC_UNKNOWN_DURATION = "unknown-duration"
+
+	DIAGNOSTIC_INCONSISTENT_MIME_TYPE = "inconsistent-mime-type"
+
+	DIAGNOSTIC_INVALID_SEGMENT_BASE = "invalid-segment-base"
+
+	DIAGNOSTIC_INVALID_SEGMENT_LIST = "invalid-segment-list"
+
+	DIAGNOSTIC_INVALID_SEGMENT_TEMPLATE = "invalid-segment-template"
+
+	DIAGNOSTIC_IGNORED_ATTRIBUTE = "ignored-attribute"
+
+	DIAGNOSTIC_TIMELINE_GAP = "timeline-gap"
+
+	DIAGNOSTIC_URL_TEMPLATE = "url-template"
+
+	DIAGNOSTIC_INVALID_URL = "invalid-url"
+
+	DIAGNOSTIC_XLINK_FAILED = "xlink-failed"
+
+	DIAGNOSTIC_SEGMENT_UNAVAILABLE = "segment-unavailable"
+
+	DIAGNOSTIC_INVALID_PSSH = "invalid-pssh"
+
+	DIAGNOSTIC_INVALID_PLAYREADY_OBJECT = "invalid-playready-object"
+
+	DIAGNOSTIC_UNSUPPORTED_ESSENTIAL_PROPERTY = "unsupported-essential-property"
+
+	DIAGNOSTIC_INVALID_SCTE35 = "invalid-scte35"
+
+	DIAGNOSTIC_INVALID_SIDX = "invalid-sidx"
+
+	DIAGNOSTIC_INVALID_INIT_SEGMENT = "invalid-init-segment"
+
+	DIAGNOSTIC_INIT_SEGMENT_MISMATCH = "init-segment-mismatch"
+
+	DIAGNOSTIC_TOO_MANY_SEGMENTS = "too-many-segments"
+
+	DIAGNOSTIC_TOO_MANY_BASE_URLS = "too-many-base-urls"
+
+	DIAGNOSTIC_ASSERTION_FAILED = "assertion-failed"
+)
+
+/**
+ * A problem found while parsing or processing an MPD.
+ */
+type Diagnostic struct {
+	/** @type {Severity} */
+	Severity Severity
+
+	/**
+	 * A stable, machine readable identifier, e.g., DIAGNOSTIC_TIMELINE_GAP.
+	 * @type {string}
+	 */
+	Code string
+
+	/** @type {string} */
+	Message string
+
+	/**
+	 * The offending node, e.g., "Period[0]/AdaptationSet[1]/Representation[id=700k]".
+	 * An empty path refers to the MPD element itself.
+	 * @type {string}
+	 */
+	Path string
+}
+
+func (diagnostic Diagnostic) String() string {
+	if diagnostic.Path == "" {
+		return fmt.Sprintf("%s [%s] %s", diagnostic.Severity, diagnostic.Code, diagnostic.Message)
+	}
+	return fmt.Sprintf("%s [%s] %s: %s", diagnostic.Severity, diagnostic.Code, diagnostic.Path, diagnostic.Message)
+}
+
+/**
+ * A Logger receives each Diagnostic as soon as it is reported. The package
+ * never writes diagnostics anywhere unless a Logger is supplied.
+ */
+type Logger interface {
+	Log(diagnostic Diagnostic)
+}
+
+/**
+ * Adapts an ordinary function to the Logger interface.
+ */
+type LoggerFunc func(diagnostic Diagnostic)
+
+func (loggerFunc LoggerFunc) Log(diagnostic Diagnostic) {
+	loggerFunc(diagnostic)
+}
+
+/**
+ * Creates a Logger which writes one line per Diagnostic to |w|, skipping
+ * diagnostics less severe than |minSeverity|.
+ */
+func NewWriterLogger(w io.Writer, minSeverity Severity) Logger {
+	return LoggerFunc(func(diagnostic Diagnostic) {
+		if diagnostic.Severity >= minSeverity {
+			fmt.Fprintln(w, diagnostic.String())
+		}
+	})
+}
+
+/**
+ * Collects diagnostics and forwards them to an optional Logger.
+ */
+type diagnosticReporter struct {
+	logger Logger
+
+	diagnostics []Diagnostic
+}
+
+func newDiagnosticReporter(logger Logger) *diagnosticReporter {
+	return &diagnosticReporter{
+		logger:      logger,
+		diagnostics: make([]Diagnostic, 0),
+	}
+}
+
+func (reporter *diagnosticReporter) report(severity Severity, code string, path string, format string, args ...interface{}) {
+	diagnostic := Diagnostic{
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+		Path:     path,
+	}
+
+	reporter.diagnostics = append(reporter.diagnostics, diagnostic)
+
+	if reporter.logger != nil {
+		reporter.logger.Log(diagnostic)
+	}
+}
+
+/**
+ * Joins path segments, e.g., "Period[0]" and "AdaptationSet[1]", into a
+ * Diagnostic path.
+ */
+func joinPath(segments ...string) string {
+	nonEmpty := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		if segment != "" {
+			nonEmpty = append(nonEmpty, segment)
+		}
+	}
+	return strings.Join(nonEmpty, "/")
+}
+
+/**
+ * Formats a path segment for the |index|th element named |name|. Elements
+ * with an id are identified by it instead of by their position.
+ */
+func pathSegment(name string, index int, id string) string {
+	if id != "" {
+		return fmt.Sprintf("%s[id=%s]", name, id)
+	}
+	return fmt.Sprintf("%s[%d]", name, index)
+}
@@ -0,0 +1,352 @@
+package mpd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	/**
+	 * How many segments a new Downloader fetches at once.
+	 * @const {number}
+	 */
+	DEFAULT_DOWNLOAD_CONCURRENCY = 4
+
+	/**
+	 * How many times a new Downloader retries a failed segment.
+	 * @const {number}
+	 */
+	DEFAULT_DOWNLOAD_RETRIES = 3
+
+	/**
+	 * How long a new Downloader waits before the first retry. The delay
+	 * doubles with each retry, up to MAX_DOWNLOAD_RETRY_DELAY.
+	 * @const {time.Duration}
+	 */
+	DEFAULT_DOWNLOAD_RETRY_DELAY = 500 * time.Millisecond
+
+	/**
+	 * @const {time.Duration}
+	 */
+	MAX_DOWNLOAD_RETRY_DELAY = 30 * time.Second
+
+	/**
+	 * The suffix of the files segments are written to until they are complete.
+	 * @const {string}
+	 */
+	PARTIAL_DOWNLOAD_SUFFIX = ".part"
+)
+
+/**
+ * Reported by a Downloader each time a file is complete.
+ */
+type DownloadProgress struct {
+	/**
+	 * The path of the file which was completed.
+	 * @type {string}
+	 */
+	File string
+
+	/**
+	 * True if the file was already there, from a previous download.
+	 * @type {boolean}
+	 */
+	Resumed bool
+
+	/**
+	 * How many of the Total files are complete.
+	 * @type {number}
+	 */
+	Completed int
+
+	/** @type {number} */
+	Total int
+
+	/**
+	 * How many bytes have been downloaded, excluding resumed files.
+	 * @type {number}
+	 */
+	Bytes int64
+}
+
+/**
+ * A Downloader fetches the initialization segment and every media segment of
+ * a stream into a directory.
+ */
+type Downloader struct {
+	/** @type {Fetcher} */
+	Fetcher Fetcher
+
+	/**
+	 * How many segments are fetched at once.
+	 * @type {number}
+	 */
+	Concurrency int
+
+	/**
+	 * How many times a failed segment is retried, with exponential backoff.
+	 * Each attempt tries every candidate URL of the segment.
+	 * @type {number}
+	 */
+	Retries int
+
+	/** @type {time.Duration} */
+	RetryDelay time.Duration
+
+	/**
+	 * Called, one call at a time, each time a file is complete.
+	 * @type {?function(DownloadProgress)}
+	 */
+	OnProgress func(DownloadProgress)
+}
+
+/**
+ * Creates a Downloader. If |fetcher| is nil an HttpFetcher is used.
+ */
+func NewDownloader(fetcher Fetcher) *Downloader {
+	if fetcher == nil {
+		fetcher = NewHttpFetcher(nil)
+	}
+
+	return &Downloader{
+		Fetcher:     fetcher,
+		Concurrency: DEFAULT_DOWNLOAD_CONCURRENCY,
+		Retries:     DEFAULT_DOWNLOAD_RETRIES,
+		RetryDelay:  DEFAULT_DOWNLOAD_RETRY_DELAY,
+		OnProgress:  nil,
+	}
+}
+
+/**
+ * A file to download.
+ */
+type downloadJob struct {
+	/** @type {string} */
+	file string
+
+	/** @type {!Array.<string>} */
+	urls []string
+
+	/** @type {number} */
+	startByte int
+
+	/**
+	 * The position of the last byte, inclusive, or -1 for the end of the
+	 * resource.
+	 * @type {number}
+	 */
+	endByte int
+
+	/**
+	 * The contents, if they have already been retrieved.
+	 * @type {ArrayBuffer}
+	 */
+	data []byte
+}
+
+/**
+ * Downloads the stream's initialization segment, if it has one, to
+ * "init<ext>", and each SegmentReference to "segment-<id><ext>", in |dir|.
+ * Files which are already in |dir| are kept, so an interrupted download can
+ * be resumed. Stops at the first segment which cannot be downloaded.
+ * @param {!StreamInfo} streamInfo
+ * @param {string} dir
+ */
+func (downloader *Downloader) Download(ctx context.Context, streamInfo *StreamInfo, dir string) error {
+	if streamInfo.SegmentIndex == nil {
+		return errors.New("stream has no SegmentIndex")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	jobs := make([]*downloadJob, 0, streamInfo.SegmentIndex.Length()+1)
+	if info := streamInfo.SegmentInitializationInfo; info != nil && len(info.Urls) > 0 {
+		jobs = append(jobs, &downloadJob{
+			file:      filepath.Join(dir, "init"+urlExtension(info.Urls[0])),
+			urls:      info.Urls,
+			startByte: info.StartByte,
+			endByte:   info.EndByte,
+			data:      streamInfo.SegmentInitializationData,
+		})
+	}
+	for _, reference := range streamInfo.SegmentIndex.References {
+		if len(reference.Urls) == 0 {
+			return fmt.Errorf("segment %d has no url", reference.Id)
+		}
+		jobs = append(jobs, &downloadJob{
+			file:      filepath.Join(dir, fmt.Sprintf("segment-%d%s", reference.Id, urlExtension(reference.Urls[0]))),
+			urls:      reference.Urls,
+			startByte: reference.StartByte,
+			endByte:   reference.EndByte,
+		})
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mutex sync.Mutex
+	var firstErr error
+	progress := DownloadProgress{Total: len(jobs)}
+
+	queue := make(chan *downloadJob)
+	var wg sync.WaitGroup
+	for i := 0; i < Max(1, downloader.Concurrency); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				resumed, size, err := downloader.download(ctx, job)
+
+				mutex.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+						cancel()
+					}
+				} else {
+					progress.File = job.file
+					progress.Resumed = resumed
+					progress.Completed++
+					progress.Bytes += size
+					if downloader.OnProgress != nil {
+						downloader.OnProgress(progress)
+					}
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, job := range jobs {
+		select {
+		case queue <- job:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+/**
+ * Downloads one file, unless it is already complete.
+ * @param {!downloadJob} job
+ * @return {boolean} True if the file was already complete.
+ * @return {number} How many bytes were downloaded.
+ */
+func (downloader *Downloader) download(ctx context.Context, job *downloadJob) (bool, int64, error) {
+	if info, err := os.Stat(job.file); err == nil {
+		if job.endByte < 0 || info.Size() == int64(job.endByte-job.startByte+1) {
+			return true, 0, nil
+		}
+	}
+
+	data := job.data
+	if data == nil {
+		var err error
+		if data, err = downloader.fetch(ctx, job); err != nil {
+			return false, 0, fmt.Errorf("failed to download %s: %s", filepath.Base(job.file), err)
+		}
+	}
+
+	// Write to a partial file first, so that an interrupted download is not
+	// mistaken for a complete one.
+	partialFile := job.file + PARTIAL_DOWNLOAD_SUFFIX
+	if err := ioutil.WriteFile(partialFile, data, 0644); err != nil {
+		return false, 0, err
+	}
+	if err := os.Rename(partialFile, job.file); err != nil {
+		return false, 0, err
+	}
+
+	return false, int64(len(data)), nil
+}
+
+/**
+ * Fetches a file's contents, retrying with exponential backoff.
+ * @param {!downloadJob} job
+ * @return {ArrayBuffer}
+ */
+func (downloader *Downloader) fetch(ctx context.Context, job *downloadJob) ([]byte, error) {
+	var byteRange *Range
+	if job.endByte >= 0 {
+		byteRange = newRange(job.startByte, job.endByte)
+	}
+
+	delay := downloader.RetryDelay
+	for attempt := 0; ; attempt++ {
+		res, err := fetchAny(ctx, downloader.Fetcher, job.urls, byteRange)
+		if err == nil {
+			data := res.Data
+			if byteRange == nil {
+				// The segment extends to the end of the resource.
+				if job.startByte > 0 {
+					data, err = sliceRange(data, newRange(job.startByte, len(data)-1))
+				}
+			} else if size := byteRange.End - byteRange.Begin + 1; len(data) > size {
+				// The Fetcher returned the entire resource.
+				data, err = sliceRange(data, byteRange)
+			} else if len(data) < size {
+				err = fmt.Errorf("expecting %d bytes, got %d", size, len(data))
+			}
+			if err != nil {
+				return nil, err
+			}
+			return data, nil
+		}
+
+		if attempt >= downloader.Retries || ctx.Err() != nil || !isRetryable(err) {
+			return nil, err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+		if delay *= 2; delay > MAX_DOWNLOAD_RETRY_DELAY {
+			delay = MAX_DOWNLOAD_RETRY_DELAY
+		}
+	}
+}
+
+/**
+ * @param {error} err
+ * @return {boolean} False if retrying cannot help, i.e., the server rejected
+ *     the request.
+ */
+func isRetryable(err error) bool {
+	if httpError, ok := err.(*HttpError); ok && httpError.StatusCode >= 400 && httpError.StatusCode < 500 {
+		return httpError.StatusCode == http.StatusRequestTimeout || httpError.StatusCode == http.StatusTooManyRequests
+	}
+	return true
+}
+
+/**
+ * @param {string} rawUrl
+ * @return {string} The extension of the URL's path, e.g., ".m4s", or empty.
+ */
+func urlExtension(rawUrl string) string {
+	if parsedUrl, err := url.Parse(rawUrl); err == nil {
+		return path.Ext(parsedUrl.Path)
+	}
+	return ""
+}
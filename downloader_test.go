@@ -0,0 +1,140 @@
+package mpd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDownloader(t *testing.T) {
+	var mutex sync.Mutex
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests[r.URL.Path]++
+		count := requests[r.URL.Path]
+		mutex.Unlock()
+
+		switch {
+		case r.URL.Path == "/3.m4s" && count == 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case r.URL.Path == "/missing/1.m4s":
+			http.NotFound(w, r)
+		case r.URL.Path == "/list.mp4":
+			http.ServeContent(w, r, "list.mp4", time.Time{}, strings.NewReader("0123456789"))
+		default:
+			w.Write([]byte(r.URL.Path))
+		}
+	}))
+	defer server.Close()
+
+	content := `<MPD type="static" mediaPresentationDuration="PT8S">
+  <Period>
+    <AdaptationSet mimeType="video/mp4">
+      <SegmentTemplate timescale="1000" duration="2000" initialization="init.mp4" media="$Number$.m4s"/>
+      <Representation id="video" bandwidth="1000000"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+	mpd, _, err := ParseMpdBytes([]byte(content), server.URL+"/manifest.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mpdProcessor := NewMpdProcessor()
+	mpdProcessor.Process(mpd)
+	streamInfo := mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[0].StreamInfos[0]
+	if streamInfo.SegmentIndex.Length() != 4 {
+		t.Fatalf("expecting 4 segment references, got %d", streamInfo.SegmentIndex.Length())
+	}
+
+	dir, err := ioutil.TempDir("", "downloader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The second segment is left over from a previous download.
+	resumedFile := filepath.Join(dir, fmt.Sprintf("segment-%d.m4s", streamInfo.SegmentIndex.References[1].Id))
+	if err := ioutil.WriteFile(resumedFile, []byte("/2.m4s"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	downloader := NewDownloader(NewHttpFetcher(server.Client()))
+	downloader.RetryDelay = time.Millisecond
+	progress := make([]DownloadProgress, 0)
+	downloader.OnProgress = func(p DownloadProgress) {
+		progress = append(progress, p)
+	}
+	if err := downloader.Download(context.Background(), streamInfo, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(progress) != 5 || progress[4].Completed != 5 || progress[4].Total != 5 {
+		t.Fatalf("unexpected progress %v", progress)
+	}
+	// The server's handlers run on other goroutines.
+	countRequests := func(path string) int {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return requests[path]
+	}
+	if countRequests("/2.m4s") != 0 || countRequests("/3.m4s") != 2 {
+		t.Errorf("expecting 0 and 2 requests, got %d and %d", countRequests("/2.m4s"), countRequests("/3.m4s"))
+	}
+	for i, reference := range streamInfo.SegmentIndex.References {
+		data, err := ioutil.ReadFile(filepath.Join(dir, fmt.Sprintf("segment-%d.m4s", reference.Id)))
+		if err != nil || string(data) != fmt.Sprintf("/%d.m4s", i+1) {
+			t.Errorf("segment %d: unexpected contents %q, %v", i, data, err)
+		}
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(dir, "init.mp4")); err != nil || string(data) != "/init.mp4" {
+		t.Errorf("init: unexpected contents %q, %v", data, err)
+	}
+
+	// Client errors are not retried.
+	streamInfo.SegmentIndex.References[0].Urls = []string{server.URL + "/missing/1.m4s"}
+	if err := downloader.Download(context.Background(), streamInfo, filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("expecting an error")
+	}
+	if countRequests("/missing/1.m4s") != 1 {
+		t.Errorf("expecting 1 request, got %d", countRequests("/missing/1.m4s"))
+	}
+
+	// A SegmentURL without a @mediaRange is the whole file.
+	content = `<MPD type="static" mediaPresentationDuration="PT4S">
+  <Period>
+    <AdaptationSet mimeType="video/mp4">
+      <Representation id="list" bandwidth="1000000">
+        <SegmentList timescale="1000" duration="2000">
+          <SegmentURL media="list1.m4s"/>
+          <SegmentURL media="list.mp4" mediaRange="2-5"/>
+        </SegmentList>
+      </Representation>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+	if mpd, _, err = ParseMpdBytes([]byte(content), server.URL+"/manifest.mpd"); err != nil {
+		t.Fatal(err)
+	}
+	mpdProcessor.Process(mpd)
+	streamInfo = mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[0].StreamInfos[0]
+	listDir := filepath.Join(dir, "list")
+	if err := downloader.Download(context.Background(), streamInfo, listDir); err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []string{"/list1.m4s", "2345"} {
+		reference := streamInfo.SegmentIndex.References[i]
+		data, err := ioutil.ReadFile(filepath.Join(listDir, fmt.Sprintf("segment-%d%s", reference.Id, urlExtension(reference.Urls[0]))))
+		if err != nil || string(data) != expected {
+			t.Errorf("list segment %d: expecting %q, got %q, %v", i, expected, data, err)
+		}
+	}
+}
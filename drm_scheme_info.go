@@ -0,0 +1,78 @@
+package mpd
+
+import (
+	"strings"
+)
+
+/**
+ * A DRM scheme, identified by its ContentProtection@schemeIdUri, which
+ * applies to every stream of a StreamSetInfo.
+ */
+type DrmSchemeInfo struct {
+	/**
+	 * The scheme's URI, lower-cased, e.g.,
+	 * "urn:uuid:edef8ba9-79d6-4ace-a3c8-27dcd51d21ed".
+	 * @type {string}
+	 */
+	SchemeIdUri string
+
+	/**
+	 * The key system, e.g., KEY_SYSTEM_WIDEVINE, or "" if the scheme is not
+	 * for a known protection system.
+	 * @type {string}
+	 */
+	KeySystem string
+
+	/**
+	 * The ContentProtection@value of the first stream which uses the scheme.
+	 * @type {string}
+	 */
+	Value string
+
+	/**
+	 * The distinct cenc:default_KID values of the streams, in order.
+	 * @type {!Array.<string>}
+	 */
+	DefaultKids []string
+
+	/**
+	 * The ContentProtection elements of the scheme, one per stream which
+	 * declares it.
+	 * @type {!Array.<!ContentProtection>}
+	 */
+	ContentProtections []*ContentProtection
+}
+
+func NewDrmSchemeInfo(schemeIdUri string) DrmSchemeInfo {
+	return DrmSchemeInfo{
+		SchemeIdUri:        strings.ToLower(schemeIdUri),
+		Value:              "",
+		DefaultKids:        make([]string, 0),
+		ContentProtections: make([]*ContentProtection, 0),
+	}
+}
+
+/**
+ * Adds a ContentProtection of this scheme.
+ *
+ * @param {ContentProtection} contentProtection
+ */
+func (drmSchemeInfo *DrmSchemeInfo) add(contentProtection *ContentProtection) {
+	if len(drmSchemeInfo.ContentProtections) == 0 {
+		drmSchemeInfo.Value = contentProtection.Value
+	}
+	if drmSchemeInfo.KeySystem == "" {
+		drmSchemeInfo.KeySystem, _ = contentProtection.KeySystem()
+	}
+	drmSchemeInfo.ContentProtections = append(drmSchemeInfo.ContentProtections, contentProtection)
+
+	if contentProtection.DefaultKid == "" {
+		return
+	}
+	for _, defaultKid := range drmSchemeInfo.DefaultKids {
+		if defaultKid == contentProtection.DefaultKid {
+			return
+		}
+	}
+	drmSchemeInfo.DefaultKids = append(drmSchemeInfo.DefaultKids, contentProtection.DefaultKid)
+}
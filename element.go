@@ -0,0 +1,120 @@
+package mpd
+
+/**
+ * An XML element, as seen by Node.Parse. The XML backend is selected at build
+ * time: encoding/xml by default, or libxml2 through gokogiri with the
+ * "gokogiri" build tag. Both produce the same tree of xmlElements.
+ */
+type element interface {
+	/**
+	 * The local name of the element, e.g., "MPD".
+	 */
+	Name() string
+
+	/**
+	 * The namespace URI of the element, or "" if it has none.
+	 */
+	Namespace() string
+
+	/**
+	 * Looks up an attribute by its local name. Attributes without a namespace
+	 * take precedence over namespaced attributes with the same local name.
+	 */
+	Attribute(name string) (string, bool)
+
+	/**
+	 * Looks up an attribute by namespace URI and local name.
+	 */
+	AttributeNS(namespace string, name string) (string, bool)
+
+	/**
+	 * All attributes, in document order. Namespace declarations are not
+	 * included.
+	 */
+	Attributes() []xmlAttribute
+
+	/**
+	 * The child elements, in document order. Text, comments and processing
+	 * instructions are not included.
+	 */
+	Children() []element
+
+	/**
+	 * The text the element starts with, i.e., the text before its first child
+	 * element. Returns false if the element does not start with text.
+	 */
+	Text() (string, bool)
+}
+
+type xmlAttribute struct {
+	namespace string
+
+	name string
+
+	value string
+}
+
+/**
+ * The in-memory element tree shared by the XML backends.
+ */
+type xmlElement struct {
+	name string
+
+	namespace string
+
+	attributes []xmlAttribute
+
+	children []element
+
+	/** @type {?string} */
+	text string
+
+	hasText bool
+}
+
+func (elem *xmlElement) Name() string {
+	return elem.name
+}
+
+func (elem *xmlElement) Namespace() string {
+	return elem.namespace
+}
+
+func (elem *xmlElement) Attribute(name string) (string, bool) {
+	value, found := "", false
+
+	for _, attribute := range elem.attributes {
+		if attribute.name != name {
+			continue
+		}
+		if attribute.namespace == "" {
+			return attribute.value, true
+		}
+		if !found {
+			value, found = attribute.value, true
+		}
+	}
+
+	return value, found
+}
+
+func (elem *xmlElement) AttributeNS(namespace string, name string) (string, bool) {
+	for _, attribute := range elem.attributes {
+		if attribute.namespace == namespace && attribute.name == name {
+			return attribute.value, true
+		}
+	}
+	return "", false
+}
+
+func (elem *xmlElement) Attributes() []xmlAttribute {
+	return elem.attributes
+}
+
+func (elem *xmlElement) Children() []element {
+	return elem.children
+}
+
+func (elem *xmlElement) Text() (string, bool) {
+	return elem.text, elem.hasText
+}
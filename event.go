@@ -0,0 +1,93 @@
+package mpd
+
+type Event struct {
+	/**
+	 * The start time of the Event, in the EventStream's timescale, relative to
+	 * the EventStream's presentationTimeOffset.
+	 * @type {number}
+	 */
+	PresentationTime uint64 // xs:unsignedLong
+
+	/**
+	 * The duration, in the EventStream's timescale, or ^uint64(0) if it is
+	 * unknown.
+	 * @type {?number}
+	 */
+	Duration uint64 // xs:unsignedLong
+
+	/** @type {?string} */
+	Id string
+
+	/**
+	 * The deprecated @messageData attribute.
+	 * @type {?string}
+	 */
+	MessageData string
+
+	/**
+	 * The @contentEncoding, i.e., "base64" if Text is base64 encoded.
+	 * @type {?string}
+	 */
+	ContentEncoding string
+
+	/**
+	 * The text the element starts with, e.g., a base64 encoded message.
+	 * @type {?string}
+	 */
+	Text string
+
+	/**
+	 * The child elements, kept verbatim, e.g., a scte35:SpliceInfoSection.
+	 * @type {!Array.<!RawElement>}
+	 */
+	Children []*RawElement
+
+	/**
+	 * The decoded SCTE-35 cue, or null if the EventStream's scheme is not an
+	 * SCTE-35 one or if the cue could not be decoded.
+	 * @type {SpliceInfoSection}
+	 */
+	SpliceInfoSection *SpliceInfoSection
+}
+
+/**
+ * Parses an "Event" tag.
+ * @param {!EventStream} parent The parent EventStream.
+ * @param {!Node} elem The Event XML element.
+ */
+func (event *Event) Parse(state *parseState, parent Node, elem element) error {
+	var err error
+
+	// Parse attributes.
+	if event.PresentationTime, err = parseAttrAsUnsignedLong(elem, "presentationTime"); err != nil {
+		event.PresentationTime = 0
+	}
+
+	if event.Duration, err = parseAttrAsUnsignedLong(elem, "duration"); err != nil {
+		event.Duration = ^uint64(0)
+	}
+
+	event.Id, _ = parseAttrAsString(elem, "id")
+	event.MessageData, _ = parseAttrAsString(elem, "messageData")
+	event.ContentEncoding, _ = parseAttrAsString(elem, "contentEncoding")
+
+	event.Text, _ = elem.Text()
+
+	event.Children = make([]*RawElement, 0, len(elem.Children()))
+	for _, child := range elem.Children() {
+		event.Children = append(event.Children, newRawElement(child))
+	}
+
+	// A malformed cue is reported but does not fail the MPD.
+	if eventStream, ok := parent.(*EventStream); ok && isScte35Scheme(eventStream.SchemeIdUri) {
+		if event.SpliceInfoSection, err = parseScte35Event(eventStream.SchemeIdUri, elem); err != nil {
+			state.report(SEVERITY_WARNING, DIAGNOSTIC_INVALID_SCTE35, "%s", err)
+		}
+	}
+
+	return nil
+}
+
+func NewEvent() Node {
+	return &Event{}
+}
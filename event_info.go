@@ -0,0 +1,65 @@
+package mpd
+
+import "time"
+
+/**
+ * An Event placed on the presentation timeline.
+ */
+type EventInfo struct {
+	/** @type {string} */
+	SchemeIdUri string
+
+	/** @type {string} */
+	Value string
+
+	/** @type {string} */
+	Id string
+
+	/**
+	 * The start time of the Event with respect to the media presentation
+	 * timeline, i.e., including the Period's start.
+	 * @type {time.Duration}
+	 */
+	StartTime time.Duration
+
+	/**
+	 * The duration, or -1 if it is unknown.
+	 * @type {time.Duration}
+	 */
+	Duration time.Duration
+
+	/**
+	 * The SCTE-35 splice time on the presentation timeline, or -1 if the
+	 * Event is not an SCTE-35 cue. The EventStream's presentationTimeOffset,
+	 * converted to SCTE35_TIMESCALE, is taken to be the PTS at the start of
+	 * the Period, and the cue's own splice time is mapped from it. Cues
+	 * without a splice time are placed at StartTime.
+	 * @type {time.Duration}
+	 */
+	SpliceTime time.Duration
+
+	/**
+	 * The EventStream the Event belongs to.
+	 * @type {!EventStream}
+	 */
+	EventStream *EventStream
+
+	/**
+	 * The Event, which carries its message.
+	 * @type {!Event}
+	 */
+	Event *Event
+}
+
+func NewEventInfo() EventInfo {
+	return EventInfo{
+		SchemeIdUri: "",
+		Value:       "",
+		Id:          "",
+		StartTime:   0,
+		Duration:    -1,
+		SpliceTime:  -1,
+		EventStream: nil,
+		Event:       nil,
+	}
+}
@@ -0,0 +1,69 @@
+package mpd
+
+type EventStream struct {
+	/**
+	 * The remote EventStream this EventStream is a placeholder for, if it has
+	 * not been resolved.
+	 * @type {Xlink}
+	 */
+	Xlink *Xlink
+
+	/** @type {?string} */
+	SchemeIdUri string
+
+	/** @type {?string} */
+	Value string
+
+	/**
+	 * The timescale of the Events. This value is never zero.
+	 * @type {number}
+	 */
+	Timescale uint32 // xs:unsignedInt
+
+	/** @type {number} */
+	PresentationTimeOffset uint64 // xs:unsignedLong
+
+	/**
+	 * The Events, in document order. Always empty for an InbandEventStream,
+	 * whose events are carried in the media segments.
+	 * @type {!Array.<!Event>}
+	 */
+	Events []*Event
+}
+
+/**
+ * Parses an "EventStream" or an "InbandEventStream" tag.
+ * @param {*} parent The parent object.
+ * @param {!Node} elem The EventStream XML element.
+ */
+func (eventStream *EventStream) Parse(state *parseState, parent Node, elem element) error {
+	// Parse attributes.
+	eventStream.Xlink = parseXlink(elem)
+	eventStream.SchemeIdUri, _ = parseAttrAsString(elem, "schemeIdUri")
+	eventStream.Value, _ = parseAttrAsString(elem, "value")
+
+	if timescale, err := parseAttrAsUnsignedInt(elem, "timescale"); err == nil && timescale > 0 {
+		eventStream.Timescale = timescale
+	}
+
+	if presentationTimeOffset, err := parseAttrAsUnsignedLong(elem, "presentationTimeOffset"); err == nil {
+		eventStream.PresentationTimeOffset = presentationTimeOffset
+	}
+
+	children, err := parseChildren(state, eventStream, elem, Event_TAG_NAME)
+	if err != nil {
+		return err
+	}
+	eventStream.Events = make([]*Event, len(children))
+	for i, child := range children {
+		eventStream.Events[i] = child.(*Event)
+	}
+
+	return nil
+}
+
+func NewEventStream() Node {
+	return &EventStream{
+		Timescale: 1,
+	}
+}
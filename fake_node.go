@@ -1,14 +1,16 @@
 package mpd
 
-import (
-	"github.com/moovweb/gokogiri/xml"
-)
-
 type FakeNode struct {
-	/** @type {*BaseUrl} */
-	BaseUrl *BaseUrl
-}
+	/**
+	 * The MPD's own URL, after any redirects.
+	 * @type {string}
+	 */
+	Url string
 
-func (fakeNode FakeNode) Parse(parent Node, elem xml.Node) {
+	/** @type {!Array.<!BaseUrl>} */
+	BaseUrls []*BaseUrl
+}
 
+func (fakeNode FakeNode) Parse(state *parseState, parent Node, elem element) error {
+	return nil
 }
@@ -0,0 +1,202 @@
+package mpd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+const (
+	/**
+	 * The timeout of the http.Client used by the default Fetcher.
+	 * @const {number}
+	 */
+	DEFAULT_FETCH_TIMEOUT = 30 * time.Second
+)
+
+/**
+ * A Fetcher retrieves the resources an MPD refers to: the MPD itself, segment
+ * indexes, initialization segments and media segments.
+ */
+type Fetcher interface {
+	/**
+	 * Fetches |url|. If |byteRange| is non-nil only the given (inclusive) byte
+	 * range is requested.
+	 */
+	Fetch(ctx context.Context, url string, byteRange *Range) (*FetchResponse, error)
+}
+
+type FetchResponse struct {
+	/**
+	 * The URL the resource was actually retrieved from, i.e., after following
+	 * any redirects.
+	 * @type {string}
+	 */
+	Url string
+
+	/** @type {http.Header} */
+	Header http.Header
+
+	/** @type {ArrayBuffer} */
+	Data []byte
+}
+
+/**
+ * A RequestFilter may modify an outgoing request, e.g., to add authentication
+ * headers or cookies. Returning an error aborts the request.
+ */
+type RequestFilter func(req *http.Request) error
+
+/**
+ * A ResponseFilter may inspect an incoming response before its body is read.
+ * Returning an error aborts the request.
+ */
+type ResponseFilter func(res *http.Response) error
+
+/**
+ * HttpError is returned by HttpFetcher when the server responds with a non-2xx
+ * status code.
+ */
+type HttpError struct {
+	Url string
+
+	StatusCode int
+}
+
+func (httpError *HttpError) Error() string {
+	return fmt.Sprintf("failed to fetch %s: %d %s", httpError.Url, httpError.StatusCode, http.StatusText(httpError.StatusCode))
+}
+
+/**
+ * The default Fetcher, built on an http.Client.
+ */
+type HttpFetcher struct {
+	/** @type {*http.Client} */
+	Client *http.Client
+
+	/**
+	 * Applied, in order, to each request before it is sent.
+	 * @type {!Array.<RequestFilter>}
+	 */
+	RequestFilters []RequestFilter
+
+	/**
+	 * Applied, in order, to each response before its body is read.
+	 * @type {!Array.<ResponseFilter>}
+	 */
+	ResponseFilters []ResponseFilter
+}
+
+/**
+ * Creates an HttpFetcher. If |client| is nil an http.Client with
+ * DEFAULT_FETCH_TIMEOUT is used.
+ */
+func NewHttpFetcher(client *http.Client) *HttpFetcher {
+	if client == nil {
+		client = &http.Client{Timeout: DEFAULT_FETCH_TIMEOUT}
+	}
+
+	return &HttpFetcher{
+		Client:          client,
+		RequestFilters:  make([]RequestFilter, 0),
+		ResponseFilters: make([]ResponseFilter, 0),
+	}
+}
+
+func (httpFetcher *HttpFetcher) Fetch(ctx context.Context, url string, byteRange *Range) (*FetchResponse, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+
+	if byteRange != nil {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", byteRange.Begin, byteRange.End))
+	}
+
+	for _, filter := range httpFetcher.RequestFilters {
+		if err = filter(req); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := httpFetcher.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	for _, filter := range httpFetcher.ResponseFilters {
+		if err = filter(res); err != nil {
+			return nil, err
+		}
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, &HttpError{Url: url, StatusCode: res.StatusCode}
+	}
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// The server may ignore the Range header and return the entire resource.
+	if byteRange != nil && res.StatusCode == http.StatusOK {
+		if data, err = sliceRange(data, byteRange); err != nil {
+			return nil, fmt.Errorf("failed to fetch %s: %s", url, err)
+		}
+	}
+
+	return &FetchResponse{
+		Url:    res.Request.URL.String(),
+		Header: res.Header,
+		Data:   data,
+	}, nil
+}
+
+/**
+ * Fetches the first of |urls| which can be retrieved, e.g., the candidate
+ * URLs of a segment, one per BaseURL.
+ * @param {!Array.<string>} urls
+ * @param {Range} byteRange
+ * @return {FetchResponse}
+ */
+func fetchAny(ctx context.Context, fetcher Fetcher, urls []string, byteRange *Range) (*FetchResponse, error) {
+	if len(urls) == 0 {
+		return nil, errors.New("no url")
+	}
+
+	var err error
+	for _, url := range urls {
+		var res *FetchResponse
+		if res, err = fetcher.Fetch(ctx, url, byteRange); err == nil {
+			return res, nil
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return nil, err
+}
+
+/**
+ * Extracts |byteRange| from an entire resource. The range is clamped to the
+ * end of the resource, as a server would clamp it.
+ * @param {ArrayBuffer} data
+ * @param {!Range} byteRange
+ * @return {ArrayBuffer}
+ */
+func sliceRange(data []byte, byteRange *Range) ([]byte, error) {
+	if byteRange.Begin >= len(data) {
+		return nil, fmt.Errorf("range %d-%d starts after the end of the resource (%d bytes)", byteRange.Begin, byteRange.End, len(data))
+	}
+	if byteRange.Begin < 0 || byteRange.Begin > byteRange.End {
+		return nil, fmt.Errorf("invalid range %d-%d", byteRange.Begin, byteRange.End)
+	}
+	return data[byteRange.Begin:Min(byteRange.End+1, len(data))], nil
+}
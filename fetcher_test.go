@@ -0,0 +1,88 @@
+package mpd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHttpFetcher(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/segment", http.StatusFound)
+			return
+		}
+		if r.URL.Path == "/ignore-range" {
+			w.Write([]byte("0123456789"))
+			return
+		}
+		http.ServeContent(w, r, "segment", time.Time{}, strings.NewReader("0123456789"))
+	}))
+	defer server.Close()
+
+	fetcher := NewHttpFetcher(server.Client())
+
+	if _, err := fetcher.Fetch(context.Background(), server.URL+"/segment", nil); err == nil {
+		t.Error("expecting an unauthorized request to fail, got nil")
+	} else if httpErr, ok := err.(*HttpError); !ok || httpErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expecting an HttpError with status 401, got %v", err)
+	}
+
+	fetcher.RequestFilters = append(fetcher.RequestFilters, func(req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer secret")
+		return nil
+	})
+
+	res, err := fetcher.Fetch(context.Background(), server.URL+"/old", &Range{Begin: 2, End: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res.Data) != "2345" {
+		t.Errorf("expecting data to be 2345, got %s", res.Data)
+	}
+	if res.Url != server.URL+"/segment" {
+		t.Errorf("expecting final url to be %s, got %s", server.URL+"/segment", res.Url)
+	}
+
+	// A server which ignores the Range header returns the entire resource.
+	res, err = fetcher.Fetch(context.Background(), server.URL+"/ignore-range", &Range{Begin: 5, End: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res.Data) != "56789" {
+		t.Errorf("expecting data to be 56789, got %s", res.Data)
+	}
+	if _, err := fetcher.Fetch(context.Background(), server.URL+"/ignore-range", &Range{Begin: 10, End: 20}); err == nil {
+		t.Error("expecting a range past the end of the resource to fail, got nil")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := fetcher.Fetch(ctx, server.URL+"/segment", nil); err == nil {
+		t.Error("expecting a cancelled request to fail, got nil")
+	}
+}
+
+// testFetcher serves resources from memory.
+type testFetcher map[string][]byte
+
+func (fetcher testFetcher) Fetch(ctx context.Context, url string, byteRange *Range) (*FetchResponse, error) {
+	data, ok := fetcher[url]
+	if !ok {
+		return nil, &HttpError{Url: url, StatusCode: http.StatusNotFound}
+	}
+	if byteRange != nil {
+		var err error
+		if data, err = sliceRange(data, byteRange); err != nil {
+			return nil, err
+		}
+	}
+	return &FetchResponse{Url: url, Data: data}, nil
+}
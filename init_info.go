@@ -0,0 +1,628 @@
+package mpd
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/**
+ * What an initialization segment says about a stream.
+ */
+type InitInfo struct {
+	/**
+	 * The mvhd timescale.
+	 * @type {number}
+	 */
+	MovieTimescale uint32
+
+	/** @type {!Array.<!TrackInfo>} */
+	Tracks []*TrackInfo
+
+	/**
+	 * The pssh boxes of the moov box.
+	 * @type {!Array.<!Pssh>}
+	 */
+	Psshs []*Pssh
+}
+
+/**
+ * What an initialization segment says about one of its tracks.
+ */
+type TrackInfo struct {
+	/** @type {number} */
+	TrackId uint32
+
+	/**
+	 * The hdlr handler type, e.g., "vide", "soun", "subt" or "text".
+	 * @type {string}
+	 */
+	HandlerType string
+
+	/**
+	 * The mdhd timescale.
+	 * @type {number}
+	 */
+	Timescale uint32
+
+	/**
+	 * The stsd sample entry type, e.g., "avc1", "hvc1", "mp4a", "ec-3", "stpp"
+	 * or "wvtt". The original format of encrypted sample entries.
+	 * @type {string}
+	 */
+	SampleEntry string
+
+	/**
+	 * The RFC 6381 codecs string, e.g., "avc1.64001f" or "mp4a.40.2", if it
+	 * can be derived from the sample entry, or else the sample entry type.
+	 * @type {string}
+	 */
+	Codecs string
+
+	/**
+	 * The visual sample entry's size, or 0 for other tracks.
+	 * @type {number}
+	 */
+	Width int
+
+	/** @type {number} */
+	Height int
+
+	/**
+	 * The audio sample entry's channel count and sample rate, or 0 for other
+	 * tracks.
+	 * @type {number}
+	 */
+	ChannelCount int
+
+	/** @type {number} */
+	SampleRate int
+
+	/**
+	 * The schm scheme type, e.g., "cenc" or "cbcs", or empty if the track is
+	 * not encrypted.
+	 * @type {string}
+	 */
+	Scheme string
+
+	/**
+	 * The tenc default KID, formatted as a lower-case UUID, or empty if the
+	 * track is not encrypted.
+	 * @type {string}
+	 */
+	DefaultKid string
+}
+
+/**
+ * A difference between the manifest and the initialization segment.
+ */
+type InitMismatch struct {
+	/**
+	 * "codecs", "width", "height" or "default_KID".
+	 * @type {string}
+	 */
+	Field string
+
+	/** @type {string} */
+	Manifest string
+
+	/** @type {string} */
+	Init string
+}
+
+func (initMismatch InitMismatch) String() string {
+	return fmt.Sprintf("%s is %q in the manifest but %q in the initialization segment", initMismatch.Field, initMismatch.Manifest, initMismatch.Init)
+}
+
+/**
+ * Calls |callback| with the type, the whole box and the payload of each box
+ * in |data|, in order.
+ * @param {Uint8Array} data A sequence of boxes.
+ */
+func forEachBox(data []byte, callback func(boxType string, box []byte, payload []byte) error) error {
+	offset := 0
+	for offset < len(data) {
+		headerSize, size, err := parseBoxHeader(data[offset:])
+		if err != nil {
+			return err
+		}
+		if size > uint64(len(data)-offset) {
+			return fmt.Errorf("%q box is truncated", data[offset+4:offset+8])
+		}
+
+		box := data[offset : offset+int(size)]
+		if err := callback(string(box[4:8]), box, box[headerSize:]); err != nil {
+			return err
+		}
+		offset += int(size)
+	}
+
+	return nil
+}
+
+/**
+ * Reads the version of a full box and the field which follows the version,
+ * flags and, depending on the version, two 32 or 64 bit times, e.g., the
+ * timescale of an mvhd or mdhd box.
+ * @param {Uint8Array} payload The full box's payload.
+ * @return {number}
+ */
+func parseFullBoxField(payload []byte) (uint32, error) {
+	offset := 12
+	if len(payload) > 0 && payload[0] == 1 {
+		offset = 20
+	}
+	if len(payload) < offset+4 {
+		return 0, errors.New("box is truncated")
+	}
+	return binary.BigEndian.Uint32(payload[offset:]), nil
+}
+
+/**
+ * Parses the moov box of an initialization segment.
+ * @param {Uint8Array} data The initialization segment.
+ * @return {InitInfo}
+ */
+func ParseInitSegment(data []byte) (*InitInfo, error) {
+	_, moov, err := findBox(data, "moov")
+	if err != nil {
+		return nil, err
+	}
+
+	initInfo := &InitInfo{
+		Tracks: make([]*TrackInfo, 0),
+		Psshs:  make([]*Pssh, 0),
+	}
+
+	headerSize, _, _ := parseBoxHeader(moov)
+	err = forEachBox(moov[headerSize:], func(boxType string, box []byte, payload []byte) error {
+		switch boxType {
+		case "mvhd":
+			timescale, err := parseFullBoxField(payload)
+			if err != nil {
+				return fmt.Errorf("mvhd: %s", err)
+			}
+			initInfo.MovieTimescale = timescale
+		case "trak":
+			trackInfo := &TrackInfo{}
+			if err := trackInfo.parseBoxes(payload); err != nil {
+				return err
+			}
+			initInfo.Tracks = append(initInfo.Tracks, trackInfo)
+		case PSSH_BOX_TYPE:
+			pssh, err := ParsePssh(box)
+			if err != nil {
+				return err
+			}
+			initInfo.Psshs = append(initInfo.Psshs, pssh)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(initInfo.Tracks) == 0 {
+		return nil, errors.New("moov box has no trak box")
+	}
+	return initInfo, nil
+}
+
+/**
+ * Walks the boxes of a trak box, descending into the containers which lead
+ * to the sample description.
+ * @param {Uint8Array} data
+ */
+func (trackInfo *TrackInfo) parseBoxes(data []byte) error {
+	return forEachBox(data, func(boxType string, box []byte, payload []byte) error {
+		switch boxType {
+		case "mdia", "minf", "stbl":
+			return trackInfo.parseBoxes(payload)
+		case "tkhd":
+			// tkhd's track_ID is where mvhd's timescale is.
+			trackId, err := parseFullBoxField(payload)
+			if err != nil {
+				return fmt.Errorf("tkhd: %s", err)
+			}
+			trackInfo.TrackId = trackId
+		case "mdhd":
+			timescale, err := parseFullBoxField(payload)
+			if err != nil {
+				return fmt.Errorf("mdhd: %s", err)
+			}
+			trackInfo.Timescale = timescale
+		case "hdlr":
+			if len(payload) < 12 {
+				return errors.New("hdlr box is truncated")
+			}
+			trackInfo.HandlerType = string(payload[8:12])
+		case "stsd":
+			// version (1) + flags (3) + entry_count (4). Only the first sample
+			// entry is described.
+			if len(payload) < 8 {
+				return errors.New("stsd box is truncated")
+			}
+			if binary.BigEndian.Uint32(payload[4:]) == 0 {
+				return errors.New("stsd box has no sample entry")
+			}
+			return forEachBox(payload[8:], func(boxType string, box []byte, payload []byte) error {
+				if trackInfo.SampleEntry == "" {
+					return trackInfo.parseSampleEntry(boxType, payload)
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+}
+
+/**
+ * Parses a sample entry.
+ * @param {string} boxType
+ * @param {Uint8Array} payload
+ */
+func (trackInfo *TrackInfo) parseSampleEntry(boxType string, payload []byte) error {
+	trackInfo.SampleEntry = boxType
+	trackInfo.Codecs = boxType
+
+	// reserved (6) + data_reference_index (2), then the fields of the
+	// visual or audio sample entry, then child boxes.
+	childrenOffset := 8
+	switch trackInfo.HandlerType {
+	case "vide":
+		// pre_defined, reserved (16) + width (2) + height (2) + resolution,
+		// frame_count, compressorname, depth, pre_defined (50)
+		if len(payload) < 78 {
+			return fmt.Errorf("%q sample entry is truncated", boxType)
+		}
+		trackInfo.Width = int(binary.BigEndian.Uint16(payload[24:]))
+		trackInfo.Height = int(binary.BigEndian.Uint16(payload[26:]))
+		childrenOffset = 78
+	case "soun":
+		// reserved (8) + channelcount (2) + samplesize (2) + pre_defined,
+		// reserved (4) + samplerate (4, 16.16)
+		if len(payload) < 28 {
+			return fmt.Errorf("%q sample entry is truncated", boxType)
+		}
+		trackInfo.ChannelCount = int(binary.BigEndian.Uint16(payload[16:]))
+		trackInfo.SampleRate = int(binary.BigEndian.Uint32(payload[24:]) >> 16)
+		childrenOffset = 28
+	default:
+		// Text and subtitle sample entries, e.g., stpp, carry strings the
+		// checks do not need.
+		if boxType != "wvtt" {
+			return nil
+		}
+	}
+	if len(payload) < childrenOffset {
+		return fmt.Errorf("%q sample entry is truncated", boxType)
+	}
+
+	err := forEachBox(payload[childrenOffset:], func(boxType string, box []byte, payload []byte) error {
+		switch boxType {
+		case "sinf":
+			return trackInfo.parseProtectionSchemeInfo(payload)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// The codec configuration boxes are parsed once the original format of
+	// an encrypted sample entry is known.
+	return forEachBox(payload[childrenOffset:], func(boxType string, box []byte, payload []byte) error {
+		switch boxType {
+		case "avcC":
+			if len(payload) < 4 {
+				return errors.New("avcC box is truncated")
+			}
+			trackInfo.Codecs = fmt.Sprintf("%s.%02x%02x%02x", trackInfo.SampleEntry, payload[1], payload[2], payload[3])
+		case "hvcC":
+			codecs, err := formatHevcCodecs(trackInfo.SampleEntry, payload)
+			if err != nil {
+				return err
+			}
+			trackInfo.Codecs = codecs
+		case "esds":
+			if codecs, ok := formatMp4aCodecs(trackInfo.SampleEntry, payload); ok {
+				trackInfo.Codecs = codecs
+			}
+		}
+		return nil
+	})
+}
+
+/**
+ * Parses a sinf box: the original format, the scheme and the default KID.
+ * @param {Uint8Array} data
+ */
+func (trackInfo *TrackInfo) parseProtectionSchemeInfo(data []byte) error {
+	return forEachBox(data, func(boxType string, box []byte, payload []byte) error {
+		switch boxType {
+		case "frma":
+			if len(payload) < 4 {
+				return errors.New("frma box is truncated")
+			}
+			trackInfo.SampleEntry = string(payload[0:4])
+			trackInfo.Codecs = trackInfo.SampleEntry
+		case "schm":
+			if len(payload) < 8 {
+				return errors.New("schm box is truncated")
+			}
+			trackInfo.Scheme = string(payload[4:8])
+		case "schi":
+			return trackInfo.parseProtectionSchemeInfo(payload)
+		case "tenc":
+			// version (1) + flags (3) + reserved or pattern (2) +
+			// default_isProtected (1) + default_Per_Sample_IV_Size (1)
+			if len(payload) < 24 {
+				return errors.New("tenc box is truncated")
+			}
+			trackInfo.DefaultKid = formatUuid(payload[8:24])
+		}
+		return nil
+	})
+}
+
+/**
+ * Formats the codecs string of an HEVC sample entry.
+ * @see ISO/IEC 14496-15:2014 annex E
+ * @param {string} sampleEntry
+ * @param {Uint8Array} hvcC The hvcC box's payload.
+ * @return {string} e.g., "hvc1.1.6.L93.B0"
+ */
+func formatHevcCodecs(sampleEntry string, hvcC []byte) (string, error) {
+	if len(hvcC) < 13 {
+		return "", errors.New("hvcC box is truncated")
+	}
+
+	profileSpace := []string{"", "A", "B", "C"}[hvcC[1]>>6]
+	tier := "L"
+	if hvcC[1]&0x20 != 0 {
+		tier = "H"
+	}
+	profileIdc := hvcC[1] & 0x1f
+
+	// The compatibility flags are written in reverse bit order.
+	compatibilityFlags := binary.BigEndian.Uint32(hvcC[2:])
+	reversed := uint32(0)
+	for i := 0; i < 32; i++ {
+		reversed = reversed<<1 | compatibilityFlags&1
+		compatibilityFlags >>= 1
+	}
+
+	codecs := fmt.Sprintf("%s.%s%d.%x.%s%d", sampleEntry, profileSpace, profileIdc, reversed, tier, hvcC[12])
+
+	// The constraint flags, without trailing zero bytes.
+	constraintFlags := hvcC[6:12]
+	for len(constraintFlags) > 0 && constraintFlags[len(constraintFlags)-1] == 0 {
+		constraintFlags = constraintFlags[:len(constraintFlags)-1]
+	}
+	for _, b := range constraintFlags {
+		codecs += fmt.Sprintf(".%X", b)
+	}
+
+	return codecs, nil
+}
+
+/**
+ * Formats the codecs string of an MPEG-4 audio sample entry from its ES
+ * descriptor.
+ * @see ISO/IEC 14496-1 section 7.2.6
+ * @param {string} sampleEntry
+ * @param {Uint8Array} esds The esds box's payload.
+ * @return {string} e.g., "mp4a.40.2"
+ * @return {boolean} False if the descriptor could not be parsed.
+ */
+func formatMp4aCodecs(sampleEntry string, esds []byte) (string, bool) {
+	// readDescriptor returns the tag and the contents of the descriptor at
+	// the start of |data|, and what follows it.
+	readDescriptor := func(data []byte) (byte, []byte, []byte, bool) {
+		if len(data) < 2 {
+			return 0, nil, nil, false
+		}
+		tag := data[0]
+		size := 0
+		i := 1
+		for ; i < len(data) && i <= 4; i++ {
+			size = size<<7 | int(data[i]&0x7f)
+			if data[i]&0x80 == 0 {
+				break
+			}
+		}
+		i++
+		if i > len(data) || size > len(data)-i {
+			return 0, nil, nil, false
+		}
+		return tag, data[i : i+size], data[i+size:], true
+	}
+
+	// version (1) + flags (3)
+	if len(esds) < 4 {
+		return "", false
+	}
+	tag, es, _, ok := readDescriptor(esds[4:])
+	if !ok || tag != 0x03 || len(es) < 3 {
+		return "", false
+	}
+
+	// ES_ID (2) + flags (1), then the optional fields the flags announce.
+	flags := es[2]
+	es = es[3:]
+	if flags&0x80 != 0 {
+		es = es[Min(2, len(es)):]
+	}
+	if flags&0x40 != 0 && len(es) > 0 {
+		es = es[Min(1+int(es[0]), len(es)):]
+	}
+	if flags&0x20 != 0 {
+		es = es[Min(2, len(es)):]
+	}
+
+	for len(es) > 0 {
+		var contents []byte
+		if tag, contents, es, ok = readDescriptor(es); !ok {
+			return "", false
+		}
+		if tag != 0x04 || len(contents) < 13 {
+			continue
+		}
+
+		// DecoderConfigDescriptor: objectTypeIndication (1), then 12 bytes,
+		// then the DecoderSpecificInfo.
+		objectTypeIndication := contents[0]
+		codecs := fmt.Sprintf("%s.%x", sampleEntry, objectTypeIndication)
+		if tag, info, _, ok := readDescriptor(contents[13:]); ok && tag == 0x05 && len(info) > 0 && objectTypeIndication == 0x40 {
+			audioObjectType := int(info[0] >> 3)
+			if audioObjectType == 31 && len(info) > 1 {
+				audioObjectType = 32 + (int(info[0]&0x07)<<3 | int(info[1]>>5))
+			}
+			codecs += "." + strconv.Itoa(audioObjectType)
+		}
+		return codecs, true
+	}
+
+	return "", false
+}
+
+/**
+ * Fetches the stream's initialization segment, as given by
+ * SegmentInitializationInfo, sets SegmentInitializationData and InitInfo.
+ * @param {Fetcher} fetcher
+ * @return {InitInfo}
+ */
+func (streamInfo *StreamInfo) LoadInitInfo(ctx context.Context, fetcher Fetcher) (*InitInfo, error) {
+	segmentInitializationInfo := streamInfo.SegmentInitializationInfo
+	if segmentInitializationInfo == nil || len(segmentInitializationInfo.Urls) == 0 {
+		return nil, errors.New("stream has no initialization segment")
+	}
+
+	var byteRange *Range
+	if segmentInitializationInfo.EndByte >= 0 {
+		byteRange = newRange(segmentInitializationInfo.StartByte, segmentInitializationInfo.EndByte)
+	}
+
+	res, err := fetchAny(ctx, fetcher, segmentInitializationInfo.Urls, byteRange)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download initialization segment: %s", err)
+	}
+	streamInfo.SegmentInitializationData = res.Data
+
+	initInfo, err := ParseInitSegment(res.Data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid initialization segment: %s", err)
+	}
+	streamInfo.InitInfo = initInfo
+
+	return initInfo, nil
+}
+
+/**
+ * Compares the initialization segment with the manifest's codecs, width,
+ * height and cenc:default_KID. Attributes the manifest omits are not
+ * compared.
+ * @param {!StreamInfo} streamInfo
+ * @return {!Array.<!InitMismatch>}
+ */
+func (initInfo *InitInfo) Compare(streamInfo *StreamInfo) []InitMismatch {
+	mismatches := make([]InitMismatch, 0)
+
+	manifestCodecs := make([]string, 0)
+	for _, codecs := range strings.Split(streamInfo.Codecs, ",") {
+		if codecs = strings.TrimSpace(codecs); codecs != "" {
+			manifestCodecs = append(manifestCodecs, codecs)
+		}
+	}
+
+	defaultKids := make([]string, 0)
+	for _, contentProtection := range streamInfo.ContentProtections {
+		if contentProtection.DefaultKid != "" {
+			defaultKids = append(defaultKids, contentProtection.DefaultKid)
+		}
+	}
+
+	for _, trackInfo := range initInfo.Tracks {
+		if len(manifestCodecs) > 0 && !codecsMatch(manifestCodecs, trackInfo.Codecs) {
+			mismatches = append(mismatches, InitMismatch{Field: "codecs", Manifest: streamInfo.Codecs, Init: trackInfo.Codecs})
+		}
+
+		if trackInfo.HandlerType == "vide" {
+			if streamInfo.Width > 0 && streamInfo.Width != trackInfo.Width {
+				mismatches = append(mismatches, InitMismatch{Field: "width", Manifest: strconv.Itoa(streamInfo.Width), Init: strconv.Itoa(trackInfo.Width)})
+			}
+			if streamInfo.Height > 0 && streamInfo.Height != trackInfo.Height {
+				mismatches = append(mismatches, InitMismatch{Field: "height", Manifest: strconv.Itoa(streamInfo.Height), Init: strconv.Itoa(trackInfo.Height)})
+			}
+		}
+
+		for _, defaultKid := range defaultKids {
+			if normalizeKid(defaultKid) != normalizeKid(trackInfo.DefaultKid) {
+				mismatches = append(mismatches, InitMismatch{Field: "default_KID", Manifest: defaultKid, Init: trackInfo.DefaultKid})
+				break
+			}
+		}
+	}
+
+	return mismatches
+}
+
+/**
+ * @param {!Array.<string>} manifestCodecs
+ * @param {string} codecs The codecs of a track.
+ * @return {boolean} True if one of |manifestCodecs| describes the track. Only
+ *     the sample entry types are compared if either side has nothing more.
+ */
+func codecsMatch(manifestCodecs []string, codecs string) bool {
+	for _, manifestCodec := range manifestCodecs {
+		if strings.EqualFold(manifestCodec, codecs) {
+			return true
+		}
+		if !strings.Contains(manifestCodec, ".") || !strings.Contains(codecs, ".") {
+			if strings.SplitN(manifestCodec, ".", 2)[0] == strings.SplitN(codecs, ".", 2)[0] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/**
+ * @param {string} kid A KID, with or without dashes.
+ * @return {string} The KID in lower-case hex, without dashes.
+ */
+func normalizeKid(kid string) string {
+	return strings.ToLower(strings.Replace(kid, "-", "", -1))
+}
+
+/**
+ * Loads the InitInfo of every StreamInfo of ManifestInfo and compares it
+ * with the manifest. Must be called after Process.
+ * @param {Fetcher} fetcher
+ * @return {!Array.<Diagnostic>} The streams whose initialization segment
+ *     could not be loaded, and the mismatches.
+ */
+func (mpdProcessor *MpdProcessor) LoadInitInfos(ctx context.Context, fetcher Fetcher) []Diagnostic {
+	mpdProcessor.reporter = newDiagnosticReporter(mpdProcessor.Logger)
+
+	forEachStreamInfo(mpdProcessor.ManifestInfo, func(path string, periodInfo PeriodInfo, streamInfo *StreamInfo) {
+		if streamInfo.SegmentInitializationInfo == nil || len(streamInfo.SegmentInitializationInfo.Urls) == 0 {
+			return
+		}
+
+		initInfo, err := streamInfo.LoadInitInfo(ctx, fetcher)
+		if err != nil {
+			mpdProcessor.report(SEVERITY_ERROR, DIAGNOSTIC_INVALID_INIT_SEGMENT, path, "%s", err)
+			return
+		}
+
+		for _, mismatch := range initInfo.Compare(streamInfo) {
+			mpdProcessor.report(SEVERITY_WARNING, DIAGNOSTIC_INIT_SEGMENT_MISMATCH, path, "%s", mismatch)
+		}
+	})
+
+	return mpdProcessor.reporter.diagnostics
+}
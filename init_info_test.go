@@ -0,0 +1,144 @@
+package mpd
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testInitSegment builds an initialization segment with a single track,
+// followed by the boxes in |extra| in its moov.
+func testInitSegment(timescale uint32, handlerType string, sampleEntry []byte, extra ...[]byte) []byte {
+	fullBox := func(field uint32, size int) []byte {
+		payload := make([]byte, size)
+		binary.BigEndian.PutUint32(payload[12:], field)
+		return payload
+	}
+	trak := testBox("trak",
+		testBox("tkhd", fullBox(1, 80)),
+		testBox("mdia",
+			testBox("mdhd", fullBox(timescale, 20)),
+			testBox("hdlr", make([]byte, 8), []byte(handlerType), make([]byte, 13)),
+			testBox("minf", testBox("stbl", testBox("stsd", []byte{0, 0, 0, 0, 0, 0, 0, 1}, sampleEntry)))))
+	return append(testBox("ftyp", []byte("isom")), testBox("moov", append([][]byte{testBox("mvhd", fullBox(1000, 96)), trak}, extra...)...)...)
+}
+
+func TestLoadInitInfo(t *testing.T) {
+	kid := []byte{0x10, 0, 0, 0, 0x10, 0, 0x10, 0, 0x10, 0, 0x10, 0, 0, 0, 0, 0x01}
+
+	// An encrypted 1280x720 avc1 track.
+	visual := make([]byte, 78)
+	binary.BigEndian.PutUint16(visual[24:], 1280)
+	binary.BigEndian.PutUint16(visual[26:], 720)
+	tenc := append([]byte{0, 0, 0, 0, 0, 0, 1, 8}, kid...)
+	video := testInitSegment(90000, "vide", testBox("encv", visual,
+		testBox("avcC", []byte{1, 0x64, 0x00, 0x1f, 0xff}),
+		testBox("sinf",
+			testBox("frma", []byte("avc1")),
+			testBox("schm", []byte{0, 0, 0, 0}, []byte("cenc"), []byte{0, 1, 0, 0}),
+			testBox("schi", testBox("tenc", tenc)))),
+		testBox("pssh", make([]byte, 4), []byte{0xed, 0xef, 0x8b, 0xa9, 0x79, 0xd6, 0x4a, 0xce, 0xa3, 0xc8, 0x27, 0xdc, 0xd5, 0x1d, 0x21, 0xed}, make([]byte, 4)))
+
+	// A stereo 48kHz AAC-LC track.
+	sound := make([]byte, 28)
+	binary.BigEndian.PutUint16(sound[16:], 2)
+	binary.BigEndian.PutUint32(sound[24:], 48000<<16)
+	esds := []byte{0, 0, 0, 0,
+		0x03, 25, 0, 1, 0,
+		0x04, 17, 0x40, 0x15, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0x05, 2, 0x11, 0x90,
+		0x06, 1, 0x02}
+	audio := testInitSegment(48000, "soun", testBox("mp4a", sound, testBox("esds", esds)))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data := video
+		if r.URL.Path == "/audio.mp4" {
+			data = audio
+		}
+		http.ServeContent(w, r, "init.mp4", time.Time{}, strings.NewReader(string(data)))
+	}))
+	defer server.Close()
+
+	content := fmt.Sprintf(`<MPD xmlns:cenc="urn:mpeg:cenc:2013" type="static" mediaPresentationDuration="PT8S">
+  <Period>
+    <AdaptationSet mimeType="video/mp4">
+      <ContentProtection schemeIdUri="urn:mpeg:dash:mp4protection:2011" value="cenc" cenc:default_KID="10000000-1000-1000-1000-100000000001"/>
+      <Representation id="video" bandwidth="1000000" codecs="avc1.64001F" width="1280" height="1080">
+        <BaseURL>video.mp4</BaseURL>
+        <SegmentBase indexRange="%d-%d">
+          <Initialization range="0-%d"/>
+        </SegmentBase>
+      </Representation>
+    </AdaptationSet>
+    <AdaptationSet mimeType="audio/mp4">
+      <Representation id="audio" bandwidth="64000" codecs="mp4a.40.5">
+        <BaseURL>audio.mp4</BaseURL>
+        <SegmentBase indexRange="%d-%d">
+          <Initialization range="0-%d"/>
+        </SegmentBase>
+      </Representation>
+    </AdaptationSet>
+  </Period>
+</MPD>`, len(video), len(video)+100, len(video)-1, len(audio), len(audio)+100, len(audio)-1)
+
+	mpd, _, err := ParseMpdBytes([]byte(content), server.URL+"/manifest.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mpdProcessor := NewMpdProcessor()
+	mpdProcessor.Process(mpd)
+	diagnostics := mpdProcessor.LoadInitInfos(context.Background(), NewHttpFetcher(server.Client()))
+
+	videoInfo := mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[0].StreamInfos[0].InitInfo
+	audioInfo := mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[1].StreamInfos[0].InitInfo
+	if videoInfo == nil || audioInfo == nil {
+		t.Fatalf("missing InitInfo, diagnostics %v", diagnostics)
+	}
+
+	expectedVideo := TrackInfo{TrackId: 1, HandlerType: "vide", Timescale: 90000, SampleEntry: "avc1", Codecs: "avc1.64001f",
+		Width: 1280, Height: 720, Scheme: "cenc", DefaultKid: "10000000-1000-1000-1000-100000000001"}
+	if videoInfo.MovieTimescale != 1000 || len(videoInfo.Tracks) != 1 || *videoInfo.Tracks[0] != expectedVideo {
+		t.Errorf("unexpected video InitInfo %+v", videoInfo.Tracks[0])
+	}
+	if len(videoInfo.Psshs) != 1 || videoInfo.Psshs[0].SystemId != "edef8ba9-79d6-4ace-a3c8-27dcd51d21ed" {
+		t.Errorf("unexpected pssh boxes %v", videoInfo.Psshs)
+	}
+
+	expectedAudio := TrackInfo{TrackId: 1, HandlerType: "soun", Timescale: 48000, SampleEntry: "mp4a", Codecs: "mp4a.40.2",
+		ChannelCount: 2, SampleRate: 48000}
+	if len(audioInfo.Tracks) != 1 || *audioInfo.Tracks[0] != expectedAudio {
+		t.Errorf("unexpected audio InitInfo %+v", audioInfo.Tracks[0])
+	}
+
+	// The height and the audio codecs do not match.
+	expected := []string{
+		"Period[0]/AdaptationSet[0]/Representation[id=video]: height",
+		"Period[0]/AdaptationSet[1]/Representation[id=audio]: codecs",
+	}
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("expecting %d diagnostics, got %v", len(expected), diagnostics)
+	}
+	for i, diagnostic := range diagnostics {
+		if diagnostic.Code != DIAGNOSTIC_INIT_SEGMENT_MISMATCH || diagnostic.Path+": "+strings.SplitN(diagnostic.Message, " ", 2)[0] != expected[i] {
+			t.Errorf("unexpected diagnostic %v", diagnostic)
+		}
+	}
+}
+
+func FuzzParseInitSegment(f *testing.F) {
+	f.Add(testInitSegment(90000, "vide", testBox("avc1", make([]byte, 78), testBox("avcC", []byte{1, 0x64, 0x00, 0x1f, 0xff}))))
+	f.Add(testInitSegment(48000, "soun", testBox("mp4a", make([]byte, 28), testBox("esds", []byte{0, 0, 0, 0, 0x03, 6, 0, 1, 0, 0x04, 1, 0x40}))))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		initInfo, err := ParseInitSegment(data)
+		if err == nil {
+			streamInfo := NewStreamInfo()
+			initInfo.Compare(&streamInfo)
+		}
+	})
+}
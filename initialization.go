@@ -1,9 +1,5 @@
 package mpd
 
-import (
-	"github.com/moovweb/gokogiri/xml"
-)
-
 type Initialization struct {
 	/** @type {String} */
 	Url string
@@ -18,12 +14,14 @@ type Initialization struct {
  *     The parent SegmentBase or parent SegmentList.
  * @param {!Node} elem The Initialization XML element.
  */
-func (initialization *Initialization) Parse(parent Node, elem xml.Node) {
+func (initialization *Initialization) Parse(state *parseState, parent Node, elem element) error {
 
 	// Parse attributes.
 	initialization.Url, _ = parseAttrAsString(elem, "sourceURL")
 
 	initialization.Range, _ = parseAttrAsRange(elem, "range")
+
+	return nil
 }
 
 /**
@@ -0,0 +1,55 @@
+package mpd
+
+import (
+	"strings"
+)
+
+// Key systems -----------------------------------------------------------------
+const (
+	KEY_SYSTEM_WIDEVINE = "widevine"
+
+	KEY_SYSTEM_PLAYREADY = "playready"
+
+	KEY_SYSTEM_FAIRPLAY = "fairplay"
+
+	KEY_SYSTEM_CLEARKEY = "clearkey"
+
+	KEY_SYSTEM_MARLIN = "marlin"
+)
+
+/**
+ * Maps DRM SystemIDs, as they appear in PSSH boxes and in
+ * ContentProtection@schemeIdUri="urn:uuid:...", to key systems.
+ * @see https://dashif.org/identifiers/content_protection/
+ */
+var keySystemsBySystemId = map[string]string{
+	"edef8ba9-79d6-4ace-a3c8-27dcd51d21ed": KEY_SYSTEM_WIDEVINE,
+	"9a04f079-9840-4286-ab92-e65be0885f95": KEY_SYSTEM_PLAYREADY,
+	"94ce86fb-07ff-4f43-adb8-93d2fa968ca2": KEY_SYSTEM_FAIRPLAY,
+	"1077efec-c0b2-4d02-ace3-3c1e52e2fb4b": KEY_SYSTEM_CLEARKEY,
+	"e2719d58-a985-b3c9-781a-b030af78d30e": KEY_SYSTEM_CLEARKEY,
+	"5e629af5-38da-4063-8977-97ffbd9902d4": KEY_SYSTEM_MARLIN,
+}
+
+/**
+ * Gets the key system of a SystemID, e.g., KEY_SYSTEM_WIDEVINE for
+ * "edef8ba9-79d6-4ace-a3c8-27dcd51d21ed". The SystemID is case-insensitive.
+ * Returns false if the SystemID is not a known one.
+ */
+func KeySystemForSystemId(systemId string) (string, bool) {
+	keySystem, ok := keySystemsBySystemId[strings.ToLower(systemId)]
+	return keySystem, ok
+}
+
+/**
+ * Gets the SystemID of a ContentProtection@schemeIdUri of the form
+ * "urn:uuid:<SystemID>", lower-cased. Returns false for any other scheme,
+ * e.g., "urn:mpeg:dash:mp4protection:2011".
+ */
+func SystemIdForSchemeIdUri(schemeIdUri string) (string, bool) {
+	const prefix = "urn:uuid:"
+	if len(schemeIdUri) <= len(prefix) || !strings.EqualFold(schemeIdUri[:len(prefix)], prefix) {
+		return "", false
+	}
+	return strings.ToLower(schemeIdUri[len(prefix):]), true
+}
@@ -0,0 +1,311 @@
+package mpd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	/**
+	 * How long to wait before polling again after a failed update, if the MPD
+	 * does not say how often it is updated.
+	 * @const {time.Duration}
+	 */
+	DEFAULT_LIVE_RETRY_INTERVAL = 2 * time.Second
+
+	/**
+	 * The shortest interval between two polls, regardless of
+	 * MPD@minimumUpdatePeriod.
+	 * @const {time.Duration}
+	 */
+	MIN_LIVE_UPDATE_INTERVAL = 10 * time.Millisecond
+)
+
+/**
+ * The outcome of one poll of a LiveSession.
+ */
+type LiveSessionUpdate struct {
+	/**
+	 * The MPD which was retrieved, or nil if the poll failed.
+	 * @type {Mpd}
+	 */
+	Mpd *Mpd
+
+	/**
+	 * The ManifestInfo, with every SegmentIndex merged into the one of the
+	 * previous poll.
+	 * @type {ManifestInfo}
+	 */
+	ManifestInfo ManifestInfo
+
+	/**
+	 * True if the MPD was published again or if any stream gained or lost
+	 * SegmentReferences since the previous poll.
+	 * @type {boolean}
+	 */
+	Changed bool
+
+	/**
+	 * The problems found while parsing and processing the MPD.
+	 * @type {!Array.<Diagnostic>}
+	 */
+	Diagnostics []Diagnostic
+
+	/**
+	 * Why the poll failed, or nil. The previous ManifestInfo is kept.
+	 * @type {error}
+	 */
+	Err error
+}
+
+/**
+ * A LiveSession follows a dynamic MPD. It refetches the MPD every
+ * MPD@minimumUpdatePeriod, reprocesses it and merges the new SegmentIndexes
+ * into those of the previous poll, so that SegmentReferences accumulate until
+ * they leave the MPD@timeShiftBufferDepth. Its Parser and Processor must not
+ * be used while it runs.
+ */
+type LiveSession struct {
+	/**
+	 * The MPD's URL. Updates are fetched from the MPD's Location, if any.
+	 * @type {string}
+	 */
+	Url string
+
+	/** @type {MpdParser} */
+	Parser MpdParser
+
+	/**
+	 * Processes each MPD. Set its ClockOffset to align it to the server.
+	 * @type {MpdProcessor}
+	 */
+	Processor MpdProcessor
+
+	/**
+	 * Guards mpd and manifestInfo, which Run updates in the background.
+	 * @type {sync.Mutex}
+	 */
+	mutex sync.Mutex
+
+	/** @type {Mpd} */
+	mpd *Mpd
+
+	/** @type {ManifestInfo} */
+	manifestInfo ManifestInfo
+}
+
+/**
+ * Creates a LiveSession which follows the MPD at |url|.
+ */
+func NewLiveSession(url string) *LiveSession {
+	return &LiveSession{
+		Url:          url,
+		Parser:       NewMpdParser(),
+		Processor:    NewMpdProcessor(),
+		mpd:          nil,
+		manifestInfo: NewManifestInfo(),
+	}
+}
+
+/**
+ * @return {Mpd} The last MPD which was retrieved, or nil.
+ */
+func (liveSession *LiveSession) Mpd() *Mpd {
+	liveSession.mutex.Lock()
+	defer liveSession.mutex.Unlock()
+	return liveSession.mpd
+}
+
+/**
+ * @return {ManifestInfo} The merged ManifestInfo of the last successful poll.
+ */
+func (liveSession *LiveSession) ManifestInfo() ManifestInfo {
+	liveSession.mutex.Lock()
+	defer liveSession.mutex.Unlock()
+	return liveSession.manifestInfo
+}
+
+/**
+ * Polls the MPD until it becomes static, it stops requiring updates or |ctx|
+ * is done, and sends the outcome of every poll over the returned channel,
+ * which is closed when polling stops. The channel must be drained.
+ * @return {chan LiveSessionUpdate}
+ */
+func (liveSession *LiveSession) Run(ctx context.Context) <-chan LiveSessionUpdate {
+	updates := make(chan LiveSessionUpdate)
+
+	go func() {
+		defer close(updates)
+
+		for {
+			update := liveSession.Update(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+
+			select {
+			case updates <- update:
+			case <-ctx.Done():
+				return
+			}
+
+			var interval time.Duration
+			if mpd := liveSession.Mpd(); mpd != nil {
+				if mpd.Type != "dynamic" || mpd.MinUpdatePeriod <= 0 {
+					// The MPD will not change anymore.
+					return
+				}
+				interval = mpd.MinUpdatePeriod
+			} else {
+				interval = DEFAULT_LIVE_RETRY_INTERVAL
+			}
+			if interval < MIN_LIVE_UPDATE_INTERVAL {
+				interval = MIN_LIVE_UPDATE_INTERVAL
+			}
+
+			timer := time.NewTimer(interval)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+		}
+	}()
+
+	return updates
+}
+
+/**
+ * Fetches, processes and merges the MPD once.
+ * @return {LiveSessionUpdate}
+ */
+func (liveSession *LiveSession) Update(ctx context.Context) LiveSessionUpdate {
+	var mpd *Mpd
+	var diagnostics []Diagnostic
+	var err error
+
+	liveSession.mutex.Lock()
+	previousMpd := liveSession.mpd
+	previousManifestInfo := liveSession.manifestInfo
+	liveSession.mutex.Unlock()
+
+	if previousMpd == nil {
+		mpd, diagnostics, err = liveSession.Parser.ParseMpd(ctx, liveSession.Url)
+	} else {
+		mpd, diagnostics, err = liveSession.Parser.RefreshMpd(ctx, previousMpd)
+	}
+	if err == nil && mpd == nil {
+		err = errors.New("no mpd")
+	}
+	if err != nil {
+		return LiveSessionUpdate{
+			ManifestInfo: previousManifestInfo,
+			Diagnostics:  diagnostics,
+			Err:          fmt.Errorf("failed to update mpd: %s", err),
+		}
+	}
+
+	diagnostics = append(diagnostics, liveSession.Processor.Process(mpd)...)
+	manifestInfo := liveSession.Processor.ManifestInfo
+
+	changed := previousMpd == nil || !mpd.PublishTime.Equal(previousMpd.PublishTime)
+	if liveSession.mergeManifestInfo(mpd, previousManifestInfo, manifestInfo) {
+		changed = true
+	}
+
+	liveSession.mutex.Lock()
+	liveSession.mpd = mpd
+	liveSession.manifestInfo = manifestInfo
+	liveSession.mutex.Unlock()
+
+	return LiveSessionUpdate{
+		Mpd:          mpd,
+		ManifestInfo: manifestInfo,
+		Changed:      changed,
+		Diagnostics:  diagnostics,
+	}
+}
+
+/**
+ * Merges the SegmentIndexes of |previousManifestInfo| into those of
+ * |manifestInfo|, in place, and evicts the SegmentReferences which have left
+ * the time shift buffer. Merged StreamInfos keep their previous UniqueId.
+ * @param {!Mpd} mpd The MPD |manifestInfo| was created from.
+ * @param {!ManifestInfo} previousManifestInfo
+ * @param {!ManifestInfo} manifestInfo
+ * @return {boolean} True if any stream gained or lost SegmentReferences.
+ */
+func (liveSession *LiveSession) mergeManifestInfo(mpd *Mpd, previousManifestInfo ManifestInfo, manifestInfo ManifestInfo) bool {
+	previous := make(map[string]*StreamInfo)
+	forEachStreamInfo(previousManifestInfo, func(key string, periodInfo PeriodInfo, streamInfo *StreamInfo) {
+		previous[key] = streamInfo
+	})
+
+	changed := false
+	forEachStreamInfo(manifestInfo, func(key string, periodInfo PeriodInfo, streamInfo *StreamInfo) {
+		oldStreamInfo := previous[key]
+		if oldStreamInfo != nil {
+			streamInfo.UniqueId = oldStreamInfo.UniqueId
+		}
+		if streamInfo.SegmentIndex == nil {
+			return
+		}
+
+		segmentIndex := *streamInfo.SegmentIndex
+		if oldStreamInfo != nil && oldStreamInfo.SegmentIndex != nil {
+			segmentIndex = *oldStreamInfo.SegmentIndex
+			segmentIndex.Merge(*streamInfo.SegmentIndex)
+		}
+
+		if mpd.Type == "dynamic" && mpd.TimeShiftBufferDepth > 0 && !mpd.AvailabilityStartTime.IsZero() {
+			// The time shift buffer, relative to the Period. SegmentReference
+			// times are media times, i.e., they include the
+			// presentationTimeOffset, plus the SegmentIndex's correction.
+			currentPresentationTime := liveSession.Processor.now().Sub(mpd.AvailabilityStartTime.Add(periodInfo.Start))
+			segmentIndex.Evict(currentPresentationTime - mpd.TimeShiftBufferDepth - streamInfo.TimestampOffset + segmentIndex.TimestampCorrection)
+		}
+		streamInfo.SegmentIndex = &segmentIndex
+
+		if oldStreamInfo == nil || oldStreamInfo.SegmentIndex == nil || !sameReferences(oldStreamInfo.SegmentIndex.References, segmentIndex.References) {
+			changed = true
+		}
+	})
+
+	return changed
+}
+
+/**
+ * Calls |callback| for each StreamInfo of |manifestInfo| with a key which
+ * identifies the stream across updates of the MPD: the ids of its Period,
+ * AdaptationSet and Representation, or their positions if they have none.
+ */
+func forEachStreamInfo(manifestInfo ManifestInfo, callback func(key string, periodInfo PeriodInfo, streamInfo *StreamInfo)) {
+	for i, periodInfo := range manifestInfo.PeriodInfos {
+		periodKey := pathSegment(Period_TAG_NAME, i, periodInfo.Id)
+		for j, streamSetInfo := range periodInfo.StreamSetInfos {
+			streamSetKey := joinPath(periodKey, pathSegment(AdaptationSet_TAG_NAME, j, streamSetInfo.Id))
+			for k, streamInfo := range streamSetInfo.StreamInfos {
+				callback(joinPath(streamSetKey, pathSegment(Representation_TAG_NAME, k, streamInfo.Id)), periodInfo, streamInfo)
+			}
+		}
+	}
+}
+
+/**
+ * @return {boolean} True if both lists hold the same segments.
+ */
+func sameReferences(a []*SegmentReference, b []*SegmentReference) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].StartTime != b[i].StartTime || a[i].EndTime != b[i].EndTime {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,139 @@
+package mpd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLiveSession(t *testing.T) {
+	availabilityStartTime := "2020-01-01T00:00:00Z"
+	content := `<MPD type="%s" availabilityStartTime="%s" publishTime="%s" minimumUpdatePeriod="PT0.01S" timeShiftBufferDepth="PT8S">
+  <Period id="p0" start="PT0S">
+    <AdaptationSet id="1" mimeType="video/mp4">
+      <SegmentTemplate timescale="1000" presentationTimeOffset="100000" media="$Time$.m4s">
+        <SegmentTimeline>
+          <S t="%d" d="2000" r="2"/>
+        </SegmentTimeline>
+      </SegmentTemplate>
+      <Representation id="video" bandwidth="1000000"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+	mpds := []string{
+		fmt.Sprintf(content, "dynamic", availabilityStartTime, "2020-01-01T00:00:00Z", 100000),
+		fmt.Sprintf(content, "dynamic", availabilityStartTime, "2020-01-01T00:00:04Z", 104000),
+		fmt.Sprintf(content, "static", availabilityStartTime, "2020-01-01T00:00:04Z", 104000),
+	}
+
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(mpds[Min(polls, len(mpds)-1)]))
+		polls++
+	}))
+	defer server.Close()
+
+	liveSession := NewLiveSession(server.URL + "/manifest.mpd")
+	liveSession.Parser.Fetcher = NewHttpFetcher(server.Client())
+
+	// 10.5s after availabilityStartTime, the time shift buffer starts at 2.5s.
+	now := time.Date(2020, time.January, 1, 0, 0, 10, 500000000, time.UTC)
+	liveSession.Processor.clock = func() time.Time { return now }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	updates := make([]LiveSessionUpdate, 0)
+	for update := range liveSession.Run(ctx) {
+		if update.Err != nil {
+			t.Fatal(update.Err)
+		}
+		updates = append(updates, update)
+	}
+	if len(updates) != 3 {
+		t.Fatalf("expecting 3 updates, got %d", len(updates))
+	}
+	if mpd := liveSession.Mpd(); mpd == nil || mpd.Type != "static" {
+		t.Errorf("expecting the last mpd to be static")
+	}
+
+	// The first segment has left the time shift buffer, the second one is no
+	// longer in the MPD but is kept. Reference times include the
+	// presentationTimeOffset.
+	expected := [][]time.Duration{
+		{102 * time.Second, 104 * time.Second},
+		{102 * time.Second, 104 * time.Second, 106 * time.Second, 108 * time.Second},
+	}
+	var uniqueId int
+	for i, startTimes := range expected {
+		streamInfo := updates[i].ManifestInfo.PeriodInfos[0].StreamSetInfos[0].StreamInfos[0]
+		if !updates[i].Changed {
+			t.Errorf("update %d: expecting a change", i)
+		}
+		if i == 0 {
+			uniqueId = streamInfo.UniqueId
+		} else if streamInfo.UniqueId != uniqueId {
+			t.Errorf("update %d: expecting UniqueId %d, got %d", i, uniqueId, streamInfo.UniqueId)
+		}
+
+		references := streamInfo.SegmentIndex.References
+		if len(references) != len(startTimes) {
+			t.Fatalf("update %d: expecting %d references, got %d", i, len(startTimes), len(references))
+		}
+		for j, startTime := range startTimes {
+			if references[j].StartTime != startTime {
+				t.Errorf("update %d: expecting reference %d to start at %s, got %s", i, j, startTime, references[j].StartTime)
+			}
+		}
+	}
+}
+
+func TestLiveSessionSegmentDuration(t *testing.T) {
+	content := `<MPD type="dynamic" availabilityStartTime="2020-01-01T00:00:00Z" minimumUpdatePeriod="PT2S" timeShiftBufferDepth="PT30S">
+  <Period id="p0" start="PT0S">
+    <AdaptationSet id="1" mimeType="video/mp4">
+      <SegmentTemplate timescale="1000" duration="2000" presentationTimeOffset="90000000" media="$Number$.m4s"/>
+      <Representation id="video" bandwidth="1000000"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+
+	liveSession := NewLiveSession("http://example.com/manifest.mpd")
+	liveSession.Parser.Fetcher = testFetcher{"http://example.com/manifest.mpd": []byte(content)}
+
+	// Segment k spans [(k - 1) * 2s, k * 2s) of the Period, or 90000s more in
+	// media time. Segments which end before the time shift buffer are evicted.
+	availabilityStartTime := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	polls := []struct {
+		now          time.Duration
+		firstSegment int
+		lastSegment  int
+	}{
+		{61 * time.Second, 16, 30},
+		{65 * time.Second, 18, 32},
+	}
+
+	for i, poll := range polls {
+		now := availabilityStartTime.Add(poll.now)
+		liveSession.Processor.clock = func() time.Time { return now }
+
+		update := liveSession.Update(context.Background())
+		if update.Err != nil {
+			t.Fatal(update.Err)
+		}
+
+		references := update.ManifestInfo.PeriodInfos[0].StreamSetInfos[0].StreamInfos[0].SegmentIndex.References
+		if len(references) != poll.lastSegment-poll.firstSegment+1 {
+			t.Fatalf("poll %d: expecting %d references, got %d", i, poll.lastSegment-poll.firstSegment+1, len(references))
+		}
+		if first := 90000*time.Second + time.Duration(poll.firstSegment-1)*2*time.Second; references[0].StartTime != first {
+			t.Errorf("poll %d: expecting the first reference to start at %s, got %s", i, first, references[0].StartTime)
+		}
+		if url := fmt.Sprintf("http://example.com/%d.m4s", poll.lastSegment); references[len(references)-1].Urls[0] != url {
+			t.Errorf("poll %d: expecting the last reference to be %s, got %s", i, url, references[len(references)-1].Urls[0])
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package mpd
+
+import "strings"
+
+type Location struct {
+	/**
+	 * The URL at which the MPD is available for updates, resolved against the
+	 * MPD's own URL.
+	 * @type {string}
+	 */
+	Url string
+}
+
+/**
+ * Parses a "Location" tag.
+ * @param {!Mpd} parent The parent Mpd.
+ * @param {!Node} elem The Location XML element.
+ */
+func (location *Location) Parse(state *parseState, parent Node, elem element) error {
+	p, ok := parent.(*Mpd)
+	if !ok {
+		return state.errorf("a Location must be a child of an MPD")
+	}
+
+	location.Url, _ = getContents(elem)
+	location.Url = resolveUrl(p.Url, strings.TrimSpace(location.Url))
+
+	return nil
+}
+
+func NewLocation() Node {
+	return &Location{}
+}
@@ -0,0 +1,56 @@
+package mpd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseLocation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/manifest.mpd":
+			http.Redirect(w, r, "/live/manifest.mpd", http.StatusFound)
+		case "/live/manifest.mpd":
+			w.Write([]byte(`<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" id="first" type="dynamic">
+  <Location>next/manifest.mpd</Location>
+  <PatchLocation ttl="60">patch.mpp</PatchLocation>
+  <BaseURL>media/</BaseURL>
+</MPD>`))
+		case "/live/next/manifest.mpd":
+			w.Write([]byte(`<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" id="second" type="dynamic"/>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	mpdParser := NewMpdParser()
+	mpd, _, err := mpdParser.ParseMpd(context.Background(), server.URL+"/manifest.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mpd.Url != server.URL+"/live/manifest.mpd" {
+		t.Errorf("expecting the mpd url to be the redirected url, got %s", mpd.Url)
+	}
+	if len(mpd.BaseUrls) != 1 || mpd.BaseUrls[0].Url != server.URL+"/live/media/" {
+		t.Errorf("expecting the base url to be resolved against the redirected url, got %v", mpd.BaseUrls)
+	}
+	if len(mpd.Locations) != 1 || mpd.Locations[0].Url != server.URL+"/live/next/manifest.mpd" {
+		t.Errorf("unexpected locations %v", mpd.Locations)
+	}
+	if len(mpd.PatchLocations) != 1 || mpd.PatchLocations[0].Url != server.URL+"/live/patch.mpp" || mpd.PatchLocations[0].Ttl != 60*time.Second {
+		t.Errorf("unexpected patch locations %v", mpd.PatchLocations)
+	}
+
+	refreshed, _, err := mpdParser.RefreshMpd(context.Background(), mpd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed.Id != "second" || refreshed.UpdateUrl() != server.URL+"/live/next/manifest.mpd" {
+		t.Errorf("expecting the refresh to follow the location, got %s from %s", refreshed.Id, refreshed.Url)
+	}
+}
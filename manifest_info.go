@@ -1,9 +1,11 @@
 package mpd
 
+import "time"
+
 type ManifestInfo struct {
 	Live bool
 
-	MinBufferTime int
+	MinBufferTime time.Duration
 
 	PeriodInfos []PeriodInfo
 }
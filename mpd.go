@@ -1,8 +1,6 @@
 package mpd
 
-import (
-	"github.com/moovweb/gokogiri/xml"
-)
+import "time"
 
 type Mpd struct {
 	/** @type {?string} */
@@ -11,56 +9,97 @@ type Mpd struct {
 	/** @type {string} */
 	Type string
 
-	/** @type {*BaseUrl} */
-	BaseUrl *BaseUrl
+	/**
+	 * The URL the MPD was retrieved from, after any redirects. Relative URLs
+	 * within the MPD are resolved against it.
+	 * @type {string}
+	 */
+	Url string
+
+	/**
+	 * Where to fetch updates of the MPD from, in document order. Empty if the
+	 * MPD should be fetched from Url.
+	 * @type {!Array.<!Location>}
+	 */
+	Locations []*Location
+
+	/** @type {!Array.<!PatchLocation>} */
+	PatchLocations []*PatchLocation
 
 	/**
-	 * The entire stream's duration, in seconds.
-	 * @type {?number}
+	 * The BaseURLs, in document order, or the parent's if there are none.
+	 * @type {!Array.<!BaseUrl>}
 	 */
-	MediaPresentationDuration int
+	BaseUrls []*BaseUrl
 
 	/**
-	 * The quantity of media, in terms of seconds, that should be buffered before
+	 * The entire stream's duration, or -1 if it is unknown.
+	 * @type {?time.Duration}
+	 */
+	MediaPresentationDuration time.Duration
+
+	/**
+	 * The quantity of media, in terms of time, that should be buffered before
 	 * playback begins, to ensure uninterrupted playback.
-	 * @type {?number}
+	 * @type {time.Duration}
 	 */
-	MinBufferTime int
+	MinBufferTime time.Duration
 
 	/**
-	 * The interval, in seconds, to poll the media server for an updated
-	 * MPD, or null if updates are not required.
-	 * @type {?number}
+	 * The interval to poll the media server for an updated MPD, or 0 if updates
+	 * are not required.
+	 * @type {time.Duration}
 	 */
-	MinUpdatePeriod int
+	MinUpdatePeriod time.Duration
 
 	/**
-	 * The wall-clock time, in seconds, that the media content specified within
-	 * the MPD started/will start to stream.
-	 * @type {?number}
+	 * The wall-clock time that the media content specified within the MPD
+	 * started/will start to stream. The zero time if it is absent.
+	 * @type {?time.Time}
 	 */
-	AvailabilityStartTime int64
+	AvailabilityStartTime time.Time
 
 	/**
-	 * The duration, in seconds, that the media server retains live media
-	 * content, excluding the current segment and the previous segment, which are
-	 * always available. For example, if this value is 60 then only media content
-	 * up to 60 seconds from the beginning of the previous segment may be
-	 * requested from the media server.
-	 * @type {?number}
+	 * The wall-clock time after which the media content specified within the
+	 * MPD is no longer available. The zero time if it is absent.
+	 * @type {?time.Time}
 	 */
-	TimeShiftBufferDepth int
+	AvailabilityEndTime time.Time
 
 	/**
-	 * The duration, in seconds, that the media server takes to make live media
-	 * content available. For example, if this value is 30 then only media
+	 * The wall-clock time at which the MPD was generated and published. The
+	 * zero time if it is absent.
+	 * @type {?time.Time}
+	 */
+	PublishTime time.Time
+
+	/**
+	 * The duration that the media server retains live media content, excluding
+	 * the current segment and the previous segment, which are always available.
+	 * For example, if this value is 60 seconds then only media content up to 60
+	 * seconds from the beginning of the previous segment may be requested from
+	 * the media server.
+	 * @type {time.Duration}
+	 */
+	TimeShiftBufferDepth time.Duration
+
+	/**
+	 * The duration that the media server takes to make live media content
+	 * available. For example, if this value is 30 seconds then only media
 	 * content at least 30 seconds in the past may be requested from the media
 	 * server.
-	 * @type {?number}
+	 * @type {time.Duration}
 	 */
 
 	//DEFAULT_SUGGESTED_PRESENTATION_DELAY_;
-	SuggestedPresentationDelay int
+	SuggestedPresentationDelay time.Duration
+
+	/**
+	 * The UTCTiming elements, in order of preference.
+	 * @see ClockSync
+	 * @type {!Array.<!Descriptor>}
+	 */
+	UtcTimings []*Descriptor
 
 	/** @type {!Array.<!Period>} */
 	Periods []*Period
@@ -78,9 +117,14 @@ func NewMpd() Node {
  *     refers to the MPD resource itself.
  * @param {!Node} elem The MPD XML element.
  */
-func (mpd *Mpd) Parse(parent Node, elem xml.Node) {
+func (mpd *Mpd) Parse(state *parseState, parent Node, elem element) error {
 	var err error
-	p := parent.(FakeNode)
+	p, ok := parent.(FakeNode)
+	if !ok {
+		return state.errorf("MPD must be the root element")
+	}
+
+	mpd.Url = p.Url
 
 	// Parse attributes.
 	if mpd.Id, err = parseAttrAsString(elem, "id"); err != nil {
@@ -103,8 +147,16 @@ func (mpd *Mpd) Parse(parent Node, elem xml.Node) {
 		mpd.MinUpdatePeriod = 0
 	}
 
-	if mpd.AvailabilityStartTime, err = parseAttAsDate(elem, "availabilityStartTime"); err != nil {
-		mpd.AvailabilityStartTime = -1
+	if mpd.AvailabilityStartTime, err = parseAttrAsDateTime(elem, "availabilityStartTime"); err != nil {
+		mpd.AvailabilityStartTime = time.Time{}
+	}
+
+	if mpd.AvailabilityEndTime, err = parseAttrAsDateTime(elem, "availabilityEndTime"); err != nil {
+		mpd.AvailabilityEndTime = time.Time{}
+	}
+
+	if mpd.PublishTime, err = parseAttrAsDateTime(elem, "publishTime"); err != nil {
+		mpd.PublishTime = time.Time{}
 	}
 
 	if mpd.TimeShiftBufferDepth, err = parseAttrAsDuration(elem, "timeShiftBufferDepth"); err != nil {
@@ -116,15 +168,50 @@ func (mpd *Mpd) Parse(parent Node, elem xml.Node) {
 	}
 
 	// Parse simple child elements.
-	ok := false
-	if mpd.BaseUrl, ok = parseChild(mpd, elem, BaseUrl_TAG_NAME).(*BaseUrl); ok == false {
-		mpd.BaseUrl = p.BaseUrl
+	if mpd.BaseUrls, err = parseBaseUrls(state, mpd, elem, p.BaseUrls); err != nil {
+		return err
+	}
+
+	children, err := parseChildren(state, mpd, elem, Location_TAG_NAME)
+	if err != nil {
+		return err
+	}
+	mpd.Locations = make([]*Location, len(children))
+	for i, child := range children {
+		mpd.Locations[i] = child.(*Location)
+	}
+
+	if children, err = parseChildren(state, mpd, elem, PatchLocation_TAG_NAME); err != nil {
+		return err
+	}
+	mpd.PatchLocations = make([]*PatchLocation, len(children))
+	for i, child := range children {
+		mpd.PatchLocations[i] = child.(*PatchLocation)
+	}
+
+	if mpd.UtcTimings, err = parseDescriptors(state, mpd, elem, UtcTiming_TAG_NAME); err != nil {
+		return err
 	}
 
 	// Parse hierarchical children.
-	children := parseChildren(mpd, elem, Period_TAG_NAME)
+	if children, err = parseChildren(state, mpd, elem, Period_TAG_NAME); err != nil {
+		return err
+	}
 	mpd.Periods = make([]*Period, len(children))
 	for i, child := range children {
 		mpd.Periods[i] = child.(*Period)
 	}
+
+	return nil
+}
+
+/**
+ * @return {string} The URL to fetch the next update of the MPD from: the
+ *     first Location, if any, or else the MPD's own URL.
+ */
+func (mpd *Mpd) UpdateUrl() string {
+	if len(mpd.Locations) > 0 {
+		return mpd.Locations[0].Url
+	}
+	return mpd.Url
 }
@@ -5,33 +5,54 @@ import (
 	"fmt"
 	"math"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 const (
 	/**
 	 * Any gap/overlap within a SegmentTimeline that is greater than or equal to
-	 * this value (in seconds) will generate a warning message.
-	 * @const {number}
+	 * this value will generate a warning message.
+	 * @const {time.Duration}
 	 */
-	GAP_OVERLAP_WARNING_THRESHOLD = 1 / 32
+	GAP_OVERLAP_WARNING_THRESHOLD = time.Second / 32
 
 	/**
-	 * The maximum span, in seconds, that a SegmentIndex must account for when that
+	 * The maximum span that a SegmentIndex must account for when that
 	 * SegmentIndex is being generated via a segment duration.
-	 * @const {number}
+	 * @const {time.Duration}
+	 */
+	MAX_SEGMENT_INDEX_SPAN = 2 * time.Minute
+
+	/**
+	 * The default value for MPD@minBufferTime if this attribute is missing.
+	 * @const {time.Duration}
 	 */
-	MAX_SEGMENT_INDEX_SPAN = 2 * 60
+	DEFAULT_MIN_BUFFER_TIME = 5 * time.Second
 
 	/**
-	 * The default value, in seconds, for MPD@minBufferTime if this attribute is
-	 * missing.
+	 * The maximum number of segment references generated for a single stream.
+	 * Protects against manifests whose SegmentTimeline or segment duration would
+	 * otherwise expand to an unbounded number of segments.
 	 * @const {number}
 	 */
-	DEFAULT_MIN_BUFFER_TIME = 5
+	MAX_SEGMENT_REFERENCES = 200000
 )
 
+/**
+ * The EssentialProperty schemes a new MpdProcessor supports. The processor
+ * itself does not act on them, they only describe the stream, e.g., its
+ * colour space.
+ * @type {!Array.<string>}
+ */
+var DEFAULT_SUPPORTED_ESSENTIAL_PROPERTIES = []string{
+	"urn:mpeg:mpegB:cicp:ColourPrimaries",
+	"urn:mpeg:mpegB:cicp:TransferCharacteristics",
+	"urn:mpeg:mpegB:cicp:MatrixCoefficients",
+}
+
 /**
  * Creates an MpdProcessor, which validates MPDs, calculates start/duration
  * attributes, removes invalid Representations, and ultimately generates a
@@ -43,23 +64,116 @@ const (
 
 type MpdProcessor struct {
 	ManifestInfo ManifestInfo
+
+	/**
+	 * Receives each Diagnostic as soon as it is reported.
+	 * @type {Logger}
+	 */
+	Logger Logger
+
+	/**
+	 * The EssentialProperty@schemeIdUri values the application understands.
+	 * Representations with any other EssentialProperty are removed.
+	 * @type {!Array.<string>}
+	 */
+	SupportedEssentialProperties []string
+
+	/**
+	 * The difference between the server's clock and the local clock, e.g.,
+	 * from ClockSync. Every live calculation uses the local clock plus this
+	 * offset.
+	 * @type {time.Duration}
+	 */
+	ClockOffset time.Duration
+
+	/**
+	 * Returns the local time, or nil for time.Now. Tests set it to pin the
+	 * clock.
+	 * @private {?function(): time.Time}
+	 */
+	clock func() time.Time
+
+	/** @private {*diagnosticReporter} */
+	reporter *diagnosticReporter
+
+	/**
+	 * The index of each Period, AdaptationSet and Representation within its
+	 * parent, before any were removed, for reporting.
+	 * @private {!Object.<*, number>}
+	 */
+	indices map[interface{}]int
 }
 
 func NewMpdProcessor() MpdProcessor {
-	return MpdProcessor{}
+	return MpdProcessor{
+		SupportedEssentialProperties: append([]string(nil), DEFAULT_SUPPORTED_ESSENTIAL_PROPERTIES...),
+	}
+}
+
+/**
+ * @return {time.Time} The server's wall-clock time.
+ */
+func (mpdProcessor *MpdProcessor) now() time.Time {
+	if mpdProcessor.clock != nil {
+		return mpdProcessor.clock().Add(mpdProcessor.ClockOffset)
+	}
+	return time.Now().Add(mpdProcessor.ClockOffset)
 }
 
 /**
  * Processes the given MPD. Sets |this.periodInfos|.
  *
  * @param {Mpd} mpd
+ * @return {!Array.<Diagnostic>} The problems found while processing |mpd|.
  */
-func (mpdProcessor *MpdProcessor) Process(mpd *Mpd) {
+func (mpdProcessor *MpdProcessor) Process(mpd *Mpd) []Diagnostic {
 	mpdProcessor.ManifestInfo = NewManifestInfo()
+	mpdProcessor.reporter = newDiagnosticReporter(mpdProcessor.Logger)
+	mpdProcessor.recordIndices(mpd)
 	mpdProcessor.validateSegmentInfo(mpd)
 	mpdProcessor.calculateDurations(mpd)
 	mpdProcessor.filterPeriods(mpd)
 	mpdProcessor.createManifestInfo(*mpd)
+	return mpdProcessor.reporter.diagnostics
+}
+
+func (mpdProcessor *MpdProcessor) report(severity Severity, code string, path string, format string, args ...interface{}) {
+	mpdProcessor.reporter.report(severity, code, path, format, args...)
+}
+
+func (mpdProcessor *MpdProcessor) assert(exp bool, path string, message string) {
+	mpdProcessor.reporter.assert(exp, path, message)
+}
+
+/**
+ * Records the index of each element of |mpd| within its parent, so that
+ * diagnostics still point at the right element after others are removed.
+ *
+ * @param {Mpd} mpd
+ */
+func (mpdProcessor *MpdProcessor) recordIndices(mpd *Mpd) {
+	mpdProcessor.indices = make(map[interface{}]int)
+	for i, period := range mpd.Periods {
+		mpdProcessor.indices[period] = i
+		for j, adaptationSet := range period.AdaptationSets {
+			mpdProcessor.indices[adaptationSet] = j
+			for k, representation := range adaptationSet.Representations {
+				mpdProcessor.indices[representation] = k
+			}
+		}
+	}
+}
+
+func (mpdProcessor *MpdProcessor) periodPath(period *Period) string {
+	return pathSegment(Period_TAG_NAME, mpdProcessor.indices[period], period.Id)
+}
+
+func (mpdProcessor *MpdProcessor) adaptationSetPath(periodPath string, adaptationSet *AdaptationSet) string {
+	return joinPath(periodPath, pathSegment(AdaptationSet_TAG_NAME, mpdProcessor.indices[adaptationSet], adaptationSet.Id))
+}
+
+func (mpdProcessor *MpdProcessor) representationPath(adaptationSetPath string, representation *Representation) string {
+	return joinPath(adaptationSetPath, pathSegment(Representation_TAG_NAME, mpdProcessor.indices[representation], representation.Id))
 }
 
 /**
@@ -75,8 +189,11 @@ func (mpdProcessor *MpdProcessor) validateSegmentInfo(mpd *Mpd) {
 				continue
 			}
 
+			adaptationSetPath := mpdProcessor.adaptationSetPath(mpdProcessor.periodPath(period), adaptationSet)
+
 			for k := 0; k < len(adaptationSet.Representations); k++ {
 				representation := adaptationSet.Representations[k]
+				path := mpdProcessor.representationPath(adaptationSetPath, representation)
 
 				n := 0
 				if representation.SegmentBase != nil {
@@ -90,21 +207,22 @@ func (mpdProcessor *MpdProcessor) validateSegmentInfo(mpd *Mpd) {
 				}
 
 				if n == 0 {
-					fmt.Printf("Representation does not contain any segment information.\r\nA Representation must contain one of SegmentBase, SegmentList, or SegmentTemplate. %s\r\n", representation)
+					mpdProcessor.report(SEVERITY_ERROR, DIAGNOSTIC_MISSING_SEGMENT_INFO, path,
+						"A Representation must contain one of SegmentBase, SegmentList, or SegmentTemplate; the Representation is removed.")
 					adaptationSet.Representations = append(adaptationSet.Representations[:k], adaptationSet.Representations[k+1:]...)
 					k--
 				} else if n != 1 {
-					fmt.Printf("Representation contains multiple segment information sources.\r\nA Representation should only contain one of SegmentBase, SegmenstList, or SegmentTemplate. %s\r\n", representation)
 					if representation.SegmentBase != nil {
-						fmt.Printf("Using SegmentBase by default.")
+						mpdProcessor.report(SEVERITY_WARNING, DIAGNOSTIC_MULTIPLE_SEGMENT_INFO, path,
+							"A Representation should only contain one of SegmentBase, SegmentList, or SegmentTemplate; using SegmentBase.")
 						representation.SegmentList = nil
 						representation.SegmentTemplate = nil
 					} else if representation.SegmentList != nil {
-						fmt.Printf("Using SegmentList by default.")
+						mpdProcessor.report(SEVERITY_WARNING, DIAGNOSTIC_MULTIPLE_SEGMENT_INFO, path,
+							"A Representation should only contain one of SegmentBase, SegmentList, or SegmentTemplate; using SegmentList.")
 						representation.SegmentTemplate = nil
 					} else {
-						// asserts.unreachable();
-						fmt.Println("unreachable")
+						mpdProcessor.assert(false, path, "unreachable")
 					}
 				}
 			} // for k
@@ -139,7 +257,7 @@ func (mpdProcessor *MpdProcessor) calculateDurations(mpd *Mpd) {
 		mpd.Periods[0].Duration = mpd.MediaPresentationDuration
 	}
 
-	totalDuration := 0
+	totalDuration := time.Duration(0)
 
 	// True if |totalDuration| includes all periods, false if it only includes up
 	// to the last Period in which a start time and duration could be
@@ -195,24 +313,27 @@ func (mpdProcessor *MpdProcessor) calculateDurations(mpd *Mpd) {
 	// Period.start + Period.duration of the last Period."
 	if mpd.MediaPresentationDuration != -1 {
 		if mpd.MediaPresentationDuration != totalDuration {
-			fmt.Println("@mediaPresentationDuration does not match the total duration of all periods.")
+			mpdProcessor.report(SEVERITY_WARNING, DIAGNOSTIC_DURATION_MISMATCH, "",
+				"@mediaPresentationDuration does not match the total duration of all periods.")
 			// Assume mpd.mediaPresentationDuration is correct;
 			// |totalDurationIncludesAllPeriods| may be false.
 		}
 	} else {
 		finalPeriod := mpd.Periods[len(mpd.Periods)-1]
 		if totalDurationIncludesAllPeriods {
-			assert(finalPeriod.Start == -1 && finalPeriod.Duration == -1)
-			assert(totalDuration == finalPeriod.Start+finalPeriod.Duration)
+			mpdProcessor.assert(finalPeriod.Start != -1 && finalPeriod.Duration != -1, "", "the final period should have a start time and a duration")
+			mpdProcessor.assert(totalDuration == finalPeriod.Start+finalPeriod.Duration, "", "the total duration should end at the end of the final period")
 			mpd.MediaPresentationDuration = totalDuration
 		} else {
 			if finalPeriod.Start != -1 && finalPeriod.Duration != -1 {
-				fmt.Println("Some Periods may not have valid start times or durations.")
+				mpdProcessor.report(SEVERITY_WARNING, DIAGNOSTIC_UNKNOWN_DURATION, "",
+					"Some Periods may not have valid start times or durations.")
 				mpd.MediaPresentationDuration = finalPeriod.Start + finalPeriod.Duration
 			} else {
 				// Fallback to what we were able to compute.
 				if mpd.Type == "static" {
-					fmt.Println("Some Periods may not have valid start times or durations; @mediaPresentationDuration may not include the duration of all periods.")
+					mpdProcessor.report(SEVERITY_WARNING, DIAGNOSTIC_UNKNOWN_DURATION, "",
+						"Some Periods may not have valid start times or durations; @mediaPresentationDuration may not include the duration of all periods.")
 					mpd.MediaPresentationDuration = totalDuration
 				}
 			}
@@ -227,7 +348,7 @@ func (mpdProcessor *MpdProcessor) filterPeriods(mpd *Mpd) {
 	for _, period := range mpd.Periods {
 		for j := 0; j < len(period.AdaptationSets); j++ {
 			adaptationSet := period.AdaptationSets[j]
-			mpdProcessor.filterAdaptationSet(adaptationSet)
+			mpdProcessor.filterAdaptationSet(mpdProcessor.adaptationSetPath(mpdProcessor.periodPath(period), adaptationSet), adaptationSet)
 			if len(adaptationSet.Representations) == 0 {
 				// Drop any AdaptationSet that is empty.
 				// An error has already been logged.
@@ -241,27 +362,80 @@ func (mpdProcessor *MpdProcessor) filterPeriods(mpd *Mpd) {
 /**
  * Removes any Representation from the given AdaptationSet that has a different
  * MIME type than the MIME type of the first Representation of the
- * AdaptationSet.
+ * AdaptationSet, or that has an EssentialProperty which is not supported.
  *
+ * @param {string} path
  * @param {AdaptationSet} adaptationSet
  */
-func (mpdProcessor *MpdProcessor) filterAdaptationSet(adaptationSet *AdaptationSet) {
+func (mpdProcessor *MpdProcessor) filterAdaptationSet(path string, adaptationSet *AdaptationSet) {
 	desiredMimeType := ""
 
+	if schemeIdUri, ok := mpdProcessor.findUnsupportedEssentialProperty(adaptationSet.EssentialProperties); ok {
+		mpdProcessor.report(SEVERITY_WARNING, DIAGNOSTIC_UNSUPPORTED_ESSENTIAL_PROPERTY, path,
+			"AdaptationSet has an unsupported EssentialProperty %s; the AdaptationSet is removed.", schemeIdUri)
+		adaptationSet.Representations = adaptationSet.Representations[:0]
+		return
+	}
+
 	for i := 0; i < len(adaptationSet.Representations); i++ {
 		representation := adaptationSet.Representations[i]
 		mimeType := representation.MimeType
 
+		if schemeIdUri, ok := mpdProcessor.findUnsupportedEssentialProperty(representation.EssentialProperties); ok {
+			mpdProcessor.report(SEVERITY_WARNING, DIAGNOSTIC_UNSUPPORTED_ESSENTIAL_PROPERTY, mpdProcessor.representationPath(path, representation),
+				"Representation has an unsupported EssentialProperty %s; the Representation is removed.", schemeIdUri)
+			adaptationSet.Representations = append(adaptationSet.Representations[:i], adaptationSet.Representations[i+1:]...)
+			i--
+			continue
+		}
+
 		if desiredMimeType == "" {
 			desiredMimeType = mimeType
 		} else if mimeType != desiredMimeType {
-			fmt.Printf("Representation has an inconsistent mime type. %s\r\n", adaptationSet.Representations[i])
+			mpdProcessor.report(SEVERITY_WARNING, DIAGNOSTIC_INCONSISTENT_MIME_TYPE, mpdProcessor.representationPath(path, representation),
+				"Representation has an inconsistent mime type %s, expected %s; the Representation is removed.", mimeType, desiredMimeType)
 			adaptationSet.Representations = append(adaptationSet.Representations[:i], adaptationSet.Representations[i+1:]...)
 			i--
 		}
 	}
 }
 
+/**
+ * Finds an EssentialProperty which is not supported. EssentialProperties
+ * which share an @id are alternatives, so only one of them must be supported.
+ *
+ * @param {!Array.<!Descriptor>} essentialProperties
+ * @return {string} The @schemeIdUri of the unsupported EssentialProperty.
+ * @return {boolean} False if every EssentialProperty is supported.
+ */
+func (mpdProcessor *MpdProcessor) findUnsupportedEssentialProperty(essentialProperties []*Descriptor) (string, bool) {
+	supportedIds := make(map[string]bool)
+	for _, essentialProperty := range essentialProperties {
+		if essentialProperty.Id != "" && mpdProcessor.isEssentialPropertySupported(essentialProperty) {
+			supportedIds[essentialProperty.Id] = true
+		}
+	}
+
+	for _, essentialProperty := range essentialProperties {
+		if essentialProperty.Id != "" && supportedIds[essentialProperty.Id] {
+			continue
+		}
+		if !mpdProcessor.isEssentialPropertySupported(essentialProperty) {
+			return essentialProperty.SchemeIdUri, true
+		}
+	}
+	return "", false
+}
+
+func (mpdProcessor *MpdProcessor) isEssentialPropertySupported(essentialProperty *Descriptor) bool {
+	for _, schemeIdUri := range mpdProcessor.SupportedEssentialProperties {
+		if schemeIdUri == essentialProperty.SchemeIdUri {
+			return true
+		}
+	}
+	return false
+}
+
 /**
  * Creates a ManifestInfo from |mpd|.
  *
@@ -273,6 +447,7 @@ func (mpdProcessor *MpdProcessor) createManifestInfo(mpd Mpd) {
 
 	for i := 0; i < len(mpd.Periods); i++ {
 		period := mpd.Periods[i]
+		periodPath := mpdProcessor.periodPath(period)
 
 		periodInfo := NewPeriodInfo()
 		periodInfo.Id = period.Id
@@ -284,41 +459,58 @@ func (mpdProcessor *MpdProcessor) createManifestInfo(mpd Mpd) {
 		}
 
 		periodInfo.Duration = period.Duration
+		periodInfo.Events = mpdProcessor.createEventInfos(periodInfo.Start, period.EventStreams)
 
 		for _, adaptationSet := range period.AdaptationSets {
+			adaptationSetPath := mpdProcessor.adaptationSetPath(periodPath, adaptationSet)
 			streamSetInfo := NewStreamSetInfo()
 			streamSetInfo.Id = adaptationSet.Id
 			streamSetInfo.Main = adaptationSet.Main
 			streamSetInfo.ContentType = adaptationSet.ContentType
 			streamSetInfo.Lang = adaptationSet.Lang
+			streamSetInfo.Roles = adaptationSet.Roles
+			streamSetInfo.Accessibilities = adaptationSet.Accessibilities
+			streamSetInfo.Ratings = adaptationSet.Ratings
+			streamSetInfo.Viewpoints = adaptationSet.Viewpoints
+			streamSetInfo.EssentialProperties = adaptationSet.EssentialProperties
+			streamSetInfo.SupplementalProperties = adaptationSet.SupplementalProperties
+			streamSetInfo.InbandEventStreams = adaptationSet.InbandEventStreams
 
 			// Keep track of the largest end time of all segment references so that
 			// we can set a Period duration if one was not explicitly set in the MPD
 			// or calculated from calculateDurations_().
-			maxLastEndTime := uint64(0)
+			maxLastEndTime := time.Duration(0)
 
 			for _, representation := range adaptationSet.Representations {
-				streamInfo := mpdProcessor.createStreamInfo(mpd, *period, *representation)
+				streamInfo := mpdProcessor.createStreamInfo(mpdProcessor.representationPath(adaptationSetPath, representation), mpd, *period, *representation)
 				if streamInfo == nil {
 					// An error has already been logged.
-					println("Stream info is nil")
 					continue
 				}
 
+				streamInfo.EssentialProperties = append(append(streamInfo.EssentialProperties,
+					adaptationSet.EssentialProperties...), representation.EssentialProperties...)
+				streamInfo.SupplementalProperties = append(append(streamInfo.SupplementalProperties,
+					adaptationSet.SupplementalProperties...), representation.SupplementalProperties...)
+				streamInfo.InbandEventStreams = append(append(streamInfo.InbandEventStreams,
+					adaptationSet.InbandEventStreams...), representation.InbandEventStreams...)
+
 				streamSetInfo.StreamInfos = append(streamSetInfo.StreamInfos, streamInfo)
-				// streamSetInfo.drmSchemes = commonDrmSchemes;
 
-				if streamInfo.SegmentIndex != nil && streamInfo.SegmentIndex.Length() > 0 {
-					if maxLastEndTime < streamInfo.SegmentIndex.Last().EndTime {
-						maxLastEndTime = streamInfo.SegmentIndex.Last().EndTime
+				if streamInfo.SegmentIndex != nil && streamInfo.SegmentIndex.Length() > 0 && streamInfo.SegmentIndex.Last().EndTime != UNBOUNDED_END_TIME {
+					// The Period's duration is in presentation time.
+					if lastEndTime := streamInfo.SegmentIndex.Last().EndTime + streamInfo.TimestampOffset; maxLastEndTime < lastEndTime {
+						maxLastEndTime = lastEndTime
 					}
 				}
 			}
 
+			streamSetInfo.DrmSchemes = mpdProcessor.computeCommonDrmSchemes(streamSetInfo.StreamInfos)
+
 			periodInfo.StreamSetInfos = append(periodInfo.StreamSetInfos, streamSetInfo)
 
 			if periodInfo.Duration == -1 {
-				periodInfo.Duration = int(maxLastEndTime)
+				periodInfo.Duration = maxLastEndTime
 
 				// If the MPD is dynamic then the Period's duration will likely change
 				// after we re-process/update the MPD. When the Period's duration
@@ -338,7 +530,7 @@ func (mpdProcessor *MpdProcessor) createManifestInfo(mpd Mpd) {
 				// TODO: Remove this hack once SourceBuffer synchronization is
 				// implemented.
 				if mpd.Type == "dynamic" {
-					periodInfo.Duration += 60 * 60 * 24 * 30
+					periodInfo.Duration += 30 * 24 * time.Hour
 				}
 			}
 		}
@@ -347,16 +539,139 @@ func (mpdProcessor *MpdProcessor) createManifestInfo(mpd Mpd) {
 	}
 }
 
+/**
+ * Places the Events of |eventStreams| on the presentation timeline.
+ *
+ * @param {time.Duration} periodStart
+ * @param {!Array.<!EventStream>} eventStreams
+ * @return {!Array.<!EventInfo>} The events, ordered by start time.
+ */
+func (mpdProcessor *MpdProcessor) createEventInfos(periodStart time.Duration, eventStreams []*EventStream) []EventInfo {
+	eventInfos := make([]EventInfo, 0)
+
+	for _, eventStream := range eventStreams {
+		presentationTimeOffset := scaleTime(eventStream.PresentationTimeOffset, eventStream.Timescale)
+
+		for _, event := range eventStream.Events {
+			eventInfo := NewEventInfo()
+			eventInfo.SchemeIdUri = eventStream.SchemeIdUri
+			eventInfo.Value = eventStream.Value
+			eventInfo.Id = event.Id
+			eventInfo.StartTime = periodStart + scaleTime(event.PresentationTime, eventStream.Timescale) - presentationTimeOffset
+			if event.Duration != ^uint64(0) {
+				eventInfo.Duration = scaleTime(event.Duration, eventStream.Timescale)
+			}
+			if event.SpliceInfoSection != nil {
+				mpdProcessor.mapSpliceInfoSection(periodStart, eventStream, event.SpliceInfoSection, &eventInfo)
+			}
+			eventInfo.EventStream = eventStream
+			eventInfo.Event = event
+
+			eventInfos = append(eventInfos, eventInfo)
+		}
+	}
+
+	sort.SliceStable(eventInfos, func(i, j int) bool {
+		return eventInfos[i].StartTime < eventInfos[j].StartTime
+	})
+
+	return eventInfos
+}
+
+/**
+ * Maps an SCTE-35 cue onto the presentation timeline.
+ *
+ * @param {time.Duration} periodStart
+ * @param {!EventStream} eventStream
+ * @param {!SpliceInfoSection} spliceInfoSection
+ * @param {!EventInfo} eventInfo
+ */
+func (mpdProcessor *MpdProcessor) mapSpliceInfoSection(periodStart time.Duration, eventStream *EventStream, spliceInfoSection *SpliceInfoSection, eventInfo *EventInfo) {
+	eventInfo.SpliceTime = eventInfo.StartTime
+
+	if spliceTime, ok := spliceInfoSection.SpliceTime(); ok && eventStream.Timescale > 0 {
+		// The Period starts at presentationTimeOffset, in the EventStream's
+		// timescale, and PTS values wrap around, so take the shortest
+		// distance from the Period's start.
+		presentationTimeOffset := rescaleTime(eventStream.PresentationTimeOffset, eventStream.Timescale, SCTE35_TIMESCALE)
+		delta := int64((spliceTime + PTS_MODULUS - presentationTimeOffset%PTS_MODULUS) % PTS_MODULUS)
+		if delta >= PTS_MODULUS/2 {
+			delta -= PTS_MODULUS
+		}
+		if delta >= 0 {
+			eventInfo.SpliceTime = periodStart + scaleTime(uint64(delta), SCTE35_TIMESCALE)
+		} else {
+			eventInfo.SpliceTime = periodStart - scaleTime(uint64(-delta), SCTE35_TIMESCALE)
+		}
+	}
+
+	if duration, ok := spliceInfoSection.Duration(); ok && eventInfo.Duration == -1 {
+		eventInfo.Duration = scaleTime(duration, SCTE35_TIMESCALE)
+	}
+}
+
+/**
+ * Computes the DRM schemes which every one of |streamInfos| declares. A
+ * stream without any ContentProtection is unencrypted, so a set which mixes
+ * encrypted and unencrypted streams has no common scheme.
+ *
+ * @param {!Array.<!StreamInfo>} streamInfos
+ * @return {!Array.<!DrmSchemeInfo>} The common schemes, in the order the
+ *     first stream declares them.
+ */
+func (mpdProcessor *MpdProcessor) computeCommonDrmSchemes(streamInfos []*StreamInfo) []DrmSchemeInfo {
+	commonDrmSchemes := make([]DrmSchemeInfo, 0)
+	if len(streamInfos) == 0 {
+		return commonDrmSchemes
+	}
+
+	for _, contentProtection := range streamInfos[0].ContentProtections {
+		drmSchemeInfo := NewDrmSchemeInfo(contentProtection.SchemeIdUri)
+
+		duplicate := false
+		for _, existing := range commonDrmSchemes {
+			if existing.SchemeIdUri == drmSchemeInfo.SchemeIdUri {
+				duplicate = true
+			}
+		}
+		if duplicate {
+			continue
+		}
+
+		common := true
+		for _, streamInfo := range streamInfos {
+			found := false
+			for _, candidate := range streamInfo.ContentProtections {
+				if strings.EqualFold(candidate.SchemeIdUri, drmSchemeInfo.SchemeIdUri) {
+					drmSchemeInfo.add(candidate)
+					found = true
+				}
+			}
+			if !found {
+				common = false
+				break
+			}
+		}
+
+		if common {
+			commonDrmSchemes = append(commonDrmSchemes, drmSchemeInfo)
+		}
+	}
+
+	return commonDrmSchemes
+}
+
 /**
  * Creates a StreamInfo from the given Representation.
  *
+ * @param {string} path The Representation's path, for reporting.
  * @param {Mpd} mpd
  * @param {Period} period
  * @param {Representation} representation
  * @return {StreamInfo} The new StreamInfo on success; otherwise,
  *     return null.
  */
-func (mpdProcessor *MpdProcessor) createStreamInfo(mpd Mpd, period Period, representation Representation) *StreamInfo {
+func (mpdProcessor *MpdProcessor) createStreamInfo(path string, mpd Mpd, period Period, representation Representation) *StreamInfo {
 	streamInfo := NewStreamInfo()
 
 	streamInfo.Id = representation.Id
@@ -365,24 +680,36 @@ func (mpdProcessor *MpdProcessor) createStreamInfo(mpd Mpd, period Period, repre
 	streamInfo.Height = representation.Height
 	streamInfo.MimeType = representation.MimeType
 	streamInfo.Codecs = representation.Codecs
+	if representation.ContentProtections != nil {
+		streamInfo.ContentProtections = representation.ContentProtections
+	}
+	if representation.Roles != nil {
+		streamInfo.Roles = representation.Roles
+	}
+	if representation.Accessibilities != nil {
+		streamInfo.Accessibilities = representation.Accessibilities
+	}
+	if representation.Ratings != nil {
+		streamInfo.Ratings = representation.Ratings
+	}
+	if representation.Viewpoints != nil {
+		streamInfo.Viewpoints = representation.Viewpoints
+	}
 
 	ok := false
 
 	if representation.SegmentBase != nil {
-		fmt.Println("BuildStreamInfoFromSegmentBase")
-		ok = mpdProcessor.buildStreamInfoFromSegmentBase(representation.SegmentBase, &streamInfo)
+		ok = mpdProcessor.buildStreamInfoFromSegmentBase(path, representation.SegmentBase, &streamInfo)
 	} else if representation.SegmentList != nil {
-		fmt.Println("BuildStreamInfoFromSegmentList")
-		ok = mpdProcessor.buildStreamInfoFromSegmentList(representation.SegmentList, &streamInfo)
+		ok = mpdProcessor.buildStreamInfoFromSegmentList(path, representation.SegmentList, &streamInfo)
 	} else if representation.SegmentTemplate != nil {
-		ok = mpdProcessor.buildStreamInfoFromSegmentTemplate(mpd, period, representation, &streamInfo)
+		ok = mpdProcessor.buildStreamInfoFromSegmentTemplate(path, mpd, period, representation, &streamInfo)
 	} else if strings.Split(representation.MimeType, "/")[0] == "text" {
 		// All we need is a URL for subtitles.
-		streamInfo.MediaUrl = representation.BaseUrl.Url
+		streamInfo.MediaUrls = buildUrls(representation.BaseUrls, "")
 		ok = true
 	} else {
-		fmt.Println("unreachable")
-		// shaka.asserts.unreachable();
+		mpdProcessor.assert(false, path, "unreachable")
 	}
 
 	if ok {
@@ -395,35 +722,38 @@ func (mpdProcessor *MpdProcessor) createStreamInfo(mpd Mpd, period Period, repre
 /**
  * Builds a StreamInfo from a SegmentBase.
  *
+ * @param {string} path
  * @param {SegmentBase} segmentBase
  * @param {StreamInfo} streamInfo
  * @return {boolean} True on success.
  */
-func (mpdProcessor *MpdProcessor) buildStreamInfoFromSegmentBase(segmentBase *SegmentBase, streamInfo *StreamInfo) bool {
+func (mpdProcessor *MpdProcessor) buildStreamInfoFromSegmentBase(path string, segmentBase *SegmentBase, streamInfo *StreamInfo) bool {
 
-	assert(segmentBase.Timescale > 0)
+	if segmentBase.Timescale <= 0 {
+		mpdProcessor.report(SEVERITY_ERROR, DIAGNOSTIC_INVALID_SEGMENT_BASE, path,
+			"SegmentBase@timescale must be positive.")
+		return false
+	}
 
 	hasSegmentIndexMetadata := segmentBase.IndexRange != nil || (segmentBase.RepresentationIndex != nil && segmentBase.RepresentationIndex.Range != nil)
-	if !hasSegmentIndexMetadata || segmentBase.BaseUrl == nil {
-		fmt.Printf("A SegmentBase must have a segment index URL and a base URL. %s\r\n", segmentBase)
+	if !hasSegmentIndexMetadata || len(segmentBase.BaseUrls) == 0 {
+		mpdProcessor.report(SEVERITY_ERROR, DIAGNOSTIC_INVALID_SEGMENT_BASE, path,
+			"A SegmentBase must have a segment index URL and a base URL.")
 		return false
 	}
 
-	if segmentBase.PresentationTimeOffset != -1 {
-		// Each timestamp within each media segment is relative to the start of the
-		// Period minus @presentationTimeOffset. So to align the start of the first
-		// segment to the start of the Period we must apply an offset of -1 *
-		// @presentationTimeOffset seconds to each timestamp within each media
-		// segment.
-		streamInfo.TimestampOffset = -1 * segmentBase.PresentationTimeOffset / segmentBase.Timescale
-	}
+	// Each timestamp within each media segment is relative to the start of the
+	// Period minus @presentationTimeOffset. So to align the start of the first
+	// segment to the start of the Period we must apply an offset of -1 *
+	// @presentationTimeOffset seconds to each timestamp within each media
+	// segment.
+	streamInfo.TimestampOffset = -1 * scaleTime(segmentBase.PresentationTimeOffset, uint32(segmentBase.Timescale))
 
 	// If a RepresentationIndex does not exist then fallback to the indexRange
 	// attribute.
 	representationIndex := segmentBase.RepresentationIndex
 	if representationIndex == nil {
 		representationIndex = &RepresentationIndex{}
-		representationIndex.Url = segmentBase.BaseUrl.Url
 		if segmentBase.IndexRange != nil {
 			representationIndex.Range = segmentBase.IndexRange.Clone()
 		} else {
@@ -432,12 +762,12 @@ func (mpdProcessor *MpdProcessor) buildStreamInfoFromSegmentBase(segmentBase *Se
 	}
 
 	// Set StreamInfo properties.
-	streamInfo.MediaUrl = segmentBase.BaseUrl.Url
+	streamInfo.MediaUrls = buildUrls(segmentBase.BaseUrls, "")
 
-	segmentIndexInfo, _ := mpdProcessor.createSegmentMetadataInfo(representationIndex)
+	segmentIndexInfo, _ := mpdProcessor.createSegmentMetadataInfo(segmentBase.BaseUrls, representationIndex)
 	streamInfo.SegmentIndexInfo = &segmentIndexInfo
 
-	segmentInitializationInfo, _ := mpdProcessor.createSegmentMetadataInfo(segmentBase.Initialization)
+	segmentInitializationInfo, _ := mpdProcessor.createSegmentMetadataInfo(segmentBase.BaseUrls, segmentBase.Initialization)
 	streamInfo.SegmentInitializationInfo = &segmentInitializationInfo
 
 	return true
@@ -446,20 +776,26 @@ func (mpdProcessor *MpdProcessor) buildStreamInfoFromSegmentBase(segmentBase *Se
 /**
  * Builds a StreamInfo from a SegmentList.
  *
+ * @param {string} path
  * @param {SegmentList} segmentList
  * @param {StreamInfo} streamInfo
  * @return {boolean} True on success.
  * @private
  */
-func (mpdProcessor *MpdProcessor) buildStreamInfoFromSegmentList(segmentList *SegmentList, streamInfo *StreamInfo) bool {
-	assert(segmentList.Timescale > 0)
+func (mpdProcessor *MpdProcessor) buildStreamInfoFromSegmentList(path string, segmentList *SegmentList, streamInfo *StreamInfo) bool {
+	if segmentList.Timescale == 0 {
+		mpdProcessor.report(SEVERITY_ERROR, DIAGNOSTIC_INVALID_SEGMENT_LIST, path,
+			"SegmentList@timescale must be positive.")
+		return false
+	}
 
 	if segmentList.SegmentDuration == -1 && len(segmentList.SegmentUrls) > 1 {
-		fmt.Printf("A SegmentList without a segment duration can only have one segment.%s\r\n", segmentList)
+		mpdProcessor.report(SEVERITY_ERROR, DIAGNOSTIC_INVALID_SEGMENT_LIST, path,
+			"A SegmentList without a segment duration can only have one segment.")
 		return false
 	}
 
-	segmentInitializationInfo, _ := mpdProcessor.createSegmentMetadataInfo(segmentList.Initialization)
+	segmentInitializationInfo, _ := mpdProcessor.createSegmentMetadataInfo(segmentList.BaseUrls, segmentList.Initialization)
 	streamInfo.SegmentInitializationInfo = &segmentInitializationInfo
 
 	lastEndTime := uint64(0)
@@ -474,24 +810,22 @@ func (mpdProcessor *MpdProcessor) buildStreamInfoFromSegmentList(segmentList *Se
 		} else {
 			startTime = lastEndTime
 		}
-		assert(startTime >= 0)
-
 		endTime := uint64(0)
-		scaledEndTime := uint64(0)
+		scaledEndTime := UNBOUNDED_END_TIME
 
-		scaledStartTime := startTime / uint64(segmentList.Timescale)
+		scaledStartTime := scaleTime(startTime, segmentList.Timescale)
 
 		// If segmentList.segmentDuration is null then there must only be one
-		// segment.
-		if segmentList.SegmentDuration != 0 {
+		// segment, and it is unbounded.
+		if segmentList.SegmentDuration != -1 {
 			endTime = startTime + uint64(segmentList.SegmentDuration)
-			scaledEndTime = endTime / uint64(segmentList.Timescale)
+			scaledEndTime = scaleTime(endTime, segmentList.Timescale)
 		}
 
 		lastEndTime = endTime
 
 		startByte := 0
-		endByte := 0
+		endByte := -1
 
 		if segmentUrl.MediaRange != nil {
 			startByte = segmentUrl.MediaRange.Begin
@@ -504,16 +838,14 @@ func (mpdProcessor *MpdProcessor) buildStreamInfoFromSegmentList(segmentList *Se
 			scaledEndTime,
 			startByte,
 			endByte,
-			segmentUrl.MediaUrl)
+			buildUrls(segmentList.BaseUrls, segmentUrl.MediaUrl))
 
 		references = append(references, &segmentReference)
 
 	}
 
 	// Set StreamInfo properties.
-	segmentIndex := NewSegmentIndex(references)
-	streamInfo.SegmentIndex = &segmentIndex
-	fmt.Printf("Generated SegmentIndex from SegmentList %s\r\n", streamInfo.SegmentIndex)
+	streamInfo.SegmentIndex = mpdProcessor.createSegmentIndex(path, references)
 
 	return true
 }
@@ -521,15 +853,16 @@ func (mpdProcessor *MpdProcessor) buildStreamInfoFromSegmentList(segmentList *Se
 /**
  * Builds a StreamInfo from a SegmentTemplate.
  *
+ * @param {string} path
  * @param {Mpd} mpd
  * @param {Period} period
  * @param {Representation} representation
  * @param {StreamInfo} streamInfo
  * @return {boolean} True on success.
  */
-func (mpdProcessor *MpdProcessor) buildStreamInfoFromSegmentTemplate(mpd Mpd, period Period, representation Representation, streamInfo *StreamInfo) bool {
+func (mpdProcessor *MpdProcessor) buildStreamInfoFromSegmentTemplate(path string, mpd Mpd, period Period, representation Representation, streamInfo *StreamInfo) bool {
 
-	assert(representation.SegmentTemplate != nil)
+	mpdProcessor.assert(representation.SegmentTemplate != nil, path, "the Representation should have a SegmentTemplate")
 
 	segmentTemplate := representation.SegmentTemplate
 
@@ -539,24 +872,25 @@ func (mpdProcessor *MpdProcessor) buildStreamInfoFromSegmentTemplate(mpd Mpd, pe
 	// segment duration.
 	if segmentTemplate.IndexUrlTemplate != "" {
 		if segmentTemplate.Timeline != nil {
-			fmt.Printf("Ignoring SegmentTimeline because an explicit segment index URL was provided for the SegmentTemplate.%s\r\n", representation)
+			mpdProcessor.report(SEVERITY_INFO, DIAGNOSTIC_IGNORED_ATTRIBUTE, path,
+				"Ignoring SegmentTimeline because an explicit segment index URL was provided for the SegmentTemplate.")
 		}
 		if segmentTemplate.SegmentDuration != -1 {
-			fmt.Printf("Ignoring segment duration because an explicit segment index URL was provided for the SegmentTemplate.%s\r\n", representation)
+			mpdProcessor.report(SEVERITY_INFO, DIAGNOSTIC_IGNORED_ATTRIBUTE, path,
+				"Ignoring segment duration because an explicit segment index URL was provided for the SegmentTemplate.")
 		}
-		fmt.Println("BuildStreamInfoFromIndexUrlTemplate")
-		ok = mpdProcessor.buildStreamInfoFromIndexUrlTemplate(representation, streamInfo)
+		ok = mpdProcessor.buildStreamInfoFromIndexUrlTemplate(path, representation, streamInfo)
 	} else if segmentTemplate.Timeline != nil {
 		if segmentTemplate.SegmentDuration != -1 {
-			fmt.Printf("Ignoring segment duration because a SegmentTimeline was provided for the SegmentTemplate.%s\r\n", representation)
+			mpdProcessor.report(SEVERITY_INFO, DIAGNOSTIC_IGNORED_ATTRIBUTE, path,
+				"Ignoring segment duration because a SegmentTimeline was provided for the SegmentTemplate.")
 		}
-		fmt.Println("BuildStreamInfoFromSegmentTimeline")
-		ok = mpdProcessor.buildStreamInfoFromSegmentTimeline(mpd, period, representation, streamInfo)
+		ok = mpdProcessor.buildStreamInfoFromSegmentTimeline(path, mpd, period, representation, streamInfo)
 	} else if segmentTemplate.SegmentDuration != -1 {
-		fmt.Println("BuildStreamInfoFromSegmentDuration")
-		ok = mpdProcessor.buildStreamInfoFromSegmentDuration(mpd, period, representation, streamInfo)
+		ok = mpdProcessor.buildStreamInfoFromSegmentDuration(path, mpd, period, representation, streamInfo)
 	} else {
-		fmt.Printf("SegmentTemplate does not provide an explicit segment index URL, a SegmentTimeline, or a segment duration.%s\r\n", representation)
+		mpdProcessor.report(SEVERITY_ERROR, DIAGNOSTIC_INVALID_SEGMENT_TEMPLATE, path,
+			"SegmentTemplate does not provide an explicit segment index URL, a SegmentTimeline, or a segment duration.")
 		ok = false
 	}
 
@@ -566,74 +900,70 @@ func (mpdProcessor *MpdProcessor) buildStreamInfoFromSegmentTemplate(mpd Mpd, pe
 /**
  * Builds a StreamInfo from a SegmentTemplate with an index URL template.
  *
+ * @param {string} path
  * @param {Representation} representation
  * @param {StreamInfo} streamInfo
  * @return {boolean} True on success.
  * @private
  */
-func (mpdProcessor *MpdProcessor) buildStreamInfoFromIndexUrlTemplate(representation Representation, streamInfo *StreamInfo) bool {
-	fmt.Println("buildStreamInfoFromIndexUrlTemplate")
-
-	assert(representation.SegmentTemplate != nil)
-	assert(representation.SegmentTemplate.IndexUrlTemplate != "")
-	assert(representation.SegmentTemplate.Timescale > 0)
+func (mpdProcessor *MpdProcessor) buildStreamInfoFromIndexUrlTemplate(path string, representation Representation, streamInfo *StreamInfo) bool {
+	mpdProcessor.assert(representation.SegmentTemplate.IndexUrlTemplate != "", path, "SegmentTemplate should have an index URL template")
+	if representation.SegmentTemplate.Timescale == 0 {
+		mpdProcessor.report(SEVERITY_ERROR, DIAGNOSTIC_INVALID_SEGMENT_TEMPLATE, path,
+			"SegmentTemplate@timescale must be positive.")
+		return false
+	}
 
 	segmentTemplate := representation.SegmentTemplate
 
 	// Generate the media URL. Since there is no SegmentTimeline there is only
 	// one media URL, so just map $Number$ to 1 and $Time$ to 0.
-	mediaUrl := ""
+	var mediaUrls []string
 
 	if segmentTemplate.MediaUrlTemplate != "" {
-		filledUrlTemplate := mpdProcessor.fillUrlTemplate(segmentTemplate.MediaUrlTemplate, representation.Id, 1, representation.Bandwidth, 0)
+		filledUrlTemplate := mpdProcessor.fillUrlTemplate(path, segmentTemplate.MediaUrlTemplate, representation.Id, 1, representation.Bandwidth, 0)
 
 		if filledUrlTemplate == "" {
 			// An error has already been logged.
 			return false
 		}
 
-		if representation.BaseUrl != nil {
-			mediaUrl = representation.BaseUrl.Url + filledUrlTemplate
-		} else {
-			mediaUrl = filledUrlTemplate
-		}
+		mediaUrls = buildUrls(representation.BaseUrls, filledUrlTemplate)
 	} else {
 		// Fallback to the Representation's URL.
-		mediaUrl = representation.BaseUrl.Url
+		mediaUrls = buildUrls(representation.BaseUrls, "")
 	}
 
 	// Generate a RepresentationIndex.
 	var err error
 	var representationIndex RepresentationIndex
 
-	if representationIndex, err = mpdProcessor.generateRepresentationIndex(representation); err != nil {
+	if representationIndex, err = mpdProcessor.generateRepresentationIndex(path, representation); err != nil {
 		// An error has already been logged.
 		return false
 	}
 
 	// Generate an Initialization.
-	var initialization Initialization
+	var initialization *Initialization
 	if segmentTemplate.InitializationUrlTemplate != "" {
-		if initialization, err = mpdProcessor.generateInitialization(representation); err != nil {
+		if initialization, err = mpdProcessor.generateInitialization(path, representation); err != nil {
 			// An error has already been logged.
 			return false
 		}
 	}
 
 	// Set StreamInfo properties.
-	streamInfo.MediaUrl = mediaUrl
+	streamInfo.MediaUrls = mediaUrls
 
-	if segmentTemplate.PresentationTimeOffset != -1 {
-		streamInfo.TimestampOffset = -1 * segmentTemplate.PresentationTimeOffset / int(segmentTemplate.Timescale)
-	}
+	streamInfo.TimestampOffset = -1 * scaleTime(segmentTemplate.PresentationTimeOffset, segmentTemplate.Timescale)
 
-	if segmentIndexInfo, err := mpdProcessor.createSegmentMetadataInfo(&representationIndex); err != nil {
+	if segmentIndexInfo, err := mpdProcessor.createSegmentMetadataInfo(representation.BaseUrls, &representationIndex); err != nil {
 		streamInfo.SegmentIndexInfo = nil
 	} else {
 		streamInfo.SegmentIndexInfo = &segmentIndexInfo
 	}
 
-	if segmentInitializationInfo, err := mpdProcessor.createSegmentMetadataInfo(&initialization); err != nil {
+	if segmentInitializationInfo, err := mpdProcessor.createSegmentMetadataInfo(representation.BaseUrls, initialization); err != nil {
 		streamInfo.SegmentInitializationInfo = nil
 	} else {
 		streamInfo.SegmentInitializationInfo = &segmentInitializationInfo
@@ -645,34 +975,29 @@ func (mpdProcessor *MpdProcessor) buildStreamInfoFromIndexUrlTemplate(representa
 /**
  * Generates a RepresentationIndex from a SegmentTemplate.
  *
+ * @param {string} path
  * @param {Representation} representation
  * @return {RepresentationIndex} A RepresentationIndex on
  *     success, null if no index URL template exists or an error occurred.
  */
-func (mpdProcessor *MpdProcessor) generateRepresentationIndex(representation Representation) (RepresentationIndex, error) {
-	assert(representation.SegmentTemplate != nil)
-
+func (mpdProcessor *MpdProcessor) generateRepresentationIndex(path string, representation Representation) (RepresentationIndex, error) {
 	representationIndex := RepresentationIndex{}
 
 	segmentTemplate := representation.SegmentTemplate
-	assert(segmentTemplate.IndexUrlTemplate != "")
 	if segmentTemplate.IndexUrlTemplate == "" {
 		return representationIndex, errors.New("missing index url template")
 	}
 
 	// $Number$ and $Time$ cannot be present in an index URL template.
-	filledUrlTemplate := mpdProcessor.fillUrlTemplate(segmentTemplate.IndexUrlTemplate, representation.Id, 0, representation.Bandwidth, 0)
+	filledUrlTemplate := mpdProcessor.fillUrlTemplate(path, segmentTemplate.IndexUrlTemplate, representation.Id, 0, representation.Bandwidth, 0)
 
 	if filledUrlTemplate == "" {
 		// An error has already been logged.
 		return representationIndex, errors.New("missing filled url template")
 	}
 
-	if representation.BaseUrl != nil && filledUrlTemplate != "" {
-		representationIndex.Url = representation.BaseUrl.Url + filledUrlTemplate
-	} else {
-		representationIndex.Url = filledUrlTemplate
-	}
+	// The URL is relative to the Representation's BaseURLs.
+	representationIndex.Url = filledUrlTemplate
 
 	return representationIndex, nil
 }
@@ -680,29 +1005,35 @@ func (mpdProcessor *MpdProcessor) generateRepresentationIndex(representation Rep
 /**
  * Builds a StreamInfo from a SegmentTemplate with a SegmentTimeline.
  *
+ * @param {string} path
  * @param {Mpd} mpd
  * @param {Period} period
  * @param {Representation} representation
  * @param {StreamInfo} streamInfo
  * @return {boolean} True on success.
  */
-func (mpdProcessor *MpdProcessor) buildStreamInfoFromSegmentTimeline(mpd Mpd, period Period, representation Representation, streamInfo *StreamInfo) bool {
-	assert(representation.SegmentTemplate != nil)
-	assert(representation.SegmentTemplate.Timeline != nil)
-	assert(representation.SegmentTemplate.Timescale > 0)
+func (mpdProcessor *MpdProcessor) buildStreamInfoFromSegmentTimeline(path string, mpd Mpd, period Period, representation Representation, streamInfo *StreamInfo) bool {
+	mpdProcessor.assert(representation.SegmentTemplate.Timeline != nil, path, "SegmentTemplate should have a SegmentTimeline")
+	if representation.SegmentTemplate.Timescale == 0 {
+		mpdProcessor.report(SEVERITY_ERROR, DIAGNOSTIC_INVALID_SEGMENT_TEMPLATE, path,
+			"SegmentTemplate@timescale must be positive.")
+		return false
+	}
 
 	if period.Start == -1 {
-		fmt.Printf("Cannot instantiate SegmentTemplate: the period's start time is unknown.%s\r\n", representation)
+		mpdProcessor.report(SEVERITY_ERROR, DIAGNOSTIC_INVALID_SEGMENT_TEMPLATE, path,
+			"Cannot instantiate SegmentTemplate: the period's start time is unknown.")
 		return false
 	}
 
 	segmentTemplate := representation.SegmentTemplate
 	if segmentTemplate.MediaUrlTemplate == "" {
-		fmt.Printf("Cannot instantiate SegmentTemplate: SegmentTemplate does not have a media URL template.%s\r\n", representation)
+		mpdProcessor.report(SEVERITY_ERROR, DIAGNOSTIC_INVALID_SEGMENT_TEMPLATE, path,
+			"Cannot instantiate SegmentTemplate: SegmentTemplate does not have a media URL template.")
 		return false
 	}
 
-	timeline := mpdProcessor.createTimeline(segmentTemplate)
+	timeline := mpdProcessor.createTimeline(path, segmentTemplate)
 	if timeline == nil {
 		// An error has already been logged.
 		return false
@@ -714,11 +1045,11 @@ func (mpdProcessor *MpdProcessor) buildStreamInfoFromSegmentTimeline(mpd Mpd, pe
 	// @availabilityStartTime then the calculation below would be more
 	// complicated than the calculations in computeAvailableSegmentRange_() since
 	// the duration of each segment is variable here.
-	earliestAvailableTimestamp := uint64(0)
+	earliestAvailableTimestamp := time.Duration(0)
 	if mpd.Type == "dynamic" && len(timeline) > 0 {
 		index := Max(0, len(timeline)-2)
 		timeShiftBufferDepth := mpd.TimeShiftBufferDepth
-		earliestAvailableTimestamp = (timeline[index].Start / uint64(segmentTemplate.Timescale)) - uint64(timeShiftBufferDepth)
+		earliestAvailableTimestamp = scaleTime(timeline[index].Start, segmentTemplate.Timescale) - timeShiftBufferDepth
 	}
 
 	// Generate a SegmentIndex.
@@ -729,8 +1060,8 @@ func (mpdProcessor *MpdProcessor) buildStreamInfoFromSegmentTimeline(mpd Mpd, pe
 		endTime := timeline[i].End
 
 		// Compute the segment's scaled start time and scaled end time.
-		scaledStartTime := startTime / uint64(segmentTemplate.Timescale)
-		scaledEndTime := endTime / uint64(segmentTemplate.Timescale)
+		scaledStartTime := scaleTime(startTime, segmentTemplate.Timescale)
+		scaledEndTime := scaleTime(endTime, segmentTemplate.Timescale)
 
 		if scaledStartTime < earliestAvailableTimestamp {
 			// Skip unavailable segments.
@@ -744,8 +1075,8 @@ func (mpdProcessor *MpdProcessor) buildStreamInfoFromSegmentTimeline(mpd Mpd, pe
 		timeReplacement := startTime
 
 		// Generate the media URL.
-		assert(segmentTemplate.MediaUrlTemplate != "")
 		filledUrlTemplate := mpdProcessor.fillUrlTemplate(
+			path,
 			segmentTemplate.MediaUrlTemplate,
 			representation.Id,
 			segmentReplacement,
@@ -757,43 +1088,35 @@ func (mpdProcessor *MpdProcessor) buildStreamInfoFromSegmentTimeline(mpd Mpd, pe
 			return false
 		}
 
-		mediaUrl := ""
-		if representation.BaseUrl != nil {
-			mediaUrl = representation.BaseUrl.Url + filledUrlTemplate
-		} else {
-			mediaUrl = filledUrlTemplate
-		}
-		segmentReference := NewSegmentReference(startTime, scaledStartTime, scaledEndTime, 0 /* startByte */, -1 /* endByte */, mediaUrl)
+		mediaUrls := buildUrls(representation.BaseUrls, filledUrlTemplate)
+		segmentReference := NewSegmentReference(startTime, scaledStartTime, scaledEndTime, 0 /* startByte */, -1 /* endByte */, mediaUrls)
 		references = append(references, &segmentReference)
 	}
 
 	// Generate an Initialization. If there are no references then assume that
 	// the intialization segment is not available.
-	var initialization Initialization
+	var initialization *Initialization
 	var err error
 	if segmentTemplate.InitializationUrlTemplate != "" && len(references) > 0 {
-		if initialization, err = mpdProcessor.generateInitialization(representation); err != nil {
+		if initialization, err = mpdProcessor.generateInitialization(path, representation); err != nil {
 			// An error has already been logged.
 			return false
 		}
 	}
 
 	// Set StreamInfo properties.
-	if segmentTemplate.PresentationTimeOffset != -1 {
-		streamInfo.TimestampOffset = -1 * segmentTemplate.PresentationTimeOffset / int(segmentTemplate.Timescale)
-	}
+	streamInfo.TimestampOffset = -1 * scaleTime(segmentTemplate.PresentationTimeOffset, segmentTemplate.Timescale)
 
 	if mpd.Type == "dynamic" && len(references) > 0 {
 		minBufferTime := mpdProcessor.ManifestInfo.MinBufferTime
-		bestAvailableTimestamp := references[len(references)-1].StartTime - uint64(minBufferTime)
+		bestAvailableTimestamp := references[len(references)-1].StartTime - minBufferTime
 
-		if bestAvailableTimestamp >= earliestAvailableTimestamp {
-			fmt.Printf("The best available segment is still available!\r\n")
-		} else {
+		if bestAvailableTimestamp < earliestAvailableTimestamp {
 			// NOTE: @minBufferTime is large compared to @timeShiftBufferDepth, so we
 			// can't start as far back, for buffering, as we'd like.
 			bestAvailableTimestamp = earliestAvailableTimestamp
-			fmt.Printf("The best available segment is no longer available.\r\n")
+			mpdProcessor.report(SEVERITY_INFO, DIAGNOSTIC_SEGMENT_UNAVAILABLE, path,
+				"The best available segment is no longer available.")
 		}
 
 		for i := 0; i < len(references); i++ {
@@ -803,29 +1126,45 @@ func (mpdProcessor *MpdProcessor) buildStreamInfoFromSegmentTimeline(mpd Mpd, pe
 			}
 		}
 
-		assert(streamInfo.CurrentSegmentStartTime != 0)
+		mpdProcessor.assert(streamInfo.CurrentSegmentStartTime != 0, path, "the current segment should have been found")
 	}
 
-	if segmentInitializationInfo, err := mpdProcessor.createSegmentMetadataInfo(&initialization); err != nil {
+	if segmentInitializationInfo, err := mpdProcessor.createSegmentMetadataInfo(representation.BaseUrls, initialization); err != nil {
 		streamInfo.SegmentInitializationInfo = nil
 	} else {
 		streamInfo.SegmentInitializationInfo = &segmentInitializationInfo
 	}
 
-	segmentIndex := NewSegmentIndex(references)
-	streamInfo.SegmentIndex = &segmentIndex
-	// fmt.Printf("Generated SegmentIndex from SegmentTimeline %s\r\n", streamInfo.SegmentIndex)
+	streamInfo.SegmentIndex = mpdProcessor.createSegmentIndex(path, references)
 
 	return true
 }
 
+/**
+ * Creates a SegmentIndex and checks that its SegmentReferences are sorted and
+ * contiguous.
+ *
+ * @param {string} path
+ * @param {!Array.<!SegmentReference>} references
+ * @return {!SegmentIndex}
+ */
+func (mpdProcessor *MpdProcessor) createSegmentIndex(path string, references []*SegmentReference) *SegmentIndex {
+	segmentIndex := NewSegmentIndex(references)
+	if err := segmentIndex.Validate(); err != nil {
+		mpdProcessor.assert(false, path, err.Error())
+	}
+
+	return &segmentIndex
+}
+
 /**
  * Expands a SegmentTimeline into a simple array-based timeline.
  *
+ * @param {string} path
+ * @param {SegmentTemplate} segmentTemplate
  * @return {Array.<{start: number, end: number}>}
  */
-func (mpdProcessor *MpdProcessor) createTimeline(segmentTemplate *SegmentTemplate) []TimeLine {
-	assert(segmentTemplate.Timeline != nil)
+func (mpdProcessor *MpdProcessor) createTimeline(path string, segmentTemplate *SegmentTemplate) []TimeLine {
 
 	timePoints := segmentTemplate.Timeline.TimePoints
 	lastEndTime := uint64(0)
@@ -840,9 +1179,16 @@ func (mpdProcessor *MpdProcessor) createTimeline(segmentTemplate *SegmentTemplat
 			repeat = timePoints[i].Repeat
 		}
 
+		if repeat >= MAX_SEGMENT_REFERENCES-len(timeline) {
+			mpdProcessor.report(SEVERITY_ERROR, DIAGNOSTIC_TOO_MANY_SEGMENTS, joinPath(path, SegmentTimeline_TAG_NAME),
+				"SegmentTimeline expands to more than %d segments.", MAX_SEGMENT_REFERENCES)
+			return nil
+		}
+
 		for j := 0; j <= repeat; j++ {
 			if timePoints[i].Duration == ^uint64(0) {
-				fmt.Printf("SegmentTimeline 'S' element does not have a duration.%s\r\n", timePoints[i])
+				mpdProcessor.report(SEVERITY_ERROR, DIAGNOSTIC_INVALID_SEGMENT_TEMPLATE, joinPath(path, SegmentTimeline_TAG_NAME, fmt.Sprintf("%s[%d]", SegmentTimePoint_TAG_NAME, i)),
+					"SegmentTimeline 'S' element does not have a duration.")
 				return nil
 			}
 
@@ -857,7 +1203,6 @@ func (mpdProcessor *MpdProcessor) createTimeline(segmentTemplate *SegmentTemplat
 					startTime = lastEndTime
 				}
 			}
-			assert(startTime >= 0)
 			endTime := startTime + timePoints[i].Duration
 
 			// The end of the last segment may end before the start of the current
@@ -876,8 +1221,9 @@ func (mpdProcessor *MpdProcessor) createTimeline(segmentTemplate *SegmentTemplat
 					delta = lastEndTime - startTime
 				}
 
-				if delta/uint64(segmentTemplate.Timescale) >= GAP_OVERLAP_WARNING_THRESHOLD {
-					fmt.Printf("SegmentTimeline contains a large gap/overlap, the content may have errors in it.%s\r\n", timePoints[i])
+				if scaleTime(delta, segmentTemplate.Timescale) >= GAP_OVERLAP_WARNING_THRESHOLD {
+					mpdProcessor.report(SEVERITY_WARNING, DIAGNOSTIC_TIMELINE_GAP, joinPath(path, SegmentTimeline_TAG_NAME, fmt.Sprintf("%s[%d]", SegmentTimePoint_TAG_NAME, i)),
+						"SegmentTimeline contains a large gap/overlap, the content may have errors in it.")
 				}
 
 				timeline[len(timeline)-1].End = startTime
@@ -895,25 +1241,31 @@ func (mpdProcessor *MpdProcessor) createTimeline(segmentTemplate *SegmentTemplat
 /**
  * Builds a StreamInfo from a SegmentTemplate with a segment duration.
  *
+ * @param {string} path
  * @param {Mpd} mpd
  * @param {Period} period
  * @param {Representation} representation
  * @param {StreamInfo} streamInfo
  * @return {boolean} True on success.
  */
-func (mpdProcessor *MpdProcessor) buildStreamInfoFromSegmentDuration(mpd Mpd, period Period, representation Representation, streamInfo *StreamInfo) bool {
-	assert(representation.SegmentTemplate != nil)
-	assert(representation.SegmentTemplate.SegmentDuration != -1)
-	assert(representation.SegmentTemplate.Timescale > 0)
+func (mpdProcessor *MpdProcessor) buildStreamInfoFromSegmentDuration(path string, mpd Mpd, period Period, representation Representation, streamInfo *StreamInfo) bool {
+	mpdProcessor.assert(representation.SegmentTemplate.SegmentDuration != -1, path, "SegmentTemplate should have a segment duration")
+	if representation.SegmentTemplate.Timescale == 0 {
+		mpdProcessor.report(SEVERITY_ERROR, DIAGNOSTIC_INVALID_SEGMENT_TEMPLATE, path,
+			"SegmentTemplate@timescale must be positive.")
+		return false
+	}
 
 	if period.Start == -1 {
-		fmt.Printf("Cannot instantiate SegmentTemplate: the period's start time is unknown.%s\r\n", representation)
+		mpdProcessor.report(SEVERITY_ERROR, DIAGNOSTIC_INVALID_SEGMENT_TEMPLATE, path,
+			"Cannot instantiate SegmentTemplate: the period's start time is unknown.")
 		return false
 	}
 
 	segmentTemplate := representation.SegmentTemplate
 	if segmentTemplate.MediaUrlTemplate == "" {
-		fmt.Printf("Cannot instantiate SegmentTemplate: SegmentTemplate does not have a media URL template.%s\r\n", representation)
+		mpdProcessor.report(SEVERITY_ERROR, DIAGNOSTIC_INVALID_SEGMENT_TEMPLATE, path,
+			"Cannot instantiate SegmentTemplate: SegmentTemplate does not have a media URL template.")
 		return false
 	}
 
@@ -926,10 +1278,20 @@ func (mpdProcessor *MpdProcessor) buildStreamInfoFromSegmentDuration(mpd Mpd, pe
 	// numbers are relative to the start of |period| unless marked otherwise.
 	var earliestSegmentNumber int = -1
 	var currentSegmentNumber int = -1
+	var liveEdgeSegmentNumber int = -1
+
+	if mpd.Type == "dynamic" {
+		if pair := mpdProcessor.computeAvailableSegmentRange(path, mpd, period, representation); pair != nil {
+			earliestSegmentNumber = pair.Earliest
+			currentSegmentNumber = pair.Current
+			liveEdgeSegmentNumber = pair.LiveEdge
+			numSegmentsBeforeCurrentSegment = currentSegmentNumber - earliestSegmentNumber
+		}
+	} else {
+		earliestSegmentNumber = 1
+	}
 
-	earliestSegmentNumber = 1 // REMOVE THIS LINE WHEN HANDLING DYNAMIC
-
-	assert(earliestSegmentNumber == -1 || earliestSegmentNumber >= 0)
+	mpdProcessor.assert(earliestSegmentNumber == -1 || earliestSegmentNumber >= 0, path, "the earliest segment number should not be negative")
 
 	// The optimal number of segment references to generate starting from, and
 	// including, the current segment
@@ -938,91 +1300,239 @@ func (mpdProcessor *MpdProcessor) buildStreamInfoFromSegmentDuration(mpd Mpd, pe
 	// Note that if |earliestSegmentNumber| is undefined then the current segment
 	// is not available.
 	if earliestSegmentNumber >= 0 {
-		numSegmentsFromCurrentSegment = mpdProcessor.computeOptimalSegmentIndexSize(mpd, period, *segmentTemplate)
+		numSegmentsFromCurrentSegment = mpdProcessor.computeOptimalSegmentIndexSize(path, mpd, period, *segmentTemplate)
 		if numSegmentsFromCurrentSegment == -1 {
 			// An error has already been logged.
 			return false
 		}
+
+		// Always account for the segments up to the live edge.
+		if liveEdgeSegmentNumber != -1 {
+			numSegmentsFromCurrentSegment = Max(numSegmentsFromCurrentSegment, liveEdgeSegmentNumber-currentSegmentNumber+1)
+		}
 	}
 
-	fmt.Printf("numSegmentsBeforeCurrentSegment: %d, numSegmentsFromCurrentSegment: %d\r\n", numSegmentsBeforeCurrentSegment, numSegmentsFromCurrentSegment)
 	totalNumSegments := numSegmentsBeforeCurrentSegment + numSegmentsFromCurrentSegment
-	references := make([]*SegmentReference, 0)
+
+	// Don't go past the end of |period|.
+	if mpd.Type == "dynamic" && period.Duration > 0 && totalNumSegments > 0 {
+		scaledSegmentDuration := scaleTime(uint64(segmentTemplate.SegmentDuration), segmentTemplate.Timescale)
+		lastSegmentNumber := int((period.Duration + scaledSegmentDuration - 1) / scaledSegmentDuration)
+		totalNumSegments = Min(totalNumSegments, lastSegmentNumber-earliestSegmentNumber+1)
+	}
+
+	// Without a @timeShiftBufferDepth every segment since
+	// @availabilityStartTime is available, so keep only the latest ones.
+	if totalNumSegments > MAX_SEGMENT_REFERENCES {
+		mpdProcessor.report(SEVERITY_WARNING, DIAGNOSTIC_TOO_MANY_SEGMENTS, path,
+			"SegmentTemplate expands to %d segments; keeping the latest %d.", totalNumSegments, MAX_SEGMENT_REFERENCES)
+		earliestSegmentNumber += totalNumSegments - MAX_SEGMENT_REFERENCES
+		totalNumSegments = MAX_SEGMENT_REFERENCES
+		if currentSegmentNumber != -1 && currentSegmentNumber < earliestSegmentNumber {
+			currentSegmentNumber = earliestSegmentNumber
+		}
+	}
+
+	references := make([]*SegmentReference, 0, totalNumSegments)
 
 	for i := 0; i < totalNumSegments; i++ {
 		segmentNumber := i + earliestSegmentNumber
 
-		startTime := (segmentNumber - 1) * segmentTemplate.SegmentDuration
-		endTime := startTime + segmentTemplate.SegmentDuration
+		// Like those of a SegmentTimeline, the references' times are media
+		// times, i.e., they include the @presentationTimeOffset.
+		startTime := uint64(segmentNumber-1)*uint64(segmentTemplate.SegmentDuration) + segmentTemplate.PresentationTimeOffset
+		endTime := startTime + uint64(segmentTemplate.SegmentDuration)
 
-		scaledStartTime := uint32(startTime) / segmentTemplate.Timescale
-		scaledEndTime := uint32(endTime) / segmentTemplate.Timescale
+		scaledStartTime := scaleTime(startTime, segmentTemplate.Timescale)
+		scaledEndTime := scaleTime(endTime, segmentTemplate.Timescale)
 
 		absoluteSegmentNumber := (segmentNumber - 1) + segmentTemplate.StartNumber
 
 		// Compute the media URL template placeholder replacements.
 		segmentReplacement := absoluteSegmentNumber
-		timeReplacement := ((segmentNumber - 1) + (segmentTemplate.StartNumber - 1)) * segmentTemplate.SegmentDuration
+		timeReplacement := uint64((segmentNumber-1)+(segmentTemplate.StartNumber-1)) * uint64(segmentTemplate.SegmentDuration)
 
 		// Generate the media URL.
-		assert(segmentTemplate.MediaUrlTemplate != "")
 		var filledUrlTemplate = mpdProcessor.fillUrlTemplate(
+			path,
 			segmentTemplate.MediaUrlTemplate,
 			representation.Id,
 			segmentReplacement,
 			representation.Bandwidth,
-			uint64(timeReplacement))
+			timeReplacement)
 
 		if filledUrlTemplate == "" {
 			// An error has already been logged.
 			return false
 		}
 
-		mediaUrl := ""
-		if representation.BaseUrl != nil {
-			mediaUrl = representation.BaseUrl.Url + filledUrlTemplate
-		} else {
-			mediaUrl = filledUrlTemplate
-		}
-		segmentRef := NewSegmentReference(uint64(startTime), uint64(scaledStartTime), uint64(scaledEndTime), 0 /* startByte */, -1 /* endByte */, mediaUrl)
+		mediaUrls := buildUrls(representation.BaseUrls, filledUrlTemplate)
+		segmentRef := NewSegmentReference(startTime, scaledStartTime, scaledEndTime, 0 /* startByte */, -1 /* endByte */, mediaUrls)
 		references = append(references, &segmentRef)
 	}
 
 	// Generate an Initialization. If there are no references then assume that
 	// the intialization segment is not available.
-	var initialization Initialization
+	var initialization *Initialization
 	var err error
 
 	if segmentTemplate.InitializationUrlTemplate != "" && len(references) > 0 {
-		if initialization, err = mpdProcessor.generateInitialization(representation); err != nil {
+		if initialization, err = mpdProcessor.generateInitialization(path, representation); err != nil {
 			// An error has already been logged.
 			return false
 		}
 	}
 
 	// Set StreamInfo properties.
-	if segmentTemplate.PresentationTimeOffset != -1 {
-		streamInfo.TimestampOffset = -1 * int(uint32(segmentTemplate.PresentationTimeOffset)/segmentTemplate.Timescale)
-	}
+	streamInfo.TimestampOffset = -1 * scaleTime(segmentTemplate.PresentationTimeOffset, segmentTemplate.Timescale)
 
 	if mpd.Type == "dynamic" && len(references) > 0 {
-		assert(currentSegmentNumber == -1)
-		scaledSegmentDuration := uint32(segmentTemplate.SegmentDuration) / segmentTemplate.Timescale
-		streamInfo.CurrentSegmentStartTime = uint64(uint32(currentSegmentNumber-1) * scaledSegmentDuration)
+		mpdProcessor.assert(currentSegmentNumber != -1, path, "the current segment number should be known")
+		if currentSegmentNumber != -1 {
+			streamInfo.CurrentSegmentStartTime = scaleTime(uint64(currentSegmentNumber-1)*uint64(segmentTemplate.SegmentDuration)+segmentTemplate.PresentationTimeOffset, segmentTemplate.Timescale)
+		}
 	}
 
-	if segmentMetadataInfo, err := mpdProcessor.createSegmentMetadataInfo((Node)(&initialization)); err != nil {
+	if segmentMetadataInfo, err := mpdProcessor.createSegmentMetadataInfo(representation.BaseUrls, initialization); err != nil {
 		streamInfo.SegmentInitializationInfo = nil
 	} else {
 		streamInfo.SegmentInitializationInfo = &segmentMetadataInfo
 	}
 
-	segmentIdx := NewSegmentIndex(references)
-	streamInfo.SegmentIndex = &segmentIdx
+	streamInfo.SegmentIndex = mpdProcessor.createSegmentIndex(path, references)
 
 	return true
 }
 
+/**
+ * Computes the segment numbers of the earliest available segment, the current
+ * segment, i.e., the segment playback should start from, and the live edge,
+ * i.e., the latest available segment, of a dynamic Period with a segment
+ * duration. Segment numbers are relative to the start of |period|, starting
+ * at 1.
+ *
+ * @see ISO/IEC 23009-1:2014 section 5.3.9.5.3
+ *
+ * @param {string} path
+ * @param {Mpd} mpd
+ * @param {Period} period
+ * @param {Representation} representation
+ * @return {Pair} The segment numbers, or nil if no segment is available.
+ */
+func (mpdProcessor *MpdProcessor) computeAvailableSegmentRange(path string, mpd Mpd, period Period, representation Representation) *Pair {
+	segmentTemplate := representation.SegmentTemplate
+
+	if mpd.AvailabilityStartTime.IsZero() {
+		mpdProcessor.report(SEVERITY_ERROR, DIAGNOSTIC_INVALID_SEGMENT_TEMPLATE, path,
+			"Cannot instantiate SegmentTemplate: the dynamic MPD does not have an @availabilityStartTime.")
+		return nil
+	}
+
+	scaledSegmentDuration := scaleTime(uint64(segmentTemplate.SegmentDuration), segmentTemplate.Timescale)
+	if scaledSegmentDuration <= 0 {
+		mpdProcessor.report(SEVERITY_ERROR, DIAGNOSTIC_INVALID_SEGMENT_TEMPLATE, path,
+			"Cannot instantiate SegmentTemplate: the segment duration must be positive.")
+		return nil
+	}
+
+	// The following diagram shows the relationship between the values we use to
+	// compute the segment numbers. The diagram depicts the media presentation
+	// timeline. 0 corresponds to availabilityStartTime + period.start in
+	// wall-clock time, and CPT corresponds to the current wall-clock time.
+	//
+	// Legend:
+	// CPT: currentPresentationTime
+	// ATO: availabilityTimeOffset
+	// SD:  scaledSegmentDuration
+	// SPD: suggestedPresentationDelay
+	// MBT: minBufferTime
+	// TSB: timeShiftBufferDepth
+	//
+	// Time:
+	//   <---|--------+--------+--------+--------+--------+------|-------->
+	//       0     earliest  current                 live edge  CPT
+	//                            |--MBT--|--SPD--|      |---SD---|-ATO-|
+	//                  |<----------------TSB------------------>|
+	// Segments:
+	//   <---1--------2--------3--------4--------5--------6--------7------>
+	//
+	// Segment k spans [(k-1) * SD, k * SD) and is available from
+	//   SegmentAvailabilityStartTime = k * SD - ATO
+	// until
+	//   SegmentAvailabilityEndTime = (k + 1) * SD + TSB.
+	currentPresentationTime := mpdProcessor.now().Sub(mpd.AvailabilityStartTime.Add(period.Start))
+
+	// The number of |period|'s last segment, if its duration is known.
+	lastSegmentNumber := -1
+	if period.Duration > 0 {
+		lastSegmentNumber = int((period.Duration + scaledSegmentDuration - 1) / scaledSegmentDuration)
+	}
+
+	// The offsets of the SegmentTemplate and of the preferred BaseURL add up.
+	availabilityTimeOffset := segmentTemplate.AvailabilityTimeOffset
+	if baseUrls := SortBaseUrls(representation.BaseUrls); len(baseUrls) > 0 {
+		if availabilityTimeOffset == math.MaxInt64 || baseUrls[0].AvailabilityTimeOffset == math.MaxInt64 {
+			availabilityTimeOffset = math.MaxInt64
+		} else {
+			availabilityTimeOffset += baseUrls[0].AvailabilityTimeOffset
+		}
+	}
+
+	// The live edge is the latest segment whose availability start time has
+	// passed. With an infinite offset every segment of |period| is available.
+	liveEdgeSegmentNumber := lastSegmentNumber
+	if availabilityTimeOffset != math.MaxInt64 || lastSegmentNumber == -1 {
+		if availabilityTimeOffset == math.MaxInt64 {
+			availabilityTimeOffset = 0
+		}
+		liveEdgeSegmentNumber = -1
+		if latestAvailableTimestamp := currentPresentationTime + availabilityTimeOffset; latestAvailableTimestamp >= scaledSegmentDuration {
+			liveEdgeSegmentNumber = int(latestAvailableTimestamp / scaledSegmentDuration)
+		}
+		if lastSegmentNumber != -1 && liveEdgeSegmentNumber > lastSegmentNumber {
+			liveEdgeSegmentNumber = lastSegmentNumber
+		}
+	}
+	if liveEdgeSegmentNumber < 1 {
+		mpdProcessor.report(SEVERITY_INFO, DIAGNOSTIC_SEGMENT_UNAVAILABLE, path,
+			"The first segment is not available yet.")
+		return nil
+	}
+
+	// The earliest segment is the first whose availability end time has not
+	// passed, i.e., the first k with (k + 1) * SD + TSB > CPT. A
+	// @timeShiftBufferDepth of zero, i.e., a missing one, is unbounded.
+	earliestSegmentNumber := 1
+	if mpd.TimeShiftBufferDepth > 0 {
+		if earliestAvailableTimestamp := currentPresentationTime - mpd.TimeShiftBufferDepth; earliestAvailableTimestamp > 0 {
+			earliestSegmentNumber = Max(1, int(earliestAvailableTimestamp/scaledSegmentDuration))
+		}
+	}
+	if earliestSegmentNumber > liveEdgeSegmentNumber {
+		mpdProcessor.report(SEVERITY_INFO, DIAGNOSTIC_SEGMENT_UNAVAILABLE, path,
+			"The Period's segments are no longer available.")
+		return nil
+	}
+
+	// The current segment is the one which starts @suggestedPresentationDelay
+	// and @minBufferTime before the live edge.
+	currentSegmentNumber := 1
+	bestAvailableTimestamp := time.Duration(liveEdgeSegmentNumber-1)*scaledSegmentDuration - mpd.SuggestedPresentationDelay - mpd.MinBufferTime
+	if bestAvailableTimestamp > 0 {
+		currentSegmentNumber = int(bestAvailableTimestamp/scaledSegmentDuration) + 1
+	}
+	if currentSegmentNumber < earliestSegmentNumber {
+		// NOTE: @minBufferTime is large compared to @timeShiftBufferDepth, so we
+		// can't start as far back, for buffering, as we'd like.
+		currentSegmentNumber = earliestSegmentNumber
+		mpdProcessor.report(SEVERITY_INFO, DIAGNOSTIC_SEGMENT_UNAVAILABLE, path,
+			"The best available segment is no longer available.")
+	}
+
+	pair := NewPair(earliestSegmentNumber, currentSegmentNumber, liveEdgeSegmentNumber)
+	return &pair
+}
+
 /**
  * Computes the optimal number of segment references, N, for |period|.  If the
  * MPD is static then N * segmentDuration is the smallest multiple of
@@ -1035,78 +1545,103 @@ func (mpdProcessor *MpdProcessor) buildStreamInfoFromSegmentDuration(mpd Mpd, pe
  * such that the generated segment references will all be valid when it's time
  * to actually fetch the corresponding segments.
  *
+ * @param {string} path
  * @param {Mpd} mpd
  * @param {Period} period
  * @param {SegmentTemplate} segmentTemplate
  * @return {?number}
  * @private
  */
-func (mpdProcessor *MpdProcessor) computeOptimalSegmentIndexSize(mpd Mpd, period Period, segmentTemplate SegmentTemplate) int {
+func (mpdProcessor *MpdProcessor) computeOptimalSegmentIndexSize(path string, mpd Mpd, period Period, segmentTemplate SegmentTemplate) int {
 
-	assert(segmentTemplate.SegmentDuration == -1)
-	assert(segmentTemplate.Timescale > 0)
-
-	var duration int = -1
+	var duration time.Duration = -1
 	if mpd.Type == "static" {
 		if period.Duration != -1 {
 			duration = period.Duration
 		} else {
-			fmt.Printf(
-				"Cannot instantiate SegmentTemplate: the Period's duration is unknown.%s\r\n", period)
+			mpdProcessor.report(SEVERITY_ERROR, DIAGNOSTIC_UNKNOWN_DURATION, path,
+				"Cannot instantiate SegmentTemplate: the Period's duration is unknown.")
 			return -1
 		}
 	} else {
+		// Account for the segments which become available before the MPD is
+		// updated.
+		duration = MAX_SEGMENT_INDEX_SPAN
+		if mpd.MinUpdatePeriod > 0 && mpd.MinUpdatePeriod < duration {
+			duration = mpd.MinUpdatePeriod
+		}
+		if period.Duration > 0 && period.Duration < duration {
+			duration = period.Duration
+		}
+	}
+	mpdProcessor.assert(duration > 0, path, "duration should be positive")
+
+	if segmentTemplate.SegmentDuration <= 0 {
+		mpdProcessor.report(SEVERITY_ERROR, DIAGNOSTIC_INVALID_SEGMENT_TEMPLATE, path,
+			"Cannot instantiate SegmentTemplate: the segment duration must be positive.")
+		return -1
 	}
-	// assert(duration && (duration != Number.POSITIVE_INFINITY), "duration should not be zero or infinity!")
-	assert(duration == -1)
 
-	scaledSegmentDuration := float64(segmentTemplate.SegmentDuration) / float64(segmentTemplate.Timescale)
+	scaledSegmentDuration := scaleTime(uint64(segmentTemplate.SegmentDuration), segmentTemplate.Timescale)
+	if scaledSegmentDuration <= 0 {
+		mpdProcessor.report(SEVERITY_ERROR, DIAGNOSTIC_INVALID_SEGMENT_TEMPLATE, path,
+			"Cannot instantiate SegmentTemplate: the segment duration is shorter than a nanosecond.")
+		return -1
+	}
 
-	n := math.Ceil(float64(duration) / scaledSegmentDuration)
-	assert(n >= 1)
+	// The smallest n such that n * scaledSegmentDuration >= duration.
+	n := duration / scaledSegmentDuration
+	if duration%scaledSegmentDuration != 0 {
+		n++
+	}
+	if n > MAX_SEGMENT_REFERENCES {
+		mpdProcessor.report(SEVERITY_ERROR, DIAGNOSTIC_TOO_MANY_SEGMENTS, path,
+			"SegmentTemplate expands to more than %d segments.", MAX_SEGMENT_REFERENCES)
+		return -1
+	}
+	mpdProcessor.assert(n >= 1, path, "the segment index should not be empty")
 	return int(n)
 }
 
 /**
  * Generates an Initialization from a SegmentTemplate.
  *
+ * @param {string} path
  * @param {Representation} representation
  * @return {Initialization} An Initialization on success, null
  *     if no initialization URL template exists or an error occurred.
  */
-func (mpdProcessor *MpdProcessor) generateInitialization(representation Representation) (Initialization, error) {
-	assert(representation.SegmentTemplate != nil)
-
-	initialization := Initialization{}
+func (mpdProcessor *MpdProcessor) generateInitialization(path string, representation Representation) (*Initialization, error) {
+	initialization := &Initialization{}
 
 	segmentTemplate := representation.SegmentTemplate
-	assert(segmentTemplate.InitializationUrlTemplate != "")
 	if segmentTemplate.InitializationUrlTemplate == "" {
-		return initialization, errors.New("segment template initialization url template is missing")
+		return nil, errors.New("segment template initialization url template is missing")
 	}
 
 	// $Number$ and $Time$ cannot be present in an initialization URL template.
-	filledUrlTemplate := mpdProcessor.fillUrlTemplate(segmentTemplate.InitializationUrlTemplate, representation.Id, 0, representation.Bandwidth, 0)
+	filledUrlTemplate := mpdProcessor.fillUrlTemplate(path, segmentTemplate.InitializationUrlTemplate, representation.Id, 0, representation.Bandwidth, 0)
 
 	if filledUrlTemplate == "" {
 		// An error has already been logged.
-		return initialization, errors.New("could not fill initialization url template")
+		return nil, errors.New("could not fill initialization url template")
 	}
 
-	if representation.BaseUrl != nil && filledUrlTemplate != "" {
-		initialization.Url = representation.BaseUrl.Url + filledUrlTemplate
-	} else {
-		initialization.Url = filledUrlTemplate
-	}
+	// The URL is relative to the Representation's BaseURLs.
+	initialization.Url = filledUrlTemplate
 
 	return initialization, nil
 }
 
+// var re = /\$(RepresentationID|Number|Bandwidth|Time)?(?:%0([0-9]+)d)?\$/g;
+var urlTemplateRegexp = regexp.MustCompile("\\$(RepresentationID|Number|Bandwidth|Time)?(?:%0([0-9]+)d)?\\$")
+
 /**
  * Fills a SegmentTemplate URL template.
  *
  * @see ISO/IEC 23009-1:2014 section 5.3.9.4.4
  *
+ * @param {string} path
  * @param {string} urlTemplate
  * @param {?string} representationId
  * @param {?number} number
@@ -1115,28 +1650,25 @@ func (mpdProcessor *MpdProcessor) generateInitialization(representation Represen
  * @return {string} A URL on success; null if the resulting URL contains
  *     illegal characters.
  */
-func (mpdProcessor *MpdProcessor) fillUrlTemplate(urlTemplate string, representationId string, number int, bandwidth uint32, time uint64) string {
+func (mpdProcessor *MpdProcessor) fillUrlTemplate(path string, urlTemplate string, representationId string, number int, bandwidth uint32, time uint64) string {
 	/** @type {!Object.<string, ?number|?string>} */
-	// fmt.Printf("In FillUrlTemplate, urlTemplate: %s, representationId: %s, number: %d, bandwidth: %d, time: %d\r\n", urlTemplate, representationId, number, bandwidth, time)
 	valueTable := make(map[string]string)
 	valueTable["$RepresentationID$"] = representationId
 	valueTable["$Number$"] = strconv.Itoa(number)
 	valueTable["$Bandwidth$"] = strconv.FormatUint(uint64(bandwidth), 10)
 	valueTable["$Time$"] = strconv.FormatUint(time, 10)
 
-	re := regexp.MustCompile("\\$(RepresentationID|Number|Bandwidth|Time)?(?:%0([0-9]+)d)?\\$")
-	// var re = /\$(RepresentationID|Number|Bandwidth|Time)?(?:%0([0-9]+)d)?\$/g;
-	url := re.ReplaceAllStringFunc(urlTemplate, func(match string) string {
+	url := urlTemplateRegexp.ReplaceAllStringFunc(urlTemplate, func(match string) string {
 		if match == "$$" {
 			return "$"
 		}
 
 		value, ok := valueTable[match]
-		assert(ok == true)
 
 		// Note that |value| may be 0 or ''.
 		if ok == false {
-			fmt.Printf("URL template does not have an available substitution for identifier %s\r\n", match)
+			mpdProcessor.report(SEVERITY_WARNING, DIAGNOSTIC_URL_TEMPLATE, path,
+				"URL template does not have an available substitution for identifier %s", match)
 			return match
 		}
 
@@ -1151,10 +1683,11 @@ func (mpdProcessor *MpdProcessor) fillUrlTemplate(urlTemplate string, representa
  * Creates a SegmentMetadataInfo from either a RepresentationIndex or an
  * Initialization.
  *
+ * @param {!Array.<!BaseUrl>} baseUrls The BaseURLs the URL is relative to.
  * @param {RepresentationIndex| Initialization} urlTypeObject
  * @return {SegmentMetadataInfo}
  */
-func (mpdProcessor *MpdProcessor) createSegmentMetadataInfo(urlTypeObject Node) (SegmentMetadataInfo, error) {
+func (mpdProcessor *MpdProcessor) createSegmentMetadataInfo(baseUrls []*BaseUrl, urlTypeObject Node) (SegmentMetadataInfo, error) {
 	segmentMetadataInfo := NewSegmentMetadataInfo()
 
 	if urlTypeObject == nil {
@@ -1166,12 +1699,20 @@ func (mpdProcessor *MpdProcessor) createSegmentMetadataInfo(urlTypeObject Node)
 
 	switch obj := urlTypeObject.(type) {
 	case *RepresentationIndex:
+		if obj == nil {
+			return segmentMetadataInfo, errors.New("missing url type object")
+		}
+		url = obj.Url
+		r = obj.Range
 	case *Initialization:
+		if obj == nil {
+			return segmentMetadataInfo, errors.New("missing url type object")
+		}
 		url = obj.Url
 		r = obj.Range
 	}
 
-	segmentMetadataInfo.Url = url
+	segmentMetadataInfo.Urls = buildUrls(baseUrls, url)
 
 	if r != nil {
 		segmentMetadataInfo.StartByte = r.Begin
@@ -2,14 +2,699 @@ package mpd
 
 import (
 	"fmt"
+	"math"
+	"reflect"
 	"testing"
+	"time"
 )
 
+func TestProcessDiagnostics(t *testing.T) {
+	content := `<MPD type="static" mediaPresentationDuration="PT10S">
+  <Period>
+    <AdaptationSet mimeType="video/mp4">
+      <SegmentTemplate timescale="1000" duration="2000" media="$RepresentationID$/$Number$.m4s"/>
+      <Representation id="low" bandwidth="100000"/>
+      <Representation id="high" bandwidth="200000" mimeType="video/webm"/>
+    </AdaptationSet>
+    <AdaptationSet id="audio" mimeType="audio/mp4">
+      <Representation id="aac" bandwidth="64000"/>
+    </AdaptationSet>
+    <AdaptationSet mimeType="video/mp4">
+      <Representation bandwidth="100000"/>
+      <Representation bandwidth="200000">
+        <EssentialProperty schemeIdUri="urn:example:unsupported"/>
+        <SegmentTemplate timescale="1000" duration="2000" media="$Number$.m4s"/>
+      </Representation>
+      <Representation bandwidth="300000">
+        <SegmentTemplate timescale="1000" duration="2000" media="$Number$.m4s"/>
+      </Representation>
+      <Representation bandwidth="400000" mimeType="video/webm">
+        <SegmentTemplate timescale="1000" duration="2000" media="$Number$.m4s"/>
+      </Representation>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+
+	mpd, _, err := ParseMpdBytes([]byte(content), "http://example.com/manifest.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logged := 0
+	mpdProcessor := NewMpdProcessor()
+	mpdProcessor.Logger = LoggerFunc(func(diagnostic Diagnostic) { logged++ })
+	diagnostics := mpdProcessor.Process(mpd)
+
+	if logged != len(diagnostics) {
+		t.Errorf("expecting the logger to receive %d diagnostics, got %d", len(diagnostics), logged)
+	}
+
+	// Paths keep each element's index in the document, even after earlier
+	// siblings are removed.
+	expected := [][2]string{
+		{DIAGNOSTIC_INCONSISTENT_MIME_TYPE, "Period[0]/AdaptationSet[0]/Representation[id=high]"},
+		{DIAGNOSTIC_MISSING_SEGMENT_INFO, "Period[0]/AdaptationSet[id=audio]/Representation[id=aac]"},
+		{DIAGNOSTIC_MISSING_SEGMENT_INFO, "Period[0]/AdaptationSet[2]/Representation[0]"},
+		{DIAGNOSTIC_UNSUPPORTED_ESSENTIAL_PROPERTY, "Period[0]/AdaptationSet[2]/Representation[1]"},
+		{DIAGNOSTIC_INCONSISTENT_MIME_TYPE, "Period[0]/AdaptationSet[2]/Representation[3]"},
+	}
+
+	for _, codeAndPath := range expected {
+		code, path := codeAndPath[0], codeAndPath[1]
+		found := false
+		for _, diagnostic := range diagnostics {
+			if diagnostic.Code == code && diagnostic.Path == path {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expecting a %s diagnostic for %s, got %v", code, path, diagnostics)
+		}
+	}
+}
+
+func TestProcessSegmentTimes(t *testing.T) {
+	content := `<MPD type="static" mediaPresentationDuration="PT2M6.5S">
+  <Period>
+    <AdaptationSet mimeType="audio/mp4">
+      <SegmentTemplate timescale="1000" duration="1968" presentationTimeOffset="500" media="$Number$.m4s"/>
+      <Representation id="audio" bandwidth="64000"/>
+    </AdaptationSet>
+    <AdaptationSet mimeType="video/mp4">
+      <SegmentTemplate timescale="90000" media="$Time$.m4s">
+        <SegmentTimeline>
+          <S t="0" d="180180" r="2"/>
+        </SegmentTimeline>
+      </SegmentTemplate>
+      <Representation id="video" bandwidth="1000000"/>
+    </AdaptationSet>
+    <AdaptationSet mimeType="text/vtt">
+      <Representation id="text" bandwidth="1000">
+        <SegmentList>
+          <SegmentURL media="text.vtt"/>
+        </SegmentList>
+      </Representation>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+
+	mpd, _, err := ParseMpdBytes([]byte(content), "http://example.com/manifest.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mpd.MediaPresentationDuration != 126500*time.Millisecond {
+		t.Errorf("expecting media presentation duration to be 2m6.5s, got %s", mpd.MediaPresentationDuration)
+	}
+
+	mpdProcessor := NewMpdProcessor()
+	for _, diagnostic := range mpdProcessor.Process(mpd) {
+		if diagnostic.Severity == SEVERITY_ERROR {
+			t.Errorf("unexpected diagnostic %s", diagnostic)
+		}
+	}
+
+	periodInfo := mpdProcessor.ManifestInfo.PeriodInfos[0]
+	if periodInfo.Duration != 126500*time.Millisecond {
+		t.Errorf("expecting period duration to be 2m6.5s, got %s", periodInfo.Duration)
+	}
+
+	audio := periodInfo.StreamSetInfos[0].StreamInfos[0]
+	if audio.TimestampOffset != -500*time.Millisecond {
+		t.Errorf("expecting timestamp offset to be -500ms, got %s", audio.TimestampOffset)
+	}
+
+	references := audio.SegmentIndex.References
+	if len(references) != 65 {
+		t.Fatalf("expecting 65 audio references, got %d", len(references))
+	}
+	// Reference times are media times, which include the
+	// presentationTimeOffset.
+	if references[64].StartTime != (64*1968+500)*time.Millisecond || references[64].EndTime != (65*1968+500)*time.Millisecond {
+		t.Errorf("expecting the last audio reference to span [126.452s, 128.42s), got [%s, %s)", references[64].StartTime, references[64].EndTime)
+	}
+
+	references = periodInfo.StreamSetInfos[1].StreamInfos[0].SegmentIndex.References
+	if len(references) != 3 {
+		t.Fatalf("expecting 3 video references, got %d", len(references))
+	}
+	if references[2].StartTime != 4004*time.Millisecond || references[2].EndTime != 6006*time.Millisecond {
+		t.Errorf("expecting the last video reference to span [4.004s, 6.006s), got [%s, %s)", references[2].StartTime, references[2].EndTime)
+	}
+
+	// A SegmentList without a duration has a single, unbounded segment.
+	references = periodInfo.StreamSetInfos[2].StreamInfos[0].SegmentIndex.References
+	if len(references) != 1 || references[0].StartTime != 0 || references[0].EndTime != UNBOUNDED_END_TIME {
+		t.Errorf("expecting a single unbounded text reference, got %v", references)
+	}
+}
+
+func TestProcessContentProtection(t *testing.T) {
+	content := `<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" xmlns:cenc="urn:mpeg:cenc:2013" type="static" mediaPresentationDuration="PT10S">
+  <Period>
+    <AdaptationSet mimeType="video/mp4">
+      <ContentProtection schemeIdUri="urn:mpeg:dash:mp4protection:2011" value="cenc" cenc:default_KID="10000000-1000-1000-1000-10000000000A"/>
+      <ContentProtection schemeIdUri="urn:uuid:EDEF8BA9-79D6-4ACE-A3C8-27DCD51D21ED">
+        <cenc:pssh>AAAAAA==</cenc:pssh>
+      </ContentProtection>
+      <SegmentTemplate timescale="1000" duration="2000" media="$RepresentationID$/$Number$.m4s"/>
+      <Representation id="low" bandwidth="100000"/>
+      <Representation id="high" bandwidth="200000">
+        <ContentProtection schemeIdUri="urn:mpeg:dash:mp4protection:2011" value="cenc" cenc:default_KID="20000000-1000-1000-1000-100000000001"/>
+        <ContentProtection schemeIdUri="urn:uuid:9a04f079-9840-4286-ab92-e65be0885f95"/>
+      </Representation>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+
+	mpd, _, err := ParseMpdBytes([]byte(content), "http://example.com/manifest.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	adaptationSet := mpd.Periods[0].AdaptationSets[0]
+	if len(adaptationSet.ContentProtections) != 2 {
+		t.Fatalf("expecting 2 content protections, got %d", len(adaptationSet.ContentProtections))
+	}
+
+	widevine := adaptationSet.ContentProtections[1]
+	if len(widevine.Children) != 1 || widevine.Children[0].Namespace != CENC_NAMESPACE || widevine.Children[0].Name != "pssh" || widevine.Children[0].Text != "AAAAAA==" {
+		t.Errorf("expecting the cenc:pssh child to be preserved, got %v", widevine.Children)
+	}
+
+	if representation := adaptationSet.Representations[0]; len(representation.ContentProtections) != 2 {
+		t.Errorf("expecting the low representation to inherit 2 content protections, got %d", len(representation.ContentProtections))
+	}
+
+	if kid := adaptationSet.Representations[1].ContentProtections[0].DefaultKid; kid != "20000000-1000-1000-1000-100000000001" {
+		t.Errorf("expecting the high representation's default KID, got %s", kid)
+	}
+
+	mpdProcessor := NewMpdProcessor()
+	mpdProcessor.Process(mpd)
+
+	drmSchemes := mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[0].DrmSchemes
+	if len(drmSchemes) != 1 {
+		t.Fatalf("expecting 1 common drm scheme, got %v", drmSchemes)
+	}
+
+	if drmSchemes[0].SchemeIdUri != "urn:mpeg:dash:mp4protection:2011" || drmSchemes[0].Value != "cenc" {
+		t.Errorf("expecting the common scheme to be mp4protection, got %s", drmSchemes[0].SchemeIdUri)
+	}
+
+	if len(drmSchemes[0].DefaultKids) != 2 || drmSchemes[0].DefaultKids[0] != "10000000-1000-1000-1000-10000000000a" {
+		t.Errorf("expecting 2 lower-cased default KIDs, got %v", drmSchemes[0].DefaultKids)
+	}
+}
+
+func TestProcessDescriptors(t *testing.T) {
+	content := `<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="static" mediaPresentationDuration="PT10S">
+  <Period>
+    <AdaptationSet mimeType="video/mp4">
+      <Role schemeIdUri="urn:mpeg:dash:role:2011" value="main"/>
+      <Role schemeIdUri="urn:mpeg:dash:role:2011" value="caption"/>
+      <Accessibility schemeIdUri="urn:tva:metadata:cs:AudioPurposeCS:2007" value="1"/>
+      <SupplementalProperty schemeIdUri="urn:mpeg:dash:adaptation-set-switching:2016" value="2"/>
+      <SegmentTemplate timescale="1000" duration="2000" media="$RepresentationID$/$Number$.m4s"/>
+      <Representation id="sdr" bandwidth="100000">
+        <EssentialProperty schemeIdUri="urn:mpeg:mpegB:cicp:TransferCharacteristics" value="1"/>
+      </Representation>
+      <Representation id="trick" bandwidth="200000">
+        <EssentialProperty schemeIdUri="http://dashif.org/guidelines/trickmode" value="1"/>
+      </Representation>
+      <Representation id="alternatives" bandwidth="300000">
+        <Role schemeIdUri="urn:mpeg:dash:role:2011" value="alternate"/>
+        <EssentialProperty id="1" schemeIdUri="urn:example:unknown"/>
+        <EssentialProperty id="1" schemeIdUri="urn:mpeg:mpegB:cicp:ColourPrimaries" value="1"/>
+      </Representation>
+    </AdaptationSet>
+    <AdaptationSet mimeType="audio/mp4">
+      <EssentialProperty schemeIdUri="urn:example:unknown"/>
+      <SegmentTemplate timescale="1000" duration="2000" media="$RepresentationID$/$Number$.m4s"/>
+      <Representation id="audio" bandwidth="100000"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+
+	mpd, _, err := ParseMpdBytes([]byte(content), "http://example.com/manifest.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	adaptationSet := mpd.Periods[0].AdaptationSets[0]
+	if len(adaptationSet.Roles) != 2 || !adaptationSet.Main {
+		t.Errorf("expecting 2 roles including main, got %v", adaptationSet.Roles)
+	}
+	if len(adaptationSet.Representations[0].Roles) != 2 || !adaptationSet.Representations[0].Main {
+		t.Errorf("expecting the sdr representation to inherit the roles")
+	}
+	if roles := adaptationSet.Representations[2].Roles; len(roles) != 1 || roles[0].Value != "alternate" || adaptationSet.Representations[2].Main {
+		t.Errorf("expecting the alternatives representation to have its own role, got %v", roles)
+	}
+
+	mpdProcessor := NewMpdProcessor()
+	diagnostics := mpdProcessor.Process(mpd)
+
+	streamSetInfos := mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos
+	if len(streamSetInfos) != 1 {
+		t.Fatalf("expecting the audio stream set to be removed, got %d stream sets", len(streamSetInfos))
+	}
+
+	streamSetInfo := streamSetInfos[0]
+	if len(streamSetInfo.Roles) != 2 || len(streamSetInfo.Accessibilities) != 1 || len(streamSetInfo.SupplementalProperties) != 1 {
+		t.Errorf("unexpected stream set descriptors %+v", streamSetInfo)
+	}
+
+	if len(streamSetInfo.StreamInfos) != 2 || streamSetInfo.StreamInfos[0].Id != "sdr" || streamSetInfo.StreamInfos[1].Id != "alternatives" {
+		t.Fatalf("expecting the trick representation to be removed, got %d streams", len(streamSetInfo.StreamInfos))
+	}
+
+	streamInfo := streamSetInfo.StreamInfos[0]
+	if len(streamInfo.EssentialProperties) != 1 || len(streamInfo.SupplementalProperties) != 1 || len(streamInfo.Roles) != 2 {
+		t.Errorf("unexpected stream descriptors %+v", streamInfo)
+	}
+
+	count := 0
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Code == DIAGNOSTIC_UNSUPPORTED_ESSENTIAL_PROPERTY {
+			count++
+		}
+	}
+	if count != 2 {
+		t.Errorf("expecting 2 %s diagnostics, got %v", DIAGNOSTIC_UNSUPPORTED_ESSENTIAL_PROPERTY, diagnostics)
+	}
+}
+
+func TestProcessEventStreams(t *testing.T) {
+	content := `<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="static" mediaPresentationDuration="PT60S">
+  <Period id="1" duration="PT30S">
+    <AdaptationSet mimeType="video/mp4">
+      <SegmentTemplate timescale="1000" duration="2000" media="$Number$.m4s"/>
+      <Representation id="video" bandwidth="100000"/>
+    </AdaptationSet>
+  </Period>
+  <Period id="2" duration="PT30S">
+    <EventStream schemeIdUri="urn:example:chapters" value="1" timescale="1000" presentationTimeOffset="5000">
+      <Event presentationTime="15000" duration="2500" id="2">Chapter 2</Event>
+      <Event presentationTime="5000" id="1" messageData="Chapter 1"/>
+    </EventStream>
+    <EventStream schemeIdUri="urn:scte:scte35:2014:xml+bin">
+      <Event presentationTime="7" duration="30" id="10">
+        <Signal xmlns="http://www.scte.org/schemas/35/2016"><Binary>/DAlAAAAAAAAAP/wFAUAAAABf+/+AAAAAH4AKTLgAAEAAAAAdu2Y0Q==</Binary></Signal>
+      </Event>
+    </EventStream>
+    <AdaptationSet mimeType="video/mp4">
+      <InbandEventStream schemeIdUri="urn:mpeg:dash:event:2012" value="1"/>
+      <SegmentTemplate timescale="1000" duration="2000" media="$Number$.m4s"/>
+      <Representation id="video" bandwidth="100000">
+        <InbandEventStream schemeIdUri="urn:scte:scte35:2013:bin"/>
+      </Representation>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+
+	mpd, _, err := ParseMpdBytes([]byte(content), "http://example.com/manifest.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eventStream := mpd.Periods[1].EventStreams[0]
+	if eventStream.Timescale != 1000 || eventStream.PresentationTimeOffset != 5000 || len(eventStream.Events) != 2 {
+		t.Errorf("unexpected EventStream %+v", eventStream)
+	}
+	if event := eventStream.Events[0]; event.Id != "2" || event.Text != "Chapter 2" || event.Duration != 2500 {
+		t.Errorf("unexpected Event %+v", event)
+	}
+	if event := eventStream.Events[1]; event.MessageData != "Chapter 1" || event.Duration != ^uint64(0) {
+		t.Errorf("unexpected Event %+v", event)
+	}
+	if event := mpd.Periods[1].EventStreams[1].Events[0]; len(event.Children) != 1 || event.Children[0].Name != "Signal" {
+		t.Errorf("expecting the Event's children to be preserved, got %v", event.Children)
+	}
+
+	mpdProcessor := NewMpdProcessor()
+	mpdProcessor.Process(mpd)
+
+	periodInfo := mpdProcessor.ManifestInfo.PeriodInfos[1]
+	if len(periodInfo.Events) != 3 {
+		t.Fatalf("expecting 3 events, got %d", len(periodInfo.Events))
+	}
+
+	expected := []struct {
+		id        string
+		startTime time.Duration
+		duration  time.Duration
+	}{
+		{"1", 30 * time.Second, -1},
+		{"10", 37 * time.Second, 30 * time.Second},
+		{"2", 40 * time.Second, 2500 * time.Millisecond},
+	}
+	for i, e := range expected {
+		eventInfo := periodInfo.Events[i]
+		if eventInfo.Id != e.id || eventInfo.StartTime != e.startTime || eventInfo.Duration != e.duration {
+			t.Errorf("expecting event %s at %s for %s, got %s at %s for %s",
+				e.id, e.startTime, e.duration, eventInfo.Id, eventInfo.StartTime, eventInfo.Duration)
+		}
+	}
+
+	streamSetInfo := periodInfo.StreamSetInfos[0]
+	if len(streamSetInfo.InbandEventStreams) != 1 || len(streamSetInfo.StreamInfos[0].InbandEventStreams) != 2 {
+		t.Errorf("expecting 1 and 2 InbandEventStreams, got %d and %d",
+			len(streamSetInfo.InbandEventStreams), len(streamSetInfo.StreamInfos[0].InbandEventStreams))
+	}
+}
+
+func TestProcessScte35(t *testing.T) {
+	content := `<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" xmlns:scte35="http://www.scte.org/schemas/35/2016" type="static" mediaPresentationDuration="PT60S">
+  <Period id="1" duration="PT20S">
+    <AdaptationSet mimeType="video/mp4">
+      <SegmentTemplate timescale="1000" duration="2000" media="$Number$.m4s"/>
+      <Representation id="video" bandwidth="100000"/>
+    </AdaptationSet>
+  </Period>
+  <Period id="2" duration="PT40S">
+    <EventStream schemeIdUri="urn:scte:scte35:2014:xml+bin" timescale="90000" presentationTimeOffset="8589934000">
+      <Event presentationTime="8589934000" id="1">
+        <scte35:Signal><scte35:Binary>/DAlAAAAAABkAP/wFAUAAAABf+/+AA247P4AKTLgAAEAAAAAjGpnZw==</scte35:Binary></scte35:Signal>
+      </Event>
+      <Event presentationTime="8589934000" id="2">
+        <scte35:Signal><scte35:Binary>/DAlAAAAAABkAP/wFAUAAAABf+/+AA247P4AKTLgAAEAAAAAjGpnZA==</scte35:Binary></scte35:Signal>
+      </Event>
+    </EventStream>
+    <EventStream schemeIdUri="urn:scte:scte35:2013:xml" timescale="10" presentationTimeOffset="20">
+      <Event presentationTime="50" duration="100" id="3">
+        <scte35:SpliceInfoSection ptsAdjustment="0" tier="4095">
+          <scte35:TimeSignal><scte35:SpliceTime ptsTime="450000"/></scte35:TimeSignal>
+          <scte35:SegmentationDescriptor segmentationEventId="8" segmentationDuration="900000" segmentationTypeId="52" segmentNum="1" segmentsExpected="2">
+            <scte35:SegmentationUpid segmentationUpidType="12" segmentationUpidFormat="hexbinary">41424344</scte35:SegmentationUpid>
+          </scte35:SegmentationDescriptor>
+        </scte35:SpliceInfoSection>
+      </Event>
+    </EventStream>
+    <EventStream schemeIdUri="urn:scte:scte35:2013:bin">
+      <Event presentationTime="30" id="4">/DAyAAAAAAAAAP/wBQb+AAQesAAcAhpDVUVJAAAAB3//AAAUmXAMBEFCQ0Q0AQIAAB0SBuA=</Event>
+    </EventStream>
+    <AdaptationSet mimeType="video/mp4">
+      <SegmentTemplate timescale="1000" duration="2000" media="$Number$.m4s"/>
+      <Representation id="video" bandwidth="100000"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+
+	mpd, diagnostics, err := ParseMpdBytes([]byte(content), "http://example.com/manifest.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(diagnostics) != 1 || diagnostics[0].Code != DIAGNOSTIC_INVALID_SCTE35 {
+		t.Errorf("expecting an %s diagnostic for the bad CRC, got %v", DIAGNOSTIC_INVALID_SCTE35, diagnostics)
+	}
+
+	spliceInsert := mpd.Periods[1].EventStreams[0].Events[0].SpliceInfoSection.SpliceInsert
+	if spliceInsert == nil || spliceInsert.SpliceEventId != 1 || !spliceInsert.OutOfNetworkIndicator || spliceInsert.BreakDuration == nil || spliceInsert.BreakDuration.Duration != 30*SCTE35_TIMESCALE {
+		t.Errorf("unexpected splice_insert %+v", spliceInsert)
+	}
+
+	segmentationDescriptors := mpd.Periods[1].EventStreams[2].Events[0].SpliceInfoSection.SegmentationDescriptors
+	if len(segmentationDescriptors) != 1 {
+		t.Fatalf("expecting 1 segmentation descriptor, got %d", len(segmentationDescriptors))
+	}
+	segmentationDescriptor := segmentationDescriptors[0]
+	if segmentationDescriptor.SegmentationEventId != 7 || segmentationDescriptor.SegmentationTypeId != 0x34 ||
+		segmentationDescriptor.SegmentationUpidType != 0x0c || string(segmentationDescriptor.SegmentationUpid) != "ABCD" ||
+		segmentationDescriptor.SegmentNum != 1 || segmentationDescriptor.SegmentsExpected != 2 {
+		t.Errorf("unexpected segmentation descriptor %+v", segmentationDescriptor)
+	}
+
+	xmlSection := mpd.Periods[1].EventStreams[1].Events[0].SpliceInfoSection
+	if xmlSection == nil || xmlSection.SpliceCommandType != TIME_SIGNAL || len(xmlSection.SegmentationDescriptors) != 1 ||
+		string(xmlSection.SegmentationDescriptors[0].SegmentationUpid) != "ABCD" {
+		t.Errorf("unexpected XML splice_info_section %+v", xmlSection)
+	}
+
+	mpdProcessor := NewMpdProcessor()
+	mpdProcessor.Process(mpd)
+
+	events := mpdProcessor.ManifestInfo.PeriodInfos[1].Events
+	expected := []struct {
+		id         string
+		startTime  time.Duration
+		spliceTime time.Duration
+		duration   time.Duration
+	}{
+		// The splice time wraps around the 33-bit PTS.
+		{"1", 20 * time.Second, 30 * time.Second, 30 * time.Second},
+		{"2", 20 * time.Second, -1, -1},
+		// The splice times are mapped from any timescale.
+		{"3", 23 * time.Second, 23 * time.Second, 10 * time.Second},
+		{"4", 50 * time.Second, 23 * time.Second, 15 * time.Second},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("expecting %d events, got %d", len(expected), len(events))
+	}
+	for i, e := range expected {
+		eventInfo := events[i]
+		if eventInfo.Id != e.id || eventInfo.StartTime != e.startTime || eventInfo.SpliceTime != e.spliceTime || eventInfo.Duration != e.duration {
+			t.Errorf("expecting event %s at %s, splicing at %s, for %s, got %s at %s, splicing at %s, for %s",
+				e.id, e.startTime, e.spliceTime, e.duration, eventInfo.Id, eventInfo.StartTime, eventInfo.SpliceTime, eventInfo.Duration)
+		}
+	}
+}
+
+func TestProcessBaseUrls(t *testing.T) {
+	content := `<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" xmlns:dvb="urn:dvb:dash:profile:dvb-dash:2014" type="static" mediaPresentationDuration="PT4S">
+  <BaseURL serviceLocation="a" dvb:priority="2" dvb:weight="1">http://a.example.com/</BaseURL>
+  <BaseURL serviceLocation="b" dvb:priority="1" dvb:weight="1">http://b.example.com/</BaseURL>
+  <BaseURL serviceLocation="c" dvb:priority="1" dvb:weight="3" availabilityTimeOffset="INF">http://c.example.com/</BaseURL>
+  <Period>
+    <AdaptationSet mimeType="video/mp4">
+      <SegmentTemplate timescale="1000" duration="2000" initialization="$RepresentationID$/init.mp4" media="$RepresentationID$/$Number$.m4s"/>
+      <Representation id="video" bandwidth="100000"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+
+	mpd, _, err := ParseMpdBytes([]byte(content), "http://example.com/manifest.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(mpd.BaseUrls) != 3 {
+		t.Fatalf("expecting 3 base urls, got %d", len(mpd.BaseUrls))
+	}
+	if baseUrl := mpd.BaseUrls[2]; baseUrl.ServiceLocation != "c" || baseUrl.Priority != 1 || baseUrl.Weight != 3 || baseUrl.AvailabilityTimeOffset != time.Duration(math.MaxInt64) {
+		t.Errorf("unexpected base url %+v", baseUrl)
+	}
+
+	representation := mpd.Periods[0].AdaptationSets[0].Representations[0]
+	if len(representation.BaseUrls) != 3 {
+		t.Errorf("expecting the representation to inherit 3 base urls, got %d", len(representation.BaseUrls))
+	}
+
+	mpdProcessor := NewMpdProcessor()
+	mpdProcessor.Process(mpd)
+
+	streamInfo := mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[0].StreamInfos[0]
+	expected := []string{
+		"http://c.example.com/video/1.m4s",
+		"http://b.example.com/video/1.m4s",
+		"http://a.example.com/video/1.m4s",
+	}
+	if urls := streamInfo.SegmentIndex.References[0].Urls; !reflect.DeepEqual(urls, expected) {
+		t.Errorf("expecting urls %v, got %v", expected, urls)
+	}
+
+	if info := streamInfo.SegmentInitializationInfo; info == nil || len(info.Urls) != 3 || info.Urls[0] != "http://c.example.com/video/init.mp4" {
+		t.Errorf("unexpected initialization info %+v", info)
+	}
+}
+
+func TestProcessUrlResolution(t *testing.T) {
+	content := `<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="static" mediaPresentationDuration="PT4S">
+  <Period>
+    <BaseURL>content/</BaseURL>
+    <AdaptationSet mimeType="video/mp4">
+      <BaseURL>../video/</BaseURL>
+      <SegmentTemplate timescale="1000" duration="2000" initialization="$RepresentationID$/init.mp4" media="http://cdn.example.com/$RepresentationID$/$Number$.m4s"/>
+      <Representation id="template" bandwidth="100000"/>
+    </AdaptationSet>
+    <AdaptationSet mimeType="audio/mp4">
+      <BaseURL>http://audio.example.com/a/b/</BaseURL>
+      <Representation id="list" bandwidth="100000">
+        <BaseURL>../c/</BaseURL>
+        <SegmentList timescale="1000" duration="2000">
+          <Initialization sourceURL="init.mp4"/>
+          <SegmentURL media="1.m4s"/>
+          <SegmentURL media="/root/2.m4s"/>
+        </SegmentList>
+      </Representation>
+    </AdaptationSet>
+    <AdaptationSet mimeType="video/mp4">
+      <SegmentList timescale="1000" duration="2000">
+        <Initialization sourceURL="init.mp4"/>
+        <SegmentURL media="seg1.m4s"/>
+      </SegmentList>
+      <Representation id="inherited-list" bandwidth="100000">
+        <BaseURL>v/</BaseURL>
+      </Representation>
+    </AdaptationSet>
+    <AdaptationSet mimeType="video/mp4">
+      <SegmentBase>
+        <Initialization sourceURL="init.mp4"/>
+      </SegmentBase>
+      <Representation id="inherited-base" bandwidth="100000">
+        <BaseURL>b/</BaseURL>
+      </Representation>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+
+	mpd, _, err := ParseMpdBytes([]byte(content), "http://example.com/dash/manifest.mpd?token=a/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A SegmentList or SegmentBase declared above the Representation still
+	// resolves against the Representation's BaseURLs.
+	segmentBase := mpd.Periods[0].AdaptationSets[3].Representations[0].SegmentBase
+	if segmentBase == nil || len(segmentBase.BaseUrls) != 1 || segmentBase.BaseUrls[0].Url != "http://example.com/dash/content/b/" {
+		t.Errorf("expecting the inherited SegmentBase to have the representation's base urls, got %+v", segmentBase)
+	}
+
+	mpdProcessor := NewMpdProcessor()
+	mpdProcessor.Process(mpd)
+
+	streamSetInfos := mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos
+	if len(streamSetInfos) != 4 {
+		t.Fatalf("expecting 4 stream sets, got %d", len(streamSetInfos))
+	}
+
+	video := streamSetInfos[0].StreamInfos[0]
+	if urls := video.SegmentIndex.References[0].Urls; !reflect.DeepEqual(urls, []string{"http://cdn.example.com/template/1.m4s"}) {
+		t.Errorf("unexpected media urls %v", urls)
+	}
+	if info := video.SegmentInitializationInfo; info == nil || !reflect.DeepEqual(info.Urls, []string{"http://example.com/dash/video/template/init.mp4"}) {
+		t.Errorf("unexpected initialization info %+v", info)
+	}
+
+	audio := streamSetInfos[1].StreamInfos[0]
+	if len(audio.SegmentIndex.References) != 2 {
+		t.Fatalf("expecting 2 references, got %d", len(audio.SegmentIndex.References))
+	}
+	if urls := audio.SegmentIndex.References[0].Urls; !reflect.DeepEqual(urls, []string{"http://audio.example.com/a/c/1.m4s"}) {
+		t.Errorf("unexpected media urls %v", urls)
+	}
+	if urls := audio.SegmentIndex.References[1].Urls; !reflect.DeepEqual(urls, []string{"http://audio.example.com/root/2.m4s"}) {
+		t.Errorf("unexpected media urls %v", urls)
+	}
+	if info := audio.SegmentInitializationInfo; info == nil || !reflect.DeepEqual(info.Urls, []string{"http://audio.example.com/a/c/init.mp4"}) {
+		t.Errorf("unexpected initialization info %+v", info)
+	}
+
+	inherited := streamSetInfos[2].StreamInfos[0]
+	if urls := inherited.SegmentIndex.References[0].Urls; !reflect.DeepEqual(urls, []string{"http://example.com/dash/content/v/seg1.m4s"}) {
+		t.Errorf("unexpected media urls %v", urls)
+	}
+	if info := inherited.SegmentInitializationInfo; info == nil || !reflect.DeepEqual(info.Urls, []string{"http://example.com/dash/content/v/init.mp4"}) {
+		t.Errorf("unexpected initialization info %+v", info)
+	}
+}
+
+func TestProcessLiveSegmentDuration(t *testing.T) {
+	content := `<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="dynamic" availabilityStartTime="2020-01-01T00:00:00Z"
+    minimumUpdatePeriod="PT4S" timeShiftBufferDepth="PT30S" suggestedPresentationDelay="PT4S" minBufferTime="PT2S">
+  <Period start="PT10S">
+    <AdaptationSet mimeType="video/mp4">
+      <SegmentTemplate timescale="1000" duration="2000" media="$Number$.m4s"/>
+      <Representation id="video" bandwidth="100000"/>
+    </AdaptationSet>
+    <AdaptationSet mimeType="audio/mp4">
+      <SegmentTemplate timescale="1000" duration="2000" media="$Number$.m4s" availabilityTimeOffset="1.5"/>
+      <Representation id="audio" bandwidth="100000"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+
+	mpd, _, err := ParseMpdBytes([]byte(content), "http://example.com/manifest.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 61 seconds into the Period.
+	now := time.Date(2020, time.January, 1, 0, 1, 11, 0, time.UTC)
+
+	mpdProcessor := NewMpdProcessor()
+	mpdProcessor.clock = func() time.Time { return now }
+	mpdProcessor.Process(mpd)
+
+	streamSetInfos := mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos
+
+	// Segment 15, [28s, 30s), is available until 62s. The live edge is segment
+	// 30, [58s, 60s). The current segment starts 6s before the live edge.
+	video := streamSetInfos[0].StreamInfos[0]
+	references := video.SegmentIndex.References
+	if len(references) != 16 {
+		t.Fatalf("expecting 16 references, got %d", len(references))
+	}
+	if references[0].StartTime != 28*time.Second || references[0].Urls[0] != "http://example.com/15.m4s" {
+		t.Errorf("unexpected earliest reference %+v", references[0])
+	}
+	if references[15].StartTime != 58*time.Second || references[15].Urls[0] != "http://example.com/30.m4s" {
+		t.Errorf("unexpected live edge reference %+v", references[15])
+	}
+	if video.CurrentSegmentStartTime != 52*time.Second {
+		t.Errorf("expecting the current segment to start at 52s, got %s", video.CurrentSegmentStartTime)
+	}
+
+	// The availabilityTimeOffset makes segment 31 available at 60.5s.
+	audio := streamSetInfos[1].StreamInfos[0]
+	if last := audio.SegmentIndex.References[len(audio.SegmentIndex.References)-1]; last.StartTime != 60*time.Second {
+		t.Errorf("expecting the live edge to start at 60s, got %s", last.StartTime)
+	}
+
+	// At 62s segment 15 has just left the time shift buffer.
+	mpdProcessor.clock = func() time.Time { return now.Add(time.Second) }
+	mpdProcessor.Process(mpd)
+	references = mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[0].StreamInfos[0].SegmentIndex.References
+	if len(references) != 16 {
+		t.Fatalf("expecting 16 references, got %d", len(references))
+	}
+	if references[0].StartTime != 30*time.Second {
+		t.Errorf("expecting the earliest reference to start at 30s, got %s", references[0].StartTime)
+	}
+
+	// Before the Period starts there are no segments.
+	mpdProcessor.clock = func() time.Time { return now.Add(-62 * time.Second) }
+	mpdProcessor.Process(mpd)
+	if references := mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[0].StreamInfos[0].SegmentIndex.References; len(references) != 0 {
+		t.Errorf("expecting no references, got %d", len(references))
+	}
+
+	// Without a @timeShiftBufferDepth, only the latest MAX_SEGMENT_REFERENCES
+	// segments are kept.
+	mpd.TimeShiftBufferDepth = 0
+	mpd.Periods[0].AdaptationSets = mpd.Periods[0].AdaptationSets[:1]
+	mpdProcessor.clock = func() time.Time { return now.Add(30 * 24 * time.Hour) }
+	diagnostics := mpdProcessor.Process(mpd)
+	video = mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[0].StreamInfos[0]
+	references = video.SegmentIndex.References
+	if len(references) != MAX_SEGMENT_REFERENCES {
+		t.Fatalf("expecting %d references, got %d", MAX_SEGMENT_REFERENCES, len(references))
+	}
+	last := references[len(references)-1]
+	if last.EndTime != (30*24*3600+60)*time.Second || references[0].StartTime != last.EndTime-MAX_SEGMENT_REFERENCES*2*time.Second {
+		t.Errorf("expecting the references to end at the live edge, got [%s, %s)", references[0].StartTime, last.EndTime)
+	}
+	if len(diagnostics) == 0 || diagnostics[0].Code != DIAGNOSTIC_TOO_MANY_SEGMENTS {
+		t.Errorf("expecting a %s diagnostic, got %v", DIAGNOSTIC_TOO_MANY_SEGMENTS, diagnostics)
+	}
+}
+
 func TestMPDProcessingExample1(t *testing.T) {
 	var mpd *Mpd
 	var err error
 
-	if mpd, err = ParseMpd("http://sdk.streamrail.com/pepsi/cdn/0.0.1/3a5dd80efc3a867e55c69996c7f22051f6c3b94d/dash/manifest.mpd"); err != nil {
+	if mpd, _, err = ParseMpd("http://sdk.streamrail.com/pepsi/cdn/0.0.1/3a5dd80efc3a867e55c69996c7f22051f6c3b94d/dash/manifest.mpd"); err != nil {
 		t.Error(err)
 	}
 
@@ -52,12 +737,12 @@ func TestMPDProcessingExample1(t *testing.T) {
 		t.Errorf("expecting 424 references, got %d", len(mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[1].StreamInfos[0].SegmentIndex.References))
 	}
 
-	if mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[0].StreamInfos[0].SegmentIndex.References[423].Url != "http://sdk.streamrail.com/pepsi/cdn/0.0.1/3a5dd80efc3a867e55c69996c7f22051f6c3b94d/dash/470k/audio/und/seg-424.m4f" {
-		t.Errorf("expecting last reference to point to %s, got:%s", "http://sdk.streamrail.com/pepsi/cdn/0.0.1/3a5dd80efc3a867e55c69996c7f22051f6c3b94d/dash/470k/audio/und/seg-424.m4f", mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[0].StreamInfos[0].SegmentIndex.References[423].Url)
+	if mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[0].StreamInfos[0].SegmentIndex.References[423].Urls[0] != "http://sdk.streamrail.com/pepsi/cdn/0.0.1/3a5dd80efc3a867e55c69996c7f22051f6c3b94d/dash/470k/audio/und/seg-424.m4f" {
+		t.Errorf("expecting last reference to point to %s, got:%s", "http://sdk.streamrail.com/pepsi/cdn/0.0.1/3a5dd80efc3a867e55c69996c7f22051f6c3b94d/dash/470k/audio/und/seg-424.m4f", mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[0].StreamInfos[0].SegmentIndex.References[423].Urls[0])
 	}
 
-	if mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[1].StreamInfos[0].SegmentIndex.References[423].Url != "http://sdk.streamrail.com/pepsi/cdn/0.0.1/3a5dd80efc3a867e55c69996c7f22051f6c3b94d/dash/470k/video/1/seg-424.m4f" {
-		t.Errorf("expecting last reference to point to %s, got:%s", "http://sdk.streamrail.com/pepsi/cdn/0.0.1/3a5dd80efc3a867e55c69996c7f22051f6c3b94d/dash/470k/video/1/seg-424.m4f", mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[1].StreamInfos[0].SegmentIndex.References[423].Url)
+	if mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[1].StreamInfos[0].SegmentIndex.References[423].Urls[0] != "http://sdk.streamrail.com/pepsi/cdn/0.0.1/3a5dd80efc3a867e55c69996c7f22051f6c3b94d/dash/470k/video/1/seg-424.m4f" {
+		t.Errorf("expecting last reference to point to %s, got:%s", "http://sdk.streamrail.com/pepsi/cdn/0.0.1/3a5dd80efc3a867e55c69996c7f22051f6c3b94d/dash/470k/video/1/seg-424.m4f", mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[1].StreamInfos[0].SegmentIndex.References[423].Urls[0])
 	}
 }
 
@@ -65,7 +750,7 @@ func MPDProcessingExample2(t *testing.T) {
 	var mpd *Mpd
 	var err error
 
-	if mpd, err = ParseMpd("http://sdk.streamrail.com/pepsi/cdn/0.0.1/925e302c164efcbe473977cff27771a3e1184902/dash/manifest.mpd"); err != nil {
+	if mpd, _, err = ParseMpd("http://sdk.streamrail.com/pepsi/cdn/0.0.1/925e302c164efcbe473977cff27771a3e1184902/dash/manifest.mpd"); err != nil {
 		t.Error(err)
 	}
 
@@ -117,22 +802,113 @@ func MPDProcessingExample2(t *testing.T) {
 	}
 
 	for _, ref := range mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[0].StreamInfos[0].SegmentIndex.References {
-		fmt.Printf("Url: %s\r\n", ref.Url)
+		fmt.Printf("Url: %s\r\n", ref.Urls[0])
+	}
+
+	if mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[0].StreamInfos[0].SegmentIndex.References[136].Urls[0] != "http://sdk.streamrail.com/pepsi/cdn/0.0.1/925e302c164efcbe473977cff27771a3e1184902/dash/700k/audio/und/seg-137.m4f" {
+		t.Errorf("expecting last reference to point to %s, got:%s", "http://sdk.streamrail.com/pepsi/cdn/0.0.1/925e302c164efcbe473977cff27771a3e1184902/dash/700k/audio/und/seg-137.m4f", mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[0].StreamInfos[0].SegmentIndex.References[136].Urls[0])
+	}
+
+	if mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[1].StreamInfos[0].SegmentIndex.References[136].Urls[0] != "http://sdk.streamrail.com/pepsi/cdn/0.0.1/925e302c164efcbe473977cff27771a3e1184902/dash/700k/video/1/seg-137.m4f" {
+		t.Errorf("expecting last reference to point to %s, got:%s", "http://sdk.streamrail.com/pepsi/cdn/0.0.1/925e302c164efcbe473977cff27771a3e1184902/dash/700k/video/1/seg-137.m4f", mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[1].StreamInfos[0].SegmentIndex.References[136].Urls[0])
 	}
 
-	if mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[0].StreamInfos[0].SegmentIndex.References[136].Url != "http://sdk.streamrail.com/pepsi/cdn/0.0.1/925e302c164efcbe473977cff27771a3e1184902/dash/700k/audio/und/seg-137.m4f" {
-		t.Errorf("expecting last reference to point to %s, got:%s", "http://sdk.streamrail.com/pepsi/cdn/0.0.1/925e302c164efcbe473977cff27771a3e1184902/dash/700k/audio/und/seg-137.m4f", mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[0].StreamInfos[0].SegmentIndex.References[136].Url)
+	if mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[1].StreamInfos[1].SegmentIndex.References[136].Urls[0] != "http://sdk.streamrail.com/pepsi/cdn/0.0.1/925e302c164efcbe473977cff27771a3e1184902/dash/1200k/video/1/seg-137.m4f" {
+		t.Errorf("expecting last reference to point to %s, got:%s", "http://sdk.streamrail.com/pepsi/cdn/0.0.1/925e302c164efcbe473977cff27771a3e1184902/dash/1200k/video/1/seg-137.m4f", mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[1].StreamInfos[0].SegmentIndex.References[136].Urls[0])
 	}
 
-	if mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[1].StreamInfos[0].SegmentIndex.References[136].Url != "http://sdk.streamrail.com/pepsi/cdn/0.0.1/925e302c164efcbe473977cff27771a3e1184902/dash/700k/video/1/seg-137.m4f" {
-		t.Errorf("expecting last reference to point to %s, got:%s", "http://sdk.streamrail.com/pepsi/cdn/0.0.1/925e302c164efcbe473977cff27771a3e1184902/dash/700k/video/1/seg-137.m4f", mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[1].StreamInfos[0].SegmentIndex.References[136].Url)
+	if mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[1].StreamInfos[2].SegmentIndex.References[136].Urls[0] != "http://sdk.streamrail.com/pepsi/cdn/0.0.1/925e302c164efcbe473977cff27771a3e1184902/dash/1531k/video/1/seg-137.m4f" {
+		t.Errorf("expecting last reference to point to %s, got:%s", "http://sdk.streamrail.com/pepsi/cdn/0.0.1/925e302c164efcbe473977cff27771a3e1184902/dash/1531k/video/1/seg-137.m4f", mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[1].StreamInfos[0].SegmentIndex.References[136].Urls[0])
+	}
+}
+
+func TestSegmentIndex(t *testing.T) {
+	newReferences := func(startTimes ...time.Duration) []*SegmentReference {
+		references := make([]*SegmentReference, 0, len(startTimes))
+		for _, startTime := range startTimes {
+			segmentReference := NewSegmentReference(0, startTime, startTime+2*time.Second, 0, -1, nil)
+			references = append(references, &segmentReference)
+		}
+		return references
+	}
+	startTimes := func(references []*SegmentReference) []time.Duration {
+		times := make([]time.Duration, 0, len(references))
+		for _, reference := range references {
+			times = append(times, reference.StartTime)
+		}
+		return times
 	}
 
-	if mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[1].StreamInfos[1].SegmentIndex.References[136].Url != "http://sdk.streamrail.com/pepsi/cdn/0.0.1/925e302c164efcbe473977cff27771a3e1184902/dash/1200k/video/1/seg-137.m4f" {
-		t.Errorf("expecting last reference to point to %s, got:%s", "http://sdk.streamrail.com/pepsi/cdn/0.0.1/925e302c164efcbe473977cff27771a3e1184902/dash/1200k/video/1/seg-137.m4f", mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[1].StreamInfos[0].SegmentIndex.References[136].Url)
+	segmentIndex := NewSegmentIndex(newReferences(0, 2*time.Second, 4*time.Second))
+	if err := segmentIndex.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if reference := segmentIndex.Find(3 * time.Second); reference == nil || reference.StartTime != 2*time.Second {
+		t.Errorf("Find(3s) returned %v", reference)
+	}
+	if reference := segmentIndex.Find(4 * time.Second); reference == nil || reference.StartTime != 4*time.Second {
+		t.Errorf("Find(4s) returned %v", reference)
+	}
+	if reference := segmentIndex.Find(6 * time.Second); reference != nil {
+		t.Errorf("Find(6s) returned %v", reference)
+	}
+
+	if between := startTimes(segmentIndex.Between(time.Second, 4*time.Second)); !reflect.DeepEqual(between, []time.Duration{0, 2 * time.Second}) {
+		t.Errorf("Between(1s, 4s) returned %v", between)
+	}
+
+	// The newer index is 1s ahead; it replaces the overlapping references.
+	newer := NewSegmentIndex(newReferences(5*time.Second, 7*time.Second))
+	newer.TimestampCorrection = time.Second
+	segmentIndex.Merge(newer)
+	if merged := startTimes(segmentIndex.References); !reflect.DeepEqual(merged, []time.Duration{0, 2 * time.Second, 4 * time.Second, 6 * time.Second}) {
+		t.Errorf("Merge produced %v", merged)
+	}
+
+	if delta := segmentIndex.Correct(time.Second); delta != time.Second || segmentIndex.References[0].StartTime != time.Second {
+		t.Errorf("Correct shifted the references by %s", delta)
+	}
+	if newer.References[0].StartTime != 5*time.Second {
+		t.Errorf("Correct modified a shared reference")
+	}
+
+	if evicted := segmentIndex.Evict(5 * time.Second); evicted != 2 || segmentIndex.References[0].StartTime != 5*time.Second {
+		t.Errorf("Evict(5s) removed %d references", evicted)
+	}
+
+	gap := NewSegmentIndex(newReferences(0, 3*time.Second))
+	if err := gap.Validate(); err == nil {
+		t.Errorf("expecting a gap error")
+	}
+
+	// A reference which is shifted to end at 0 stays bounded.
+	shifted := NewSegmentIndex(newReferences(0, 2*time.Second))
+	shifted.Correct(-2 * time.Second)
+	if err := shifted.Validate(); err != nil {
+		t.Errorf("expecting the shifted references to be valid, got %v", err)
+	}
+	if shifted.References[0].EndTime != 0 {
+		t.Errorf("expecting the first shifted reference to end at 0, got %s", shifted.References[0].EndTime)
+	}
+	if reference := shifted.Find(time.Second); reference == nil || reference.StartTime != 0 {
+		t.Errorf("Find(1s) returned %v", reference)
+	}
+	newer = NewSegmentIndex(newReferences(0, 2*time.Second))
+	newer.TimestampCorrection = -2 * time.Second
+	shifted.Merge(newer)
+	if merged := startTimes(shifted.References); !reflect.DeepEqual(merged, []time.Duration{-2 * time.Second, 0, 2 * time.Second}) {
+		t.Errorf("Merge produced %v", merged)
+	}
+	if err := shifted.Validate(); err != nil {
+		t.Errorf("expecting the merged references to be valid, got %v", err)
 	}
 
-	if mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[1].StreamInfos[2].SegmentIndex.References[136].Url != "http://sdk.streamrail.com/pepsi/cdn/0.0.1/925e302c164efcbe473977cff27771a3e1184902/dash/1531k/video/1/seg-137.m4f" {
-		t.Errorf("expecting last reference to point to %s, got:%s", "http://sdk.streamrail.com/pepsi/cdn/0.0.1/925e302c164efcbe473977cff27771a3e1184902/dash/1531k/video/1/seg-137.m4f", mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[1].StreamInfos[0].SegmentIndex.References[136].Url)
+	// An unbounded reference stays unbounded when shifted.
+	unbounded := NewSegmentReference(0, 0, UNBOUNDED_END_TIME, 0, -1, nil)
+	open := NewSegmentIndex([]*SegmentReference{&unbounded})
+	open.Correct(time.Second)
+	if reference := open.Find(time.Hour); reference == nil || reference.EndTime != UNBOUNDED_END_TIME {
+		t.Errorf("Find(1h) returned %v", reference)
 	}
 }
@@ -0,0 +1,55 @@
+package mpd
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+type MsprPro struct {
+	/**
+	 * The PlayReady Object, decoded from base64.
+	 * @type {Uint8Array}
+	 * @expose
+	 */
+	Pro []byte
+
+	/**
+	 * The PlayReady Object's WRMHEADER, or null if it could not be parsed.
+	 * @type {PlayReadyHeader}
+	 * @expose
+	 */
+	Header *PlayReadyHeader
+}
+
+/**
+ * Parses a "mspr:pro" tag. A malformed PlayReady Object is reported but does
+ * not fail the MPD.
+ * @param {!ContentProtection} parent The parent ContentProtection.
+ * @param {!Node} elem The mspr:pro XML element.
+ */
+func (msprPro *MsprPro) Parse(state *parseState, parent Node, elem element) error {
+	contents, err := getContents(elem)
+	if err != nil {
+		state.report(SEVERITY_WARNING, DIAGNOSTIC_INVALID_PLAYREADY_OBJECT, "mspr:pro is empty")
+		return nil
+	}
+
+	// Whitespace within base64 content is allowed.
+	contents = strings.Join(strings.Fields(contents), "")
+
+	if msprPro.Pro, err = base64.StdEncoding.DecodeString(contents); err != nil {
+		state.report(SEVERITY_WARNING, DIAGNOSTIC_INVALID_PLAYREADY_OBJECT, "mspr:pro is not valid base64: %s", err)
+		msprPro.Pro = nil
+		return nil
+	}
+
+	if msprPro.Header, err = ParsePlayReadyObject(msprPro.Pro); err != nil {
+		state.report(SEVERITY_WARNING, DIAGNOSTIC_INVALID_PLAYREADY_OBJECT, "%s", err)
+	}
+
+	return nil
+}
+
+func NewMsprPro() Node {
+	return &MsprPro{}
+}
@@ -1,13 +1,24 @@
 package mpd
 
+/**
+ * The segment numbers of a SegmentTemplate's available segments, relative to
+ * the start of the Period.
+ */
 type Pair struct {
+	/** The earliest segment which is still available. */
 	Earliest int
-	Current  int
+
+	/** The segment playback should start from. */
+	Current int
+
+	/** The latest segment which is available. */
+	LiveEdge int
 }
 
-func NewPair(earliestSegmentNumber, currentSegmentNumber int) Pair {
+func NewPair(earliestSegmentNumber, currentSegmentNumber, liveEdgeSegmentNumber int) Pair {
 	return Pair{
 		Earliest: earliestSegmentNumber,
 		Current:  currentSegmentNumber,
+		LiveEdge: liveEdgeSegmentNumber,
 	}
 }
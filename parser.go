@@ -1,23 +1,24 @@
 package mpd
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"net/http"
+	"math"
+	"os"
 	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
-
-	"github.com/moovweb/gokogiri"
-	"github.com/moovweb/gokogiri/xml"
 )
 
 const (
-	DEFAULT_MIN_BUFFER_TIME_              = 5
-	DEFAULT_SUGGESTED_PRESENTATION_DELAY_ = 1
+	DEFAULT_MIN_BUFFER_TIME_              = 5 * time.Second
+	DEFAULT_SUGGESTED_PRESENTATION_DELAY_ = 1 * time.Second
 )
 
 // MPD tag names --------------------------------------------------------------
@@ -35,16 +36,40 @@ const (
 
 	Role_TAG_NAME = "Role"
 
+	Accessibility_TAG_NAME = "Accessibility"
+
+	Rating_TAG_NAME = "Rating"
+
+	Viewpoint_TAG_NAME = "Viewpoint"
+
+	EssentialProperty_TAG_NAME = "EssentialProperty"
+
+	SupplementalProperty_TAG_NAME = "SupplementalProperty"
+
+	EventStream_TAG_NAME = "EventStream"
+
+	InbandEventStream_TAG_NAME = "InbandEventStream"
+
+	Event_TAG_NAME = "Event"
+
 	ContentComponent_TAG_NAME = "ContentComponent"
 
 	Representation_TAG_NAME = "Representation"
 
 	ContentProtection_TAG_NAME = "ContentProtection"
 
-	CencPssh_TAG_NAME = "cenc:pssh"
+	CencPssh_TAG_NAME = "pssh"
+
+	MsprPro_TAG_NAME = "pro"
 
 	BaseUrl_TAG_NAME = "BaseURL"
 
+	Location_TAG_NAME = "Location"
+
+	PatchLocation_TAG_NAME = "PatchLocation"
+
+	UtcTiming_TAG_NAME = "UTCTiming"
+
 	SegmentBase_TAG_NAME = "SegmentBase"
 
 	RepresentationIndex_TAG_NAME = "RepresentationIndex"
@@ -63,69 +88,238 @@ const (
 )
 
 type Node interface {
-	Parse(parent Node, elem xml.Node)
+	Parse(state *parseState, parent Node, elem element) error
 }
 
 type Cloneable interface {
 	Clone() Node
 }
 
-func downloadMpd(url string) ([]byte, error) {
+/**
+ * An MpdParser downloads and parses MPDs. All downloads, including those of
+ * remote elements, go through its Fetcher, and all diagnostics go to its
+ * Logger, if any. The zero value is usable, and never touches the network.
+ */
+type MpdParser struct {
+	/**
+	 * If nil, nothing is downloaded: ParseMpd fails, and remote elements are
+	 * kept, with their Xlink, instead of being resolved.
+	 * @type {Fetcher}
+	 */
+	Fetcher Fetcher
 
-	res, err := http.Get(url)
+	/** @type {Logger} */
+	Logger Logger
 
-	if err != nil {
-		fmt.Printf("failed to download mpd %s, error:%s\r\n", url, err)
-		return nil, err
+	/**
+	 * How many remote documents may be chained when resolving xlinks. If 0,
+	 * DEFAULT_XLINK_MAX_DEPTH.
+	 * @type {number}
+	 */
+	MaxXlinkDepth int
+
+	/**
+	 * How many remote documents may be downloaded when resolving the xlinks of
+	 * one MPD, or of one call to ResolveXlink. If 0, DEFAULT_XLINK_MAX_FETCHES.
+	 * @type {number}
+	 */
+	MaxXlinkFetches int
+}
+
+func NewMpdParser() MpdParser {
+	return MpdParser{
+		Fetcher:         NewHttpFetcher(nil),
+		Logger:          nil,
+		MaxXlinkDepth:   DEFAULT_XLINK_MAX_DEPTH,
+		MaxXlinkFetches: DEFAULT_XLINK_MAX_FETCHES,
 	}
+}
+
+// ParseMpd downloads the MPD at |url| using the default Fetcher and parses
+// it. Relative URLs within the MPD are resolved against |url|.
+func ParseMpd(url string) (*Mpd, []Diagnostic, error) {
+	mpdParser := NewMpdParser()
+	return mpdParser.ParseMpd(context.Background(), url)
+}
+
+// ParseMpdReader reads an MPD from |r| and parses it. |baseURL| is the
+// location of the MPD and is used to resolve relative URLs within it. As with
+// ParseMpdBytes, nothing is downloaded.
+func ParseMpdReader(r io.Reader, baseURL string) (*Mpd, []Diagnostic, error) {
+	mpdParser := MpdParser{}
+	return mpdParser.ParseMpdReader(r, baseURL)
+}
 
-	mpd, err := ioutil.ReadAll(res.Body)
-	res.Body.Close()
+// ParseMpdBytes parses an MPD which is already in memory. |baseURL| is the
+// location of the MPD and is used to resolve relative URLs within it. Nothing
+// is downloaded: remote elements, even those with xlink:actuate="onLoad", keep
+// their Xlink. Use MpdParser.ParseMpdBytes with a Fetcher to resolve them
+// while parsing.
+func ParseMpdBytes(data []byte, baseURL string) (*Mpd, []Diagnostic, error) {
+	mpdParser := MpdParser{}
+	return mpdParser.ParseMpdBytes(data, baseURL)
+}
+
+// ParseMpd downloads the MPD at |url| using |mpdParser.Fetcher| and parses
+// it. Relative URLs within the MPD are resolved against the URL the MPD was
+// retrieved from, i.e., |url| after any redirects.
+func (mpdParser *MpdParser) ParseMpd(ctx context.Context, url string) (*Mpd, []Diagnostic, error) {
+	if mpdParser.Fetcher == nil {
+		return nil, nil, errors.New("mpd parser has no fetcher")
+	}
 
+	// download mpd file
+	res, err := mpdParser.Fetcher.Fetch(ctx, url, nil)
 	if err != nil {
-		fmt.Printf("failed to read response body, error: %s\r\n", err)
-		return nil, err
+		reporter := newDiagnosticReporter(mpdParser.Logger)
+		reporter.report(SEVERITY_ERROR, DIAGNOSTIC_DOWNLOAD_FAILED, "", "failed to download mpd %s: %s", url, err)
+		return nil, reporter.diagnostics, err
 	}
 
-	return mpd, nil
+	if res.Url != "" {
+		url = res.Url
+	}
+
+	return mpdParser.parseMpdBytes(ctx, res.Data, url)
 }
 
-func ParseMpd(url string) (*Mpd, error) {
+// RefreshMpd downloads and parses the next update of |mpd|, from its first
+// Location if it advertises one, or else from the URL it was retrieved from.
+func (mpdParser *MpdParser) RefreshMpd(ctx context.Context, mpd *Mpd) (*Mpd, []Diagnostic, error) {
+	return mpdParser.ParseMpd(ctx, mpd.UpdateUrl())
+}
 
-	// download mpd file
-	content, err := downloadMpd(url)
+// ParseMpdReader reads an MPD from |r| and parses it. |baseURL| is the
+// location of the MPD and is used to resolve relative URLs within it.
+func (mpdParser *MpdParser) ParseMpdReader(r io.Reader, baseURL string) (*Mpd, []Diagnostic, error) {
+	content, err := ioutil.ReadAll(r)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
+	return mpdParser.ParseMpdBytes(content, baseURL)
+}
+
+// ParseMpdBytes parses an MPD which is already in memory. |baseURL| is the
+// location of the MPD and is used to resolve relative URLs within it. Remote
+// elements with xlink:actuate="onLoad" are downloaded through
+// |mpdParser.Fetcher|, unless it is nil.
+func (mpdParser *MpdParser) ParseMpdBytes(data []byte, baseURL string) (*Mpd, []Diagnostic, error) {
+	return mpdParser.parseMpdBytes(context.Background(), data, baseURL)
+}
+
+func (mpdParser *MpdParser) parseMpdBytes(ctx context.Context, data []byte, baseURL string) (*Mpd, []Diagnostic, error) {
+	state := newParseState(mpdParser.Logger)
+
 	// initialize mpd types registry.
-	initTypeRegistry()
+	typeRegistryOnce.Do(initTypeRegistry)
 
 	// load xml
-	doc, err := gokogiri.ParseXml(content)
+	elem, err := parseXml(data)
 	if err != nil {
-		fmt.Printf("failed to parse xml file, error: %s\n", err)
-		return nil, err
+		state.report(SEVERITY_ERROR, DIAGNOSTIC_INVALID_XML, "failed to parse xml: %s", err)
+		return nil, state.reporter.diagnostics, err
 	}
 
-	// free the resources when done
-	defer doc.Free()
-
 	// construct a virtual parent for the MPD to use in resolving relative URLs.
-	parent := FakeNode{BaseUrl: &BaseUrl{Url: url[:strings.LastIndex(url, "/")+1]}}
+	// Its URL is the MPD's directory, without the MPD's query string.
+	baseUrl := NewBaseUrl().(*BaseUrl)
+	baseUrl.Url = resolveUrl(baseURL, ".")
+	parent := FakeNode{Url: baseURL, BaseUrls: []*BaseUrl{baseUrl}}
 
-	fmt.Println("Start parsing")
-	ok := false
-	root, ok := parseChild(parent, doc, Mpd_TAG_NAME).(*Mpd)
+	if elem.Name() != Mpd_TAG_NAME {
+		state.report(SEVERITY_ERROR, DIAGNOSTIC_MISSING_ELEMENT, "the document's root element is %s, not MPD", elem.Name())
+		return nil, state.reporter.diagnostics, errors.New("failed to parse mpd")
+	}
 
-	if ok {
-		return root, nil
-	} else {
-		return nil, errors.New("failed to parse mpd")
+	// Replace remote elements which have xlink:actuate="onLoad".
+	resolver := mpdParser.newXlinkResolver(ctx, state)
+	resolver.resolveChildren(elem, baseURL, nil)
+
+	root := NewMpd().(*Mpd)
+	if err = root.Parse(state, parent, elem); err != nil {
+		return nil, state.reporter.diagnostics, err
+	}
+
+	return root, state.reporter.diagnostics, nil
+}
+
+/**
+ * The state shared by every Parse call while parsing a single MPD.
+ */
+type parseState struct {
+	/** @type {*diagnosticReporter} */
+	reporter *diagnosticReporter
+
+	/**
+	 * The path of the element currently being parsed, relative to the MPD
+	 * element.
+	 * @type {!Array.<string>}
+	 */
+	path []string
+}
+
+func newParseState(logger Logger) *parseState {
+	return &parseState{
+		reporter: newDiagnosticReporter(logger),
+		path:     make([]string, 0),
+	}
+}
+
+func (state *parseState) report(severity Severity, code string, format string, args ...interface{}) {
+	state.reporter.report(severity, code, joinPath(state.path...), format, args...)
+}
+
+/**
+ * Reports an error at the current path and returns it as a ParseError.
+ */
+func (state *parseState) errorf(format string, args ...interface{}) error {
+	err := &ParseError{
+		Path:    joinPath(state.path...),
+		Message: fmt.Sprintf(format, args...),
+	}
+	state.reporter.report(SEVERITY_ERROR, DIAGNOSTIC_INVALID_ELEMENT, err.Path, "%s", err.Message)
+	return err
+}
+
+func (state *parseState) push(segment string) {
+	state.path = append(state.path, segment)
+}
+
+func (state *parseState) pop() {
+	state.path = state.path[:len(state.path)-1]
+}
+
+/**
+ * A ParseError describes an element which could not be parsed.
+ */
+type ParseError struct {
+	/**
+	 * The offending element, e.g., "Period[0]/AdaptationSet[1]".
+	 * @type {string}
+	 */
+	Path string
+
+	/** @type {string} */
+	Message string
+}
+
+func (parseError *ParseError) Error() string {
+	if parseError.Path == "" {
+		return fmt.Sprintf("failed to parse mpd: %s", parseError.Message)
 	}
+	return fmt.Sprintf("failed to parse %s: %s", parseError.Path, parseError.Message)
 }
 
 func PrintMPD(root Node, ident int) {
+	FprintMPD(os.Stdout, root, ident)
+}
+
+/**
+ * Prints the tree under |root| to |w|, as PrintMPD does to the standard
+ * output.
+ */
+func FprintMPD(w io.Writer, root Node, ident int) {
 
 	// Check for zero value
 	v := reflect.ValueOf(root)
@@ -136,16 +330,16 @@ func PrintMPD(root Node, ident int) {
 	s := reflect.ValueOf(root).Elem()
 	typeOfT := s.Type()
 
-	printTabs(ident)
-	fmt.Println(typeOfT)
+	printTabs(w, ident)
+	fmt.Fprintln(w, typeOfT)
 
 	// Scan type fields
 	for i := 0; i < s.NumField(); i++ {
 		f := s.Field(i)
 
-		printTabs(ident + 1)
+		printTabs(w, ident+1)
 		// fmt.Printf("%d: %s %s = %v\n", i, typeOfT.Field(i).Name, f.Type(), f.Interface())
-		fmt.Printf("%s = %v\n", typeOfT.Field(i).Name, f.Interface())
+		fmt.Fprintf(w, "%s = %v\n", typeOfT.Field(i).Name, f.Interface())
 
 		ok := false
 		var n Node
@@ -156,20 +350,20 @@ func PrintMPD(root Node, ident int) {
 			for i := 0; i < f.Len(); i++ {
 				e := f.Index(i)
 				if n, ok = e.Interface().(Node); ok == true {
-					PrintMPD(n, ident+1)
+					FprintMPD(w, n, ident+1)
 				}
 			}
 		}
 
 		if n, ok = f.Interface().(Node); ok == true {
-			PrintMPD(n, ident+1)
+			FprintMPD(w, n, ident+1)
 		}
 	}
 }
 
-func printTabs(ammount int) {
+func printTabs(w io.Writer, ammount int) {
 	for i := 0; i < ammount; i++ {
-		fmt.Printf("\t")
+		fmt.Fprint(w, "\t")
 	}
 }
 
@@ -178,6 +372,10 @@ type constructor func() Node
 // var typeRegistry = make(map[string]reflect.Type)
 var typeRegistry = make(map[string]constructor)
 
+// typeRegistryOnce guards initTypeRegistry so that MPDs can be parsed
+// concurrently.
+var typeRegistryOnce sync.Once
+
 func initTypeRegistry() {
 	typeRegistry[Mpd_TAG_NAME] = NewMpd
 
@@ -185,18 +383,42 @@ func initTypeRegistry() {
 
 	typeRegistry[AdaptationSet_TAG_NAME] = NewAdaptationSet
 
-	typeRegistry[Role_TAG_NAME] = NewRole
+	typeRegistry[Role_TAG_NAME] = NewDescriptor
+
+	typeRegistry[Accessibility_TAG_NAME] = NewDescriptor
+
+	typeRegistry[Rating_TAG_NAME] = NewDescriptor
+
+	typeRegistry[Viewpoint_TAG_NAME] = NewDescriptor
+
+	typeRegistry[EssentialProperty_TAG_NAME] = NewDescriptor
+
+	typeRegistry[SupplementalProperty_TAG_NAME] = NewDescriptor
+
+	typeRegistry[EventStream_TAG_NAME] = NewEventStream
+
+	typeRegistry[InbandEventStream_TAG_NAME] = NewEventStream
+
+	typeRegistry[Event_TAG_NAME] = NewEvent
 
 	typeRegistry[ContentComponent_TAG_NAME] = NewContentComponent
 
 	typeRegistry[Representation_TAG_NAME] = NewRepresentation
 
-	// typeRegistry[ContentProtection_TAG_NAME] = NewContentProtection
+	typeRegistry[ContentProtection_TAG_NAME] = NewContentProtection
+
+	typeRegistry[CencPssh_TAG_NAME] = NewCencPssh
 
-	// typeRegistry[CencPssh_TAG_NAME] = NewCencPssh
+	typeRegistry[MsprPro_TAG_NAME] = NewMsprPro
 
 	typeRegistry[BaseUrl_TAG_NAME] = NewBaseUrl
 
+	typeRegistry[Location_TAG_NAME] = NewLocation
+
+	typeRegistry[PatchLocation_TAG_NAME] = NewPatchLocation
+
+	typeRegistry[UtcTiming_TAG_NAME] = NewDescriptor
+
 	typeRegistry[SegmentBase_TAG_NAME] = NewSegmentBase
 
 	typeRegistry[RepresentationIndex_TAG_NAME] = NewRepresentationIndex
@@ -214,9 +436,9 @@ func initTypeRegistry() {
 	typeRegistry[SegmentTimePoint_TAG_NAME] = NewSegmentTimePoint
 }
 
-func createInstance(name string) Node {
+func createInstance(state *parseState, name string) Node {
 	if _, ok := typeRegistry[name]; !ok {
-		fmt.Println("name missing from typeRegistry")
+		state.report(SEVERITY_ERROR, DIAGNOSTIC_UNKNOWN_ELEMENT, "%s is missing from the type registry", name)
 		return nil
 	}
 
@@ -245,9 +467,12 @@ func newRange(begin, end int) *Range {
 
 /**
  * Creates a deep copy of this Range.
- * @return {Range}
+ * @return {Range} A copy of |r|, or nil if |r| is nil.
  */
-func (r Range) Clone() *Range {
+func (r *Range) Clone() *Range {
+	if r == nil {
+		return nil
+	}
 	return newRange(r.Begin, r.End)
 }
 
@@ -267,11 +492,13 @@ func clone(obj Cloneable) Node {
  * @return {?string} The text contents, or null if there are none.
  * @private
  */
-func getContents(elem xml.Node) (string, error) {
-	if elem.FirstChild().NodeType() != xml.XML_TEXT_NODE {
-		return "", errors.New("wrong node type")
+func getContents(elem element) (string, error) {
+	if text, ok := elem.Text(); ok {
+		return text, nil
+	} else if len(elem.Children()) == 0 {
+		return "", errors.New("element is empty")
 	} else {
-		return elem.FirstChild().Content(), nil
+		return "", errors.New("wrong node type")
 	}
 }
 
@@ -281,21 +508,31 @@ func getContents(elem xml.Node) (string, error) {
  * @param {!Node} elem The parent XML element.
  * @param {!T} original The existing MPD node object.
  * @param {!String} original's tag name.
- * @return {!T} The merged MPD node object. If a child XML element cannot be
- *     parsed (see parseChild_) then the merged MPD node object is identical
+ * @return {!T} The merged MPD node object. If a child XML element does not
+ *     exist (see parseChild_) then the merged MPD node object is identical
  *     to |original|, although it is not the same object.
+ * @return {error} The error, if the child XML element could not be parsed.
  * @template T
  * @private
  */
-func mergeChild(parent Node, elem xml.Node, original Cloneable, originalTagName string) Node {
-	// fmt.Printf("mergeChild parent: %s, elem: %s, original: %s, originalTagName: %s\r\n", parent, elem, original, originalTagName)
+func mergeChild(state *parseState, parent Node, elem element, original Cloneable, originalTagName string) (Node, error) {
 	merged := original.Clone()
 
-	if childElement, err := findChild(elem, originalTagName); err == nil {
-		merged.Parse(parent, childElement)
+	childElement, err := findChild(elem, originalTagName)
+	if err == errDuplicateChild {
+		state.report(SEVERITY_WARNING, DIAGNOSTIC_DUPLICATE_ELEMENT, "more than one %s element, all of them are ignored", originalTagName)
+	}
+
+	if err == nil {
+		state.push(originalTagName)
+		err = merged.Parse(state, parent, childElement)
+		state.pop()
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return merged
+	return merged, nil
 }
 
 /**
@@ -306,38 +543,54 @@ func mergeChild(parent Node, elem xml.Node, original Cloneable, originalTagName
  *     child XML element. The constructor must define the attribute "TAG_NAME".
  * @return {T} The parsed child XML element on success, or null if a child
  *     XML element does not exist with the given tag name OR if there exists
- *     more than one child XML element with the given tag name OR if the child
- *     XML element could not be parsed.
+ *     more than one child XML element with the given tag name.
+ * @return {error} The error, if the child XML element could not be parsed.
  * @template T
  * @private
  */
-func parseChild(parent Node, elem xml.Node, name string) Node {
+func parseChild(state *parseState, parent Node, elem element, name string) (Node, error) {
 	var parsedChild Node
-	var childElement xml.Node
+	var childElement element
 	var err error
 
 	if childElement, err = findChild(elem, name); err != nil {
-		fmt.Println("Element missing: ", name)
-		return parsedChild
+		if err == errDuplicateChild {
+			state.report(SEVERITY_WARNING, DIAGNOSTIC_DUPLICATE_ELEMENT, "more than one %s element, all of them are ignored", name)
+		}
+		return nil, nil
+	}
+
+	if parsedChild = createInstance(state, name); parsedChild == nil {
+		return nil, nil
 	}
 
-	parsedChild = createInstance(name)
-	parsedChild.Parse(parent, childElement)
+	state.push(name)
+	err = parsedChild.Parse(state, parent, childElement)
+	state.pop()
+	if err != nil {
+		return nil, err
+	}
 
-	return parsedChild
+	return parsedChild, nil
 }
 
-func findChild(elem xml.Node, name string) (xml.Node, error) {
-	var childElement xml.Node
+var (
+	errDuplicateChild = errors.New("more than one child with given tag name exists")
+
+	errMissingChild = errors.New("child with given tag name is missing")
+)
+
+func findChild(elem element, name string) (element, error) {
+	var childElement element
 	found := false
 
-	for child := elem.FirstChild(); child != nil; child = child.NextSibling() {
+	for _, child := range elem.Children() {
 		if child.Name() != name {
 			continue
 		}
 
 		if found == true {
-			return childElement, errors.New("more than one child with given tag name exists")
+			return childElement, errDuplicateChild
 		}
 
 		found = true
@@ -347,7 +600,7 @@ func findChild(elem xml.Node, name string) (xml.Node, error) {
 	if found == true {
 		return childElement, nil
 	} else {
-		return childElement, errors.New("child with given tag name is missing")
+		return childElement, errMissingChild
 	}
 }
 
@@ -358,120 +611,181 @@ func findChild(elem xml.Node, name string) (xml.Node, error) {
  * @param {function(new:T)} constructor The constructor of each parsed child
  *     XML element. The constructor must define the attribute "TAG_NAME".
  * @return {!Array.<!T>} The parsed child XML elements.
+ * @return {error} The error, if any child XML element could not be parsed.
  * @template T
  * @private
  */
-func parseChildren(parent Node, elem xml.Node, name string) []Node {
+func parseChildren(state *parseState, parent Node, elem element, name string) ([]Node, error) {
 	var parsedChildren []Node
+	index := 0
 
-	for childNode := elem.FirstChild(); childNode != nil; childNode = childNode.NextSibling() {
+	for _, childNode := range elem.Children() {
 		if childNode.Name() != name {
 			continue
 		}
 
-		if parsedChild := createInstance(name); parsedChild != nil {
-			parsedChild.Parse(parent, childNode)
+		if parsedChild := createInstance(state, name); parsedChild != nil {
+			id, _ := parseAttrAsString(childNode, "id")
+			state.push(pathSegment(name, index, id))
+			err := parsedChild.Parse(state, parent, childNode)
+			state.pop()
+			if err != nil {
+				return nil, err
+			}
 			parsedChildren = append(parsedChildren, parsedChild)
 		}
+		index++
 	}
 
-	return parsedChildren
+	return parsedChildren, nil
 }
 
 /**
- * Parses an XML date string.
+ * Parses an xs:dateTime attribute.
  * @param {string} dateString
- * @return {?number}
+ * @return {?time.Time}
  * @private
  */
-func parseAttAsDate(elem xml.Node, name string) (int64, error) {
-	attribute := elem.Attribute(name)
-	if attribute == nil {
-		return 0, errors.New("missing attribute")
+func parseAttrAsDateTime(elem element, name string) (time.Time, error) {
+	value, ok := elem.Attribute(name)
+	if !ok {
+		return time.Time{}, errors.New("missing attribute")
 	}
+	return ParseDateTime(value)
+}
 
-	layout := "2006-01-02T15:04:05.000Z"
-	if t, err := time.Parse(layout, attribute.Value()); err != nil {
-		return 0, err
-	} else {
-		return t.Unix(), nil
+var dateTimeRegexp = regexp.MustCompile("^(-?[0-9]{4,})-([0-9]{2})-([0-9]{2})T([0-9]{2}):([0-9]{2}):([0-9]{2})(?:\\.([0-9]+))?(Z|[+-][0-9]{2}:?[0-9]{2})?$")
+
+/**
+ * Parses an xs:dateTime string, e.g., "2015-03-24T09:30:00.123+02:00".
+ * Fractional seconds are kept to the nanosecond. As is usual for DASH, a
+ * value without a timezone is taken to be in UTC.
+ * @param {string} value
+ * @return {time.Time} The parsed time, in UTC.
+ * @see http://www.datypic.com/sc/xsd/t-xsd_dateTime.html
+ */
+func ParseDateTime(value string) (time.Time, error) {
+	matches := dateTimeRegexp.FindStringSubmatch(strings.TrimSpace(value))
+	if matches == nil {
+		return time.Time{}, fmt.Errorf("%q is not an xs:dateTime", value)
+	}
+
+	fields := make([]int, 6)
+	for i := range fields {
+		field, err := strconv.Atoi(matches[i+1])
+		if err != nil {
+			return time.Time{}, err
+		}
+		fields[i] = field
+	}
+	year, month, day, hour, minute, second := fields[0], fields[1], fields[2], fields[3], fields[4], fields[5]
+
+	nanosecond := 0
+	if fraction := matches[7]; len(fraction) > 0 {
+		// Only the first nine fractional digits are significant.
+		if len(fraction) > 9 {
+			fraction = fraction[:9]
+		}
+		nanosecond, _ = strconv.Atoi(fraction + strings.Repeat("0", 9-len(fraction)))
+	}
+
+	// "24:00:00" is allowed and denotes the first instant of the next day.
+	endOfDay := hour == 24 && minute == 0 && second == 0 && nanosecond == 0
+	if (hour > 23 && !endOfDay) || minute > 59 || second > 59 {
+		return time.Time{}, fmt.Errorf("%q is not an xs:dateTime: time is out of range", value)
+	}
+
+	if month < 1 || month > 12 || day < 1 || day > time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day() {
+		return time.Time{}, fmt.Errorf("%q is not an xs:dateTime: date is out of range", value)
+	}
+
+	location := time.UTC
+	if zone := matches[8]; len(zone) > 0 && zone != "Z" {
+		digits := strings.Replace(zone[1:], ":", "", 1)
+		hours, _ := strconv.Atoi(digits[:2])
+		minutes, _ := strconv.Atoi(digits[2:])
+		if hours > 14 || minutes > 59 {
+			return time.Time{}, fmt.Errorf("%q is not an xs:dateTime: timezone is out of range", value)
+		}
+		offset := hours*60*60 + minutes*60
+		if zone[0] == '-' {
+			offset = -offset
+		}
+		location = time.FixedZone("", offset)
 	}
+
+	return time.Date(year, time.Month(month), day, hour, minute, second, nanosecond, location).UTC(), nil
 }
 
 /**
  * Parses an XML duration string.
  * Negative values are not supported. Years and months are treated as exactly
- * 365 and 30 days respectively.
+ * 365 and 30 days respectively. Fractional seconds are kept to the
+ * nanosecond.
  * @param {string} durationString The duration string, e.g., "PT1H3M43.2S",
  *     which means 1 hour, 3 minutes, and 43.2 seconds.
- * @return {?number} The parsed duration in seconds, or null if the duration
+ * @return {?time.Duration} The parsed duration, or null if the duration
  *     string could not be parsed.
  * @see http://www.datypic.com/sc/xsd/t-xsd_duration.html
  * @private
  */
-func parseAttrAsDuration(elem xml.Node, name string) (int, error) {
-	attribute := elem.Attribute(name)
-	if attribute == nil {
+func parseAttrAsDuration(elem element, name string) (time.Duration, error) {
+	value, ok := elem.Attribute(name)
+	if !ok {
 		return 0, errors.New("missing attribute")
 	}
+	return parseDuration(value)
+}
+
+var durationRegexp = regexp.MustCompile("^P(?:([0-9]*)Y)?(?:([0-9]*)M)?(?:([0-9]*)D)?(?:T(?:([0-9]*)H)?(?:([0-9]*)M)?(?:([0-9]*)(?:\\.([0-9]*))?S)?)?$")
 
-	re := regexp.MustCompile("^P(?:([0-9]*)Y)?(?:([0-9]*)M)?(?:([0-9]*)D)?(?:T(?:([0-9]*)H)?(?:([0-9]*)M)?(?:([0-9.]*)S)?)?$")
-	matches := re.FindStringSubmatch(attribute.Value())
+func parseDuration(value string) (time.Duration, error) {
+	matches := durationRegexp.FindStringSubmatch(value)
 
 	if matches == nil {
 		return 0, errors.New("attribute is not a duration")
 	}
 
-	duration := 0
-
-	// Assume a year always has 365 days.
-	if len(matches[1]) > 0 {
-		if years, err := parseNonNegativeInt(matches[1]); err != nil {
-			return 0, err
-		} else {
-			duration += (60 * 60 * 24 * 365) * years
-		}
+	// Assume a year always has 365 days and a month is 30 days.
+	units := []time.Duration{
+		365 * 24 * time.Hour,
+		30 * 24 * time.Hour,
+		24 * time.Hour,
+		time.Hour,
+		time.Minute,
+		time.Second,
 	}
 
-	// Assume a month is 30 days.
-	if len(matches[2]) > 0 {
-		if months, err := parseNonNegativeInt(matches[2]); err != nil {
-			return 0, err
-		} else {
-			duration += (60 * 60 * 24 * 30) * months
-		}
-	}
+	var duration time.Duration
 
-	if len(matches[3]) > 0 {
-		if days, err := parseNonNegativeInt(matches[3]); err != nil {
-			return 0, err
-		} else {
-			duration += (60 * 60 * 24) * days
+	for i, unit := range units {
+		if len(matches[i+1]) == 0 {
+			continue
 		}
-	}
 
-	if len(matches[4]) > 0 {
-		if hours, err := parseNonNegativeInt(matches[4]); err != nil {
+		count, err := strconv.ParseInt(matches[i+1], 10, 64)
+		if err != nil {
 			return 0, err
-		} else {
-			duration += (60 * 60) * hours
 		}
-	}
-
-	if len(matches[5]) > 0 {
-		if minutes, err := parseNonNegativeInt(matches[5]); err != nil {
-			return 0, err
-		} else {
-			duration += 60 * minutes
+		if count > int64(math.MaxInt64/unit) || duration > math.MaxInt64-time.Duration(count)*unit {
+			return 0, errors.New("duration is out of range")
 		}
+		duration += time.Duration(count) * unit
 	}
 
-	if len(matches[6]) > 0 {
-		if seconds, err := strconv.ParseInt(matches[6], 10, 32); err != nil {
+	// Only the first nine fractional digits are significant.
+	if fraction := matches[7]; len(fraction) > 0 {
+		if len(fraction) > 9 {
+			fraction = fraction[:9]
+		}
+		nanoseconds, err := strconv.ParseInt(fraction+strings.Repeat("0", 9-len(fraction)), 10, 64)
+		if err != nil {
 			return 0, err
-		} else {
-			duration += int(seconds)
 		}
+		if duration > math.MaxInt64-time.Duration(nanoseconds) {
+			return 0, errors.New("duration is out of range")
+		}
+		duration += time.Duration(nanoseconds)
 	}
 
 	return duration, nil
@@ -484,17 +798,16 @@ func parseAttrAsDuration(elem xml.Node, name string) (int, error) {
  *     could not be parsed.
  * @private
  */
-func parseAttrAsRange(elem xml.Node, name string) (*Range, error) {
+func parseAttrAsRange(elem element, name string) (*Range, error) {
 	var err error
-	attribute := elem.Attribute(name)
+	valueStr, ok := elem.Attribute(name)
 	begin := 0
 	end := 0
 
-	if attribute == nil {
+	if !ok {
 		return nil, errors.New("missing attribute")
 	}
 
-	valueStr := attribute.Value()
 	re := regexp.MustCompile("([0-9]+)-([0-9]+)")
 	matches := re.FindStringSubmatch(valueStr)
 
@@ -520,12 +833,12 @@ func parseAttrAsRange(elem xml.Node, name string) (*Range, error) {
  *     return null.
  * @private
  */
-func parseAttrAsPositiveInt(elem xml.Node, name string) (int, error) {
-	attribute := elem.Attribute(name)
-	if attribute == nil {
+func parseAttrAsPositiveInt(elem element, name string) (int, error) {
+	value, ok := elem.Attribute(name)
+	if !ok {
 		return 0, errors.New("missing attribute")
 	}
-	return parsePositiveInt(attribute.Value())
+	return parsePositiveInt(value)
 }
 
 func parsePositiveInt(value string) (int, error) {
@@ -547,12 +860,12 @@ func parsePositiveInt(value string) (int, error) {
  *     return null.
  * @private
  */
-func parseAttrAsNonNegativeInt(elem xml.Node, name string) (int, error) {
-	attribute := elem.Attribute(name)
-	if attribute == nil {
+func parseAttrAsNonNegativeInt(elem element, name string) (int, error) {
+	value, ok := elem.Attribute(name)
+	if !ok {
 		return 0, errors.New("missing attribute")
 	}
-	return parseNonNegativeInt(attribute.Value())
+	return parseNonNegativeInt(value)
 }
 
 func parseNonNegativeInt(value string) (int, error) {
@@ -574,12 +887,12 @@ func parseNonNegativeInt(value string) (int, error) {
  *     return null.
  * @private
  */
-func parseAttrAsUnsignedLong(elem xml.Node, name string) (uint64, error) {
-	attribute := elem.Attribute(name)
-	if attribute == nil {
+func parseAttrAsUnsignedLong(elem element, name string) (uint64, error) {
+	value, ok := elem.Attribute(name)
+	if !ok {
 		return 0, errors.New("missing attribute")
 	}
-	return parseUnsignedLong(attribute.Value())
+	return parseUnsignedLong(value)
 }
 
 func parseUnsignedLong(value string) (uint64, error) {
@@ -597,12 +910,12 @@ func parseUnsignedLong(value string) (uint64, error) {
  *     return null.
  * @private
  */
-func parseAttrAsUnsignedInt(elem xml.Node, name string) (uint32, error) {
-	attribute := elem.Attribute(name)
-	if attribute == nil {
+func parseAttrAsUnsignedInt(elem element, name string) (uint32, error) {
+	value, ok := elem.Attribute(name)
+	if !ok {
 		return 0, errors.New("missing attribute")
 	}
-	return parseUnsignedInt(attribute.Value())
+	return parseUnsignedInt(value)
 }
 
 func parseUnsignedInt(value string) (uint32, error) {
@@ -621,10 +934,10 @@ func parseUnsignedInt(value string) (uint32, error) {
  *     returned.
  * @private
  */
-func parseAttrAsString(elem xml.Node, name string) (string, error) {
-	attribute := elem.Attribute(name)
-	if attribute == nil {
+func parseAttrAsString(elem element, name string) (string, error) {
+	value, ok := elem.Attribute(name)
+	if !ok {
 		return "", errors.New("missing attribute")
 	}
-	return attribute.Value(), nil
+	return value, nil
 }
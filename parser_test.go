@@ -1,59 +1,124 @@
 package mpd
 
 import (
-	"github.com/moovweb/gokogiri/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
-func TestParseAttAsDate(t *testing.T) {
-	var sinceEpoch int64
+func TestParseAttrAsDateTime(t *testing.T) {
+	var dateTime time.Time
 	var err error
 
 	xmlText := "<Root birthday=\"1984-10-21T05:00:00.000Z\"></Root>"
-	doc, err := xml.Parse([]byte(xmlText), xml.DefaultEncodingBytes, nil, xml.DefaultParseOption, xml.DefaultEncodingBytes)
+	doc, err := parseXml([]byte(xmlText))
 
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
 
-	if sinceEpoch, err = parseAttAsDate(doc.FirstChild(), "birthday"); err != nil {
+	if dateTime, err = parseAttrAsDateTime(doc, "birthday"); err != nil {
 		t.Error(err)
 	}
-	if sinceEpoch != 467182800 {
-		t.Errorf("expecting time from epoch to be %d, got: %d", 467182800, sinceEpoch)
+	if dateTime.Unix() != 467182800 {
+		t.Errorf("expecting time from epoch to be %d, got: %d", 467182800, dateTime.Unix())
 	}
 
-	if sinceEpoch, err = parseAttAsDate(doc.FirstChild(), "MIA"); err == nil {
+	if dateTime, err = parseAttrAsDateTime(doc, "MIA"); err == nil {
 		t.Error("expecting to receive an error, got nil")
 	}
-	if sinceEpoch != 0 {
-		t.Errorf("expecting sinceEpoch to be 0 got: %d", sinceEpoch)
+	if !dateTime.IsZero() {
+		t.Errorf("expecting dateTime to be the zero time got: %s", dateTime)
+	}
+}
+
+func TestParseDateTime(t *testing.T) {
+	valid := map[string]time.Time{
+		"2015-03-24T09:30:00Z":            time.Date(2015, 3, 24, 9, 30, 0, 0, time.UTC),
+		"2015-03-24T09:30:00":             time.Date(2015, 3, 24, 9, 30, 0, 0, time.UTC),
+		"2015-03-24T09:30:00.5Z":          time.Date(2015, 3, 24, 9, 30, 0, 500000000, time.UTC),
+		"2015-03-24T09:30:00.123456Z":     time.Date(2015, 3, 24, 9, 30, 0, 123456000, time.UTC),
+		"2015-03-24T09:30:00.1234567891Z": time.Date(2015, 3, 24, 9, 30, 0, 123456789, time.UTC),
+		"2015-03-24T09:30:00+02:00":       time.Date(2015, 3, 24, 7, 30, 0, 0, time.UTC),
+		"2015-03-24T09:30:00.25-05:30":    time.Date(2015, 3, 24, 15, 0, 0, 250000000, time.UTC),
+		"2015-03-24T09:30:00+0100":        time.Date(2015, 3, 24, 8, 30, 0, 0, time.UTC),
+		"2015-12-31T24:00:00Z":            time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC),
+		"2016-02-29T00:00:00Z":            time.Date(2016, 2, 29, 0, 0, 0, 0, time.UTC),
+		" 1970-01-01T00:00:00Z ":          time.Unix(0, 0).UTC(),
+	}
+
+	for value, expected := range valid {
+		actual, err := ParseDateTime(value)
+		if err != nil {
+			t.Errorf("%q: %s", value, err)
+		} else if !actual.Equal(expected) {
+			t.Errorf("%q: expecting %s, got %s", value, expected, actual)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"2015-03-24",
+		"2015-03-24 09:30:00Z",
+		"2015-13-24T09:30:00Z",
+		"2015-02-29T09:30:00Z",
+		"2015-03-24T24:30:00Z",
+		"2015-03-24T09:60:00Z",
+		"2015-03-24T09:30:00+15:00",
+		"2015-03-24T09:30:00.Z",
+	}
+
+	for _, value := range invalid {
+		if _, err := ParseDateTime(value); err == nil {
+			t.Errorf("%q: expecting an error, got nil", value)
+		}
 	}
 }
 
 func TestParseAttrAsDuration(t *testing.T) {
-	var seconds int
+	var duration time.Duration
 	var err error
 
-	xmlText := "<Root duration=\"PT2H12M52S\"></Root>"
-	doc, err := xml.Parse([]byte(xmlText), xml.DefaultEncodingBytes, nil, xml.DefaultParseOption, xml.DefaultEncodingBytes)
+	xmlText := "<Root duration=\"PT2H12M52S\" fractional=\"PT1M43.2S\" precise=\"PT0.0000000019S\" huge=\"P999999999999Y\"></Root>"
+	doc, err := parseXml([]byte(xmlText))
 
 	if err != nil {
+		t.Fatal(err)
+	}
+
+	if duration, err = parseAttrAsDuration(doc, "duration"); err != nil {
 		t.Error(err)
 	}
+	if duration != 2*time.Hour+12*time.Minute+52*time.Second {
+		t.Errorf("expecting duration to be %s, got: %s", 2*time.Hour+12*time.Minute+52*time.Second, duration)
+	}
 
-	if seconds, err = parseAttrAsDuration(doc.FirstChild(), "duration"); err != nil {
+	if duration, err = parseAttrAsDuration(doc, "fractional"); err != nil {
 		t.Error(err)
 	}
-	if seconds != (2*60*60)+(12*60)+52 {
-		t.Errorf("expecting seconds to be %d, got: %d", (12*60)+52, seconds)
+	if duration != time.Minute+43*time.Second+200*time.Millisecond {
+		t.Errorf("expecting duration to be 1m43.2s, got: %s", duration)
+	}
+
+	if duration, err = parseAttrAsDuration(doc, "precise"); err != nil {
+		t.Error(err)
+	}
+	if duration != time.Nanosecond {
+		t.Errorf("expecting duration to be truncated to 1ns, got: %s", duration)
+	}
+
+	if _, err = parseAttrAsDuration(doc, "huge"); err == nil {
+		t.Error("expecting an out of range error, got nil")
 	}
 
-	if seconds, err = parseAttrAsDuration(doc.FirstChild(), "MIA"); err == nil {
+	if duration, err = parseAttrAsDuration(doc, "MIA"); err == nil {
 		t.Error("expecting to receive an error, got nil")
 	}
-	if seconds != 0 {
-		t.Errorf("expecting seconds to be 0 got: %d", seconds)
+	if duration != 0 {
+		t.Errorf("expecting duration to be 0 got: %s", duration)
 	}
 }
 
@@ -62,13 +127,13 @@ func TestParseAttrAsRange(t *testing.T) {
 	var err error
 
 	xmlText := "<Root acceleration=\"0-1000\"></Root>"
-	doc, err := xml.Parse([]byte(xmlText), xml.DefaultEncodingBytes, nil, xml.DefaultParseOption, xml.DefaultEncodingBytes)
+	doc, err := parseXml([]byte(xmlText))
 
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
 
-	if r, err = parseAttrAsRange(doc.FirstChild(), "acceleration"); err != nil {
+	if r, err = parseAttrAsRange(doc, "acceleration"); err != nil {
 		t.Error(err)
 	}
 	if r.Begin != 0 {
@@ -78,40 +143,40 @@ func TestParseAttrAsRange(t *testing.T) {
 		t.Errorf("expecting range to End at 1000, got %d", r.End)
 	}
 
-	if r, err = parseAttrAsRange(doc.FirstChild(), "MIA"); err == nil {
+	if r, err = parseAttrAsRange(doc, "MIA"); err == nil {
 		t.Error("expecting to receive an error, got nil")
 	}
 	if r != nil {
-		t.Errorf("expecting range to be nil got: %s", r)
+		t.Errorf("expecting range to be nil got: %v", r)
 	}
 }
 
-func TestparseAttrAsPositiveInt(t *testing.T) {
+func TestParseAttrAsPositiveInt(t *testing.T) {
 	var num int
 	var err error
 
 	xmlText := "<Root meaning=\"42\" freez=\"−173\" void=\"0\"></Root>"
-	doc, err := xml.Parse([]byte(xmlText), xml.DefaultEncodingBytes, nil, xml.DefaultParseOption, xml.DefaultEncodingBytes)
+	doc, err := parseXml([]byte(xmlText))
 
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
 
-	if num, err = parseAttrAsPositiveInt(doc.FirstChild(), "meaning"); err != nil {
+	if num, err = parseAttrAsPositiveInt(doc, "meaning"); err != nil {
 		t.Error(err)
 	}
 	if num != 42 {
 		t.Errorf("expecting num to be 42, got: %d", num)
 	}
 
-	if num, err = parseAttrAsPositiveInt(doc.FirstChild(), "void"); err == nil {
+	if num, err = parseAttrAsPositiveInt(doc, "void"); err == nil {
 		t.Error("expecting to receive an error, got nil")
 	}
 	if num != 0 {
 		t.Errorf("expecting num to be 0, got: %d", num)
 	}
 
-	if num, err = parseAttrAsPositiveInt(doc.FirstChild(), "freez"); err == nil {
+	if num, err = parseAttrAsPositiveInt(doc, "freez"); err == nil {
 		t.Error("expecting to receive an error, got nil")
 	}
 	if num != 0 {
@@ -124,27 +189,27 @@ func TestParseAttrAsNonNegativeInt(t *testing.T) {
 	var err error
 
 	xmlText := "<Root meaning=\"42\" freez=\"−173\" void=\"0\"></Root>"
-	doc, err := xml.Parse([]byte(xmlText), xml.DefaultEncodingBytes, nil, xml.DefaultParseOption, xml.DefaultEncodingBytes)
+	doc, err := parseXml([]byte(xmlText))
 
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
 
-	if num, err = parseAttrAsNonNegativeInt(doc.FirstChild(), "meaning"); err != nil {
+	if num, err = parseAttrAsNonNegativeInt(doc, "meaning"); err != nil {
 		t.Error(err)
 	}
 	if num != 42 {
 		t.Errorf("expecting num to be 42, got: %d", num)
 	}
 
-	if num, err = parseAttrAsNonNegativeInt(doc.FirstChild(), "void"); err != nil {
+	if num, err = parseAttrAsNonNegativeInt(doc, "void"); err != nil {
 		t.Error(err)
 	}
 	if num != 0 {
 		t.Errorf("expecting num to be 0, got: %d", num)
 	}
 
-	if num, err = parseAttrAsNonNegativeInt(doc.FirstChild(), "freez"); err == nil {
+	if num, err = parseAttrAsNonNegativeInt(doc, "freez"); err == nil {
 		t.Error("expecting to receive an error, got nil")
 	}
 	if num != 0 {
@@ -157,20 +222,20 @@ func TestParseAttrAsString(t *testing.T) {
 	var err error
 
 	xmlText := "<Root monkey=\"business\"></Root>"
-	doc, err := xml.Parse([]byte(xmlText), xml.DefaultEncodingBytes, nil, xml.DefaultParseOption, xml.DefaultEncodingBytes)
+	doc, err := parseXml([]byte(xmlText))
 
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
 
-	if str, err = parseAttrAsString(doc.FirstChild(), "monkey"); err != nil {
+	if str, err = parseAttrAsString(doc, "monkey"); err != nil {
 		t.Error(err)
 	}
 	if str != "business" {
 		t.Errorf("expecting string to be 'business', got: %s", str)
 	}
 
-	if str, err = parseAttrAsString(doc.FirstChild(), "monkey-bar"); err == nil {
+	if str, err = parseAttrAsString(doc, "monkey-bar"); err == nil {
 		t.Errorf("error should have returned for missing attribure, got nil")
 	}
 	if str != "" {
@@ -182,7 +247,7 @@ func TestParser(t *testing.T) {
 	var root *Mpd
 	var err error
 
-	if root, err = ParseMpd("http://sdk.streamrail.com/pepsi/cdn/0.0.1/3a5dd80efc3a867e55c69996c7f22051f6c3b94d/dash/manifest.mpd"); err != nil {
+	if root, _, err = ParseMpd("http://sdk.streamrail.com/pepsi/cdn/0.0.1/3a5dd80efc3a867e55c69996c7f22051f6c3b94d/dash/manifest.mpd"); err != nil {
 		t.Error(err)
 	}
 
@@ -193,7 +258,7 @@ func TestParser(t *testing.T) {
 	}
 
 	if root.Type != "static" {
-		t.Error("expecting mpd type to be static, got %s", root.Type)
+		t.Errorf("expecting mpd type to be static, got %s", root.Type)
 	}
 
 	if len(root.Periods) != 1 {
@@ -201,14 +266,14 @@ func TestParser(t *testing.T) {
 	}
 
 	if len(root.Periods[0].AdaptationSets) != 2 {
-		t.Error("expecting mpd to have two adaptation sets, got %d", len(root.Periods[0].AdaptationSets))
+		t.Errorf("expecting mpd to have two adaptation sets, got %d", len(root.Periods[0].AdaptationSets))
 	}
 
-	if root.Periods[0].AdaptationSets[0].ContentType != "audio" {
+	if !root.Periods[0].AdaptationSets[0].ContentType.Contains("audio") {
 		t.Errorf("expecting first adaptaion content type to be audio, actual %s", root.Periods[0].AdaptationSets[0].ContentType)
 	}
 
-	if root.Periods[0].AdaptationSets[1].ContentType != "video" {
+	if !root.Periods[0].AdaptationSets[1].ContentType.Contains("video") {
 		t.Errorf("expecting first adaptaion content type to be audio, actual %s", root.Periods[0].AdaptationSets[0].ContentType)
 	}
 
@@ -286,3 +351,215 @@ func TestParser(t *testing.T) {
 		t.Errorf("expecting first representation segment tempate initialization url template to be $RepresentationID$/init.mp4 got %s", root.Periods[0].AdaptationSets[0].Representations[0].SegmentTemplate.InitializationUrlTemplate)
 	}
 }
+
+func TestParseMpdBytes(t *testing.T) {
+	content, err := ioutil.ReadFile("testdata/static.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, _, err := ParseMpdBytes(content, "http://example.com/dash/manifest.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if root.Type != "static" {
+		t.Errorf("expecting mpd type to be static, got %s", root.Type)
+	}
+
+	if len(root.BaseUrls) != 1 || root.BaseUrls[0].Url != "http://example.com/dash/" {
+		t.Errorf("expecting mpd base url to be http://example.com/dash/, got %v", root.BaseUrls)
+	}
+
+	if len(root.Periods) != 1 {
+		t.Fatalf("expecting mpd to have one period, got %d", len(root.Periods))
+	}
+
+	if len(root.Periods[0].AdaptationSets) != 2 {
+		t.Fatalf("expecting mpd to have two adaptation sets, got %d", len(root.Periods[0].AdaptationSets))
+	}
+
+	if len(root.Periods[0].AdaptationSets[1].Representations) != 3 {
+		t.Errorf("expecting video adaptation set to have three representations, got %d", len(root.Periods[0].AdaptationSets[1].Representations))
+	}
+}
+
+func TestParseMpdReader(t *testing.T) {
+	f, err := os.Open("testdata/static.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	root, _, err := ParseMpdReader(f, "http://example.com/dash/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(root.Periods) != 1 {
+		t.Fatalf("expecting mpd to have one period, got %d", len(root.Periods))
+	}
+
+	representation := root.Periods[0].AdaptationSets[0].Representations[0]
+	if representation.Id != "700k" {
+		t.Errorf("expecting audio representation id to be 700k, got %s", representation.Id)
+	}
+
+	if len(representation.BaseUrls) != 1 || representation.BaseUrls[0].Url != "http://example.com/dash/" {
+		t.Errorf("expecting representation base url to be http://example.com/dash/, got %v", representation.BaseUrls)
+	}
+}
+
+func TestParseMalformedMpd(t *testing.T) {
+	manifests := []string{
+		"",
+		"<NotAnMpd/>",
+		"<MPD><Period><AdaptationSet><Representation><SegmentList><SegmentURL/></SegmentList></Representation></AdaptationSet></Period></MPD>",
+		"<MPD type=\"static\" mediaPresentationDuration=\"PT10S\"><Period><AdaptationSet mimeType=\"video/mp4\"><SegmentTemplate timescale=\"0\" duration=\"0\" media=\"$Number$.mp4\"/><Representation id=\"v\" bandwidth=\"1\"/></AdaptationSet></Period></MPD>",
+		"<MPD type=\"static\" mediaPresentationDuration=\"PT10S\"><Period><AdaptationSet mimeType=\"video/mp4\"><SegmentTemplate media=\"$Time$.mp4\"><SegmentTimeline><S d=\"1\" r=\"2147483647\"/></SegmentTimeline></SegmentTemplate><Representation id=\"v\" bandwidth=\"1\"/></AdaptationSet></Period></MPD>",
+		"<MPD><Period><AdaptationSet><SegmentBase><RepresentationIndex range=\"9-1\"/></SegmentBase><Representation/></AdaptationSet></Period></MPD>",
+	}
+
+	for _, manifest := range manifests {
+		mpd, _, err := ParseMpdBytes([]byte(manifest), "http://example.com/manifest.mpd")
+		if err == nil {
+			mpdProcessor := NewMpdProcessor()
+			mpdProcessor.Process(mpd)
+		}
+	}
+}
+
+func TestParseTooManyBaseUrls(t *testing.T) {
+	// Ten relative BaseURLs at each level would resolve to 10,000 locations for
+	// the Representation.
+	baseUrls := ""
+	for i := 0; i < 10; i++ {
+		baseUrls += fmt.Sprintf("<BaseURL>%d/</BaseURL>", i)
+	}
+	content := `<MPD type="static" mediaPresentationDuration="PT4S">` + baseUrls + `<Period>` + baseUrls +
+		`<AdaptationSet mimeType="video/mp4">` + baseUrls +
+		`<SegmentTemplate timescale="1000" duration="2000" media="$Number$.m4s"/>` +
+		`<Representation id="video" bandwidth="100000">` + baseUrls + `</Representation></AdaptationSet></Period></MPD>`
+
+	mpd, diagnostics, err := ParseMpdBytes([]byte(content), "http://example.com/manifest.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	representation := mpd.Periods[0].AdaptationSets[0].Representations[0]
+	if len(representation.BaseUrls) != MAX_BASE_URLS {
+		t.Errorf("expecting %d base urls, got %d", MAX_BASE_URLS, len(representation.BaseUrls))
+	}
+	if url := representation.BaseUrls[0].Url; url != "http://example.com/0/0/0/0/" {
+		t.Errorf("expecting the first base url to be kept, got %s", url)
+	}
+
+	found := 0
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Code == DIAGNOSTIC_TOO_MANY_BASE_URLS {
+			found++
+		}
+	}
+	if found == 0 {
+		t.Errorf("expecting a %s diagnostic, got %v", DIAGNOSTIC_TOO_MANY_BASE_URLS, diagnostics)
+	}
+
+	mpdProcessor := NewMpdProcessor()
+	mpdProcessor.Process(mpd)
+	references := mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[0].StreamInfos[0].SegmentIndex.References
+	if len(references) != 2 || len(references[0].Urls) != MAX_BASE_URLS || !strings.HasSuffix(references[0].Urls[0], "/1.m4s") {
+		t.Errorf("expecting each segment to have %d urls, got %v", MAX_BASE_URLS, references)
+	}
+}
+
+func FuzzParseMpdBytes(f *testing.F) {
+	content, err := ioutil.ReadFile("testdata/static.mpd")
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(content)
+
+	// Without a Fetcher, remote elements are not downloaded.
+	mpdParser := MpdParser{}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		mpd, _, err := mpdParser.ParseMpdBytes(data, "http://example.com/manifest.mpd")
+		if err == nil {
+			mpdProcessor := NewMpdProcessor()
+			mpdProcessor.Process(mpd)
+		}
+	})
+}
+
+func TestParseXml(t *testing.T) {
+	xmlText := `<?xml version="1.0" encoding="UTF-8"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" xmlns:xlink="http://www.w3.org/1999/xlink" type="static">
+	<!-- comment -->
+	<BaseURL>http://<!-- comment -->example.com/<![CDATA[dash/]]></BaseURL>
+	<Period xlink:href="remote.xml" href="local"><![CDATA[ignored]]><AdaptationSet/></Period>
+</MPD>`
+
+	root, err := parseXml([]byte(xmlText))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if root.Name() != "MPD" || root.Namespace() != "urn:mpeg:dash:schema:mpd:2011" {
+		t.Errorf("expecting root to be MPD in the DASH namespace, got %s in %s", root.Name(), root.Namespace())
+	}
+
+	if value, ok := root.Attribute("type"); !ok || value != "static" {
+		t.Errorf("expecting type to be static, got %s", value)
+	}
+
+	if _, ok := root.Attribute("xmlns"); ok {
+		t.Error("expecting namespace declarations not to be attributes")
+	}
+
+	children := root.Children()
+	if len(children) != 2 {
+		t.Fatalf("expecting root to have two child elements, got %d", len(children))
+	}
+
+	if text, err := getContents(children[0]); err != nil || text != "http://example.com/dash/" {
+		t.Errorf("expecting BaseURL contents to be http://example.com/dash/, got %s", text)
+	}
+
+	period := children[1]
+	if value, ok := period.Attribute("href"); !ok || value != "local" {
+		t.Errorf("expecting the unqualified href to take precedence, got %s", value)
+	}
+	if value, ok := period.AttributeNS("http://www.w3.org/1999/xlink", "href"); !ok || value != "remote.xml" {
+		t.Errorf("expecting xlink:href to be remote.xml, got %s", value)
+	}
+	if _, err := getContents(children[1].Children()[0]); err == nil {
+		t.Error("expecting an error for an empty element, got nil")
+	}
+
+	if _, err := parseXml([]byte("<MPD></MPD><MPD></MPD>")); err == nil {
+		t.Error("expecting an error for a document with two root elements, got nil")
+	}
+}
+
+func TestParseXmlEncoding(t *testing.T) {
+	// Text with characters outside ASCII in each single-byte encoding.
+	documents := map[string][]byte{
+		"ISO-8859-1":   []byte("<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n<MPD><BaseURL>Caf\xe9</BaseURL></MPD>"),
+		"windows-1252": []byte("<?xml version=\"1.0\" encoding=\"windows-1252\"?>\n<MPD><BaseURL>Caf\xe9 \x96 2\x80</BaseURL></MPD>"),
+	}
+	expected := map[string]string{
+		"ISO-8859-1":   "Café",
+		"windows-1252": "Café – 2€",
+	}
+
+	for encoding, data := range documents {
+		root, err := parseXml(data)
+		if err != nil {
+			t.Errorf("%s: %v", encoding, err)
+			continue
+		}
+		if text, err := getContents(root.Children()[0]); err != nil || text != expected[encoding] {
+			t.Errorf("%s: expecting BaseURL contents to be %s, got %s", encoding, expected[encoding], text)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package mpd
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type PatchLocation struct {
+	/**
+	 * The URL of an MPD patch, resolved against the MPD's own URL.
+	 * @type {string}
+	 */
+	Url string
+
+	/**
+	 * How long the patch is available for, or -1 if it is unknown.
+	 * @type {?time.Duration}
+	 */
+	Ttl time.Duration
+}
+
+/**
+ * Parses a "PatchLocation" tag.
+ * @see ISO/IEC 23009-1:2019 section 5.15
+ * @param {!Mpd} parent The parent Mpd.
+ * @param {!Node} elem The PatchLocation XML element.
+ */
+func (patchLocation *PatchLocation) Parse(state *parseState, parent Node, elem element) error {
+	p, ok := parent.(*Mpd)
+	if !ok {
+		return state.errorf("a PatchLocation must be a child of an MPD")
+	}
+
+	patchLocation.Url, _ = getContents(elem)
+	patchLocation.Url = resolveUrl(p.Url, strings.TrimSpace(patchLocation.Url))
+
+	// @ttl is a number of seconds.
+	if value, ok := elem.Attribute("ttl"); ok {
+		if seconds, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil && seconds >= 0 && seconds < math.MaxInt64/float64(time.Second) {
+			patchLocation.Ttl = time.Duration(seconds * float64(time.Second))
+		}
+	}
+
+	return nil
+}
+
+func NewPatchLocation() Node {
+	return &PatchLocation{
+		Ttl: -1,
+	}
+}
@@ -1,29 +1,37 @@
 package mpd
 
-import (
-	"github.com/moovweb/gokogiri/xml"
-)
+import "time"
 
 type Period struct {
 	/** @type {?string} */
 	Id string
 
 	/**
-	 * The start time of the Period, in seconds, with respect to the media
-	 * presentation timeline. Note that the Period becomes/became available at
-	 * Mpd.availabilityStartTime + Period.start.
-	 * @type {?number}
+	 * The remote Period this Period is a placeholder for, if it has not been
+	 * resolved.
+	 * @type {Xlink}
 	 */
-	Start int
+	Xlink *Xlink
 
 	/**
-	 * The duration in seconds.
-	 * @type {?number}
+	 * The start time of the Period with respect to the media presentation
+	 * timeline, or -1 if it is unknown. Note that the Period becomes/became
+	 * available at Mpd.availabilityStartTime + Period.start.
+	 * @type {?time.Duration}
 	 */
-	Duration int
+	Start time.Duration
 
-	/** @type {*BaseUrl} */
-	BaseUrl *BaseUrl
+	/**
+	 * The duration, or -1 if it is unknown.
+	 * @type {?time.Duration}
+	 */
+	Duration time.Duration
+
+	/**
+	 * The BaseURLs, in document order, or the parent's if there are none.
+	 * @type {!Array.<!BaseUrl>}
+	 */
+	BaseUrls []*BaseUrl
 
 	/** @type {SegmentBase} */
 	SegmentBase *SegmentBase
@@ -34,6 +42,9 @@ type Period struct {
 	/** @type {SegmentTemplate} */
 	SegmentTemplate *SegmentTemplate
 
+	/** @type {!Array.<!EventStream>} */
+	EventStreams []*EventStream
+
 	/** @type {!Array.<!AdaptationSet>} */
 	AdaptationSets []*AdaptationSet
 }
@@ -43,12 +54,16 @@ type Period struct {
  * @param {!Mpd} parent The parent Mpd.
  * @param {!Node} elem The Period XML element.
  */
-func (period *Period) Parse(parent Node, elem xml.Node) {
-	var p *Mpd = parent.(*Mpd)
+func (period *Period) Parse(state *parseState, parent Node, elem element) error {
+	p, ok := parent.(*Mpd)
+	if !ok {
+		return state.errorf("a Period must be a child of an MPD")
+	}
 	var err error
 
 	// Parse attributes.
 	period.Id, _ = parseAttrAsString(elem, "id")
+	period.Xlink = parseXlink(elem)
 
 	if period.Start, err = parseAttrAsDuration(elem, "start"); err != nil {
 		period.Start = -1
@@ -59,29 +74,51 @@ func (period *Period) Parse(parent Node, elem xml.Node) {
 	}
 
 	// Parse simple child elements.
-	ok := false
-	if period.BaseUrl, ok = parseChild(period, elem, BaseUrl_TAG_NAME).(*BaseUrl); ok == false {
-		period.BaseUrl = p.BaseUrl
+	var child Node
+	if period.BaseUrls, err = parseBaseUrls(state, period, elem, p.BaseUrls); err != nil {
+		return err
+	}
+
+	children, err := parseChildren(state, period, elem, EventStream_TAG_NAME)
+	if err != nil {
+		return err
+	}
+	period.EventStreams = make([]*EventStream, len(children))
+	for i, child := range children {
+		period.EventStreams[i] = child.(*EventStream)
 	}
 
 	// Parse hierarchical children.
-	if period.SegmentBase, ok = parseChild(period, elem, SegmentBase_TAG_NAME).(*SegmentBase); ok == false {
+	if child, err = parseChild(state, period, elem, SegmentBase_TAG_NAME); err != nil {
+		return err
+	}
+	if period.SegmentBase, ok = child.(*SegmentBase); ok == false {
 		period.SegmentBase = nil
 	}
 
-	if period.SegmentList, ok = parseChild(period, elem, SegmentList_TAG_NAME).(*SegmentList); ok == false {
+	if child, err = parseChild(state, period, elem, SegmentList_TAG_NAME); err != nil {
+		return err
+	}
+	if period.SegmentList, ok = child.(*SegmentList); ok == false {
 		period.SegmentList = nil
 	}
 
-	if period.SegmentTemplate, ok = parseChild(period, elem, SegmentTemplate_TAG_NAME).(*SegmentTemplate); ok == false {
+	if child, err = parseChild(state, period, elem, SegmentTemplate_TAG_NAME); err != nil {
+		return err
+	}
+	if period.SegmentTemplate, ok = child.(*SegmentTemplate); ok == false {
 		period.SegmentTemplate = nil
 	}
 
-	children := parseChildren(period, elem, AdaptationSet_TAG_NAME)
+	if children, err = parseChildren(state, period, elem, AdaptationSet_TAG_NAME); err != nil {
+		return err
+	}
 	period.AdaptationSets = make([]*AdaptationSet, len(children))
 	for i, child := range children {
 		period.AdaptationSets[i] = child.(*AdaptationSet)
 	}
+
+	return nil
 }
 
 func NewPeriod() Node {
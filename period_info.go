@@ -1,16 +1,24 @@
 package mpd
 
+import "time"
+
 type PeriodInfo struct {
 	Id string
 
-	Start int
+	Start time.Duration
 
 	/**
-	 * The period's duration, in seconds.
+	 * The period's duration, or -1 if it is unknown.
 	 */
-	Duration int
+	Duration time.Duration
 
 	StreamSetInfos []StreamSetInfo
+
+	/**
+	 * The Events of the Period's EventStreams, ordered by start time.
+	 * @type {!Array.<!EventInfo>}
+	 */
+	Events []EventInfo
 }
 
 func NewPeriodInfo() PeriodInfo {
@@ -19,5 +27,6 @@ func NewPeriodInfo() PeriodInfo {
 		Start:          0,
 		Duration:       -1,
 		StreamSetInfos: make([]StreamSetInfo, 0),
+		Events:         make([]EventInfo, 0),
 	}
 }
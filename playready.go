@@ -0,0 +1,218 @@
+package mpd
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	/**
+	 * The type of the PlayReady Object record which holds the WRMHEADER.
+	 * @const {number}
+	 */
+	PLAYREADY_RIGHTS_MANAGEMENT_HEADER_RECORD = 1
+)
+
+/**
+ * A PlayReady Header, i.e., a WRMHEADER.
+ * @see https://learn.microsoft.com/en-us/playready/specifications/playready-header-specification
+ */
+type PlayReadyHeader struct {
+	/**
+	 * The WRMHEADER@version, e.g., "4.0.0.0" or "4.3.0.0".
+	 * @type {string}
+	 */
+	Version string
+
+	/**
+	 * The key IDs, formatted as lower-case UUIDs. PlayReady encodes KIDs as
+	 * little-endian GUIDs, so these are converted to match cenc:default_KID.
+	 * @type {!Array.<string>}
+	 */
+	KeyIds []string
+
+	/**
+	 * The license acquisition URL, or "" if there is none.
+	 * @type {string}
+	 */
+	LaUrl string
+
+	/**
+	 * The license acquisition user interface URL, or "" if there is none.
+	 * @type {string}
+	 */
+	LuiUrl string
+
+	/**
+	 * The domain service ID, or "" if there is none.
+	 * @type {string}
+	 */
+	DsId string
+
+	/**
+	 * The children of the CUSTOMATTRIBUTES element, kept verbatim.
+	 * @type {!Array.<!RawElement>}
+	 */
+	CustomAttributes []*RawElement
+}
+
+/**
+ * Parses a PlayReady Object, as found in mspr:pro and in the data of a
+ * PlayReady PSSH box, and returns its WRMHEADER.
+ * @param {Uint8Array} pro
+ * @return {PlayReadyHeader}
+ */
+func ParsePlayReadyObject(pro []byte) (*PlayReadyHeader, error) {
+	// length (4) + record count (2)
+	if len(pro) < 6 {
+		return nil, errors.New("PlayReady Object is truncated")
+	}
+
+	length := binary.LittleEndian.Uint32(pro[0:4])
+	if uint64(length) != uint64(len(pro)) {
+		return nil, fmt.Errorf("PlayReady Object length is %d, but %d bytes are available", length, len(pro))
+	}
+
+	count := binary.LittleEndian.Uint16(pro[4:6])
+	offset := 6
+
+	for i := uint16(0); i < count; i++ {
+		// type (2) + length (2)
+		if len(pro) < offset+4 {
+			return nil, errors.New("PlayReady Object is truncated")
+		}
+		recordType := binary.LittleEndian.Uint16(pro[offset : offset+2])
+		recordLength := int(binary.LittleEndian.Uint16(pro[offset+2 : offset+4]))
+		offset += 4
+
+		if len(pro) < offset+recordLength {
+			return nil, errors.New("PlayReady Object is truncated")
+		}
+		record := pro[offset : offset+recordLength]
+		offset += recordLength
+
+		if recordType == PLAYREADY_RIGHTS_MANAGEMENT_HEADER_RECORD {
+			return ParsePlayReadyHeader(record)
+		}
+	}
+
+	return nil, errors.New("PlayReady Object has no WRMHEADER")
+}
+
+/**
+ * Parses a WRMHEADER, encoded as UTF-16LE XML.
+ * @param {Uint8Array} header
+ * @return {PlayReadyHeader}
+ */
+func ParsePlayReadyHeader(header []byte) (*PlayReadyHeader, error) {
+	if len(header)%2 != 0 {
+		return nil, errors.New("WRMHEADER is not valid UTF-16")
+	}
+
+	units := make([]uint16, len(header)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(header[2*i:])
+	}
+	text := string(utf16.Decode(units))
+
+	// The XML has been transcoded to UTF-8, so drop the byte order mark and
+	// any XML declaration, which would claim it is still UTF-16.
+	text = strings.TrimPrefix(text, "\uFEFF")
+	if strings.HasPrefix(text, "<?xml") {
+		if end := strings.Index(text, "?>"); end >= 0 {
+			text = text[end+2:]
+		}
+	}
+
+	root, err := parseXml([]byte(text))
+	if err != nil {
+		return nil, fmt.Errorf("WRMHEADER is not valid XML: %s", err)
+	}
+	if root.Name() != "WRMHEADER" {
+		return nil, fmt.Errorf("root element is %s, not WRMHEADER", root.Name())
+	}
+
+	playReadyHeader := &PlayReadyHeader{
+		KeyIds:           make([]string, 0),
+		CustomAttributes: make([]*RawElement, 0),
+	}
+	playReadyHeader.Version, _ = root.Attribute("version")
+
+	data, err := findChild(root, "DATA")
+	if err != nil {
+		return nil, errors.New("WRMHEADER has no DATA element")
+	}
+
+	for _, child := range data.Children() {
+		text, _ := child.Text()
+		text = strings.TrimSpace(text)
+
+		switch child.Name() {
+		case "LA_URL":
+			playReadyHeader.LaUrl = text
+		case "LUI_URL":
+			playReadyHeader.LuiUrl = text
+		case "DS_ID":
+			playReadyHeader.DsId = text
+		case "CUSTOMATTRIBUTES":
+			for _, customAttribute := range child.Children() {
+				playReadyHeader.CustomAttributes = append(playReadyHeader.CustomAttributes, newRawElement(customAttribute))
+			}
+		}
+	}
+
+	// Version 4.0 puts a single KID in DATA, version 4.1 puts it in
+	// DATA/PROTECTINFO, and versions 4.2 and 4.3 list several in
+	// DATA/PROTECTINFO/KIDS.
+	if err = playReadyHeader.addKeyIds(data); err != nil {
+		return nil, err
+	}
+
+	return playReadyHeader, nil
+}
+
+/**
+ * Adds the KID elements found below the given element.
+ * @param {!Node} elem
+ */
+func (playReadyHeader *PlayReadyHeader) addKeyIds(elem element) error {
+	for _, child := range elem.Children() {
+		if child.Name() == "CUSTOMATTRIBUTES" {
+			continue
+		}
+		if child.Name() != "KID" {
+			if err := playReadyHeader.addKeyIds(child); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, ok := child.Attribute("VALUE")
+		if !ok {
+			value, _ = child.Text()
+		}
+
+		keyId, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil || len(keyId) != 16 {
+			return fmt.Errorf("KID %q is not a base64 GUID", value)
+		}
+		playReadyHeader.KeyIds = append(playReadyHeader.KeyIds, formatGuid(keyId))
+	}
+	return nil
+}
+
+/**
+ * Formats 16 bytes holding a little-endian GUID as a lower-case UUID.
+ */
+func formatGuid(b []byte) string {
+	uuid := make([]byte, 16)
+	copy(uuid, b)
+	uuid[0], uuid[1], uuid[2], uuid[3] = b[3], b[2], b[1], b[0]
+	uuid[4], uuid[5] = b[5], b[4]
+	uuid[6], uuid[7] = b[7], b[6]
+	return formatUuid(uuid)
+}
@@ -0,0 +1,150 @@
+package mpd
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+	"unicode/utf16"
+)
+
+func makePlayReadyObject(header string) []byte {
+	record := make([]byte, 0)
+	for _, unit := range utf16.Encode([]rune(header)) {
+		record = binary.LittleEndian.AppendUint16(record, unit)
+	}
+
+	pro := make([]byte, 0)
+	pro = binary.LittleEndian.AppendUint32(pro, uint32(10+len(record)))
+	pro = binary.LittleEndian.AppendUint16(pro, 1)
+	pro = binary.LittleEndian.AppendUint16(pro, PLAYREADY_RIGHTS_MANAGEMENT_HEADER_RECORD)
+	pro = binary.LittleEndian.AppendUint16(pro, uint16(len(record)))
+	return append(pro, record...)
+}
+
+func TestParsePlayReadyObject(t *testing.T) {
+	v40 := `<WRMHEADER xmlns="http://schemas.microsoft.com/DRM/2007/03/PlayReadyHeader" version="4.0.0.0">
+  <DATA>
+    <PROTECTINFO><KEYLEN>16</KEYLEN><ALGID>AESCTR</ALGID></PROTECTINFO>
+    <KID>AAAAEAAQABAQABAAAAAAAQ==</KID>
+    <LA_URL>https://license.example.com/rightsmanager.asmx</LA_URL>
+    <LUI_URL>https://example.com/lui</LUI_URL>
+    <CUSTOMATTRIBUTES><IIS_DRM_VERSION>8.0.0</IIS_DRM_VERSION></CUSTOMATTRIBUTES>
+  </DATA>
+</WRMHEADER>`
+
+	header, err := ParsePlayReadyObject(makePlayReadyObject(v40))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Version != "4.0.0.0" || header.LaUrl != "https://license.example.com/rightsmanager.asmx" || header.LuiUrl != "https://example.com/lui" {
+		t.Errorf("unexpected version 4.0 header %+v", header)
+	}
+	if len(header.KeyIds) != 1 || header.KeyIds[0] != "10000000-1000-1000-1000-100000000001" {
+		t.Errorf("expecting 1 key ID, got %v", header.KeyIds)
+	}
+	if len(header.CustomAttributes) != 1 || header.CustomAttributes[0].Name != "IIS_DRM_VERSION" || header.CustomAttributes[0].Text != "8.0.0" {
+		t.Errorf("expecting 1 custom attribute, got %v", header.CustomAttributes)
+	}
+
+	v43 := "\uFEFF" + `<?xml version="1.0" encoding="UTF-16"?><WRMHEADER xmlns="http://schemas.microsoft.com/DRM/2007/03/PlayReadyHeader" version="4.3.0.0">
+  <DATA>
+    <PROTECTINFO>
+      <KIDS>
+        <KID ALGID="AESCBC" VALUE="AAAAEAAQABAQABAAAAAAAQ=="></KID>
+        <KID ALGID="AESCBC" VALUE="AAAAIAAQABAQABAAAAAAAg=="></KID>
+      </KIDS>
+    </PROTECTINFO>
+    <LA_URL>https://license.example.com/</LA_URL>
+  </DATA>
+</WRMHEADER>`
+
+	header, err = ParsePlayReadyObject(makePlayReadyObject(v43))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Version != "4.3.0.0" || header.LaUrl != "https://license.example.com/" {
+		t.Errorf("unexpected version 4.3 header %+v", header)
+	}
+	if len(header.KeyIds) != 2 || header.KeyIds[1] != "20000000-1000-1000-1000-100000000002" {
+		t.Errorf("expecting 2 key IDs, got %v", header.KeyIds)
+	}
+
+	pro := makePlayReadyObject(v40)
+	invalid := [][]byte{
+		pro[:4],
+		pro[:len(pro)-1],
+		makePlayReadyObject("<WRMHEADER><DATA><KID>AAAA</KID></DATA></WRMHEADER>"),
+		makePlayReadyObject("<WRMHEADER/>"),
+		makePlayReadyObject("<NOTWRMHEADER/>"),
+	}
+	for _, b := range invalid {
+		if _, err := ParsePlayReadyObject(b); err == nil {
+			t.Errorf("expecting an error for PlayReady Object %x", b)
+		}
+	}
+}
+
+func TestParseMsprPro(t *testing.T) {
+	header := `<WRMHEADER xmlns="http://schemas.microsoft.com/DRM/2007/03/PlayReadyHeader" version="4.0.0.0"><DATA><KID>AAAAEAAQABAQABAAAAAAAQ==</KID><LA_URL>https://license.example.com/</LA_URL></DATA></WRMHEADER>`
+	pro := base64.StdEncoding.EncodeToString(makePlayReadyObject(header))
+
+	playready, _ := hex.DecodeString("9a04f07998404286ab92e65be0885f95")
+	pssh := base64.StdEncoding.EncodeToString(makePsshBox(0, playready, nil, makePlayReadyObject(header)))
+
+	content := `<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" xmlns:cenc="urn:mpeg:cenc:2013" xmlns:mspr="urn:microsoft:playready" type="static" mediaPresentationDuration="PT10S">
+  <Period>
+    <AdaptationSet mimeType="video/mp4">
+      <ContentProtection schemeIdUri="urn:uuid:9a04f079-9840-4286-ab92-e65be0885f95">
+        <mspr:pro>` + pro + `</mspr:pro>
+      </ContentProtection>
+      <ContentProtection schemeIdUri="urn:uuid:9a04f079-9840-4286-ab92-e65be0885f95">
+        <cenc:pssh>` + pssh + `</cenc:pssh>
+      </ContentProtection>
+      <ContentProtection schemeIdUri="urn:uuid:9a04f079-9840-4286-ab92-e65be0885f95">
+        <mspr:pro>AAAA</mspr:pro>
+      </ContentProtection>
+      <Representation id="1" bandwidth="100000"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+
+	mpd, diagnostics, err := ParseMpdBytes([]byte(content), "http://example.com/manifest.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contentProtections := mpd.Periods[0].AdaptationSets[0].ContentProtections
+	for i := 0; i < 2; i++ {
+		playReadyHeader := contentProtections[i].PlayReadyHeader()
+		if playReadyHeader == nil {
+			t.Fatalf("expecting ContentProtection[%d] to have a PlayReady Header", i)
+		}
+		if playReadyHeader.LaUrl != "https://license.example.com/" || len(playReadyHeader.KeyIds) != 1 || playReadyHeader.KeyIds[0] != "10000000-1000-1000-1000-100000000001" {
+			t.Errorf("unexpected PlayReady Header %+v", playReadyHeader)
+		}
+	}
+
+	if contentProtections[2].Pro == nil || contentProtections[2].PlayReadyHeader() != nil {
+		t.Errorf("expecting the invalid mspr:pro to have no PlayReady Header")
+	}
+
+	found := false
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Code == DIAGNOSTIC_INVALID_PLAYREADY_OBJECT {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expecting an %s diagnostic, got %v", DIAGNOSTIC_INVALID_PLAYREADY_OBJECT, diagnostics)
+	}
+}
+
+func FuzzParsePlayReadyObject(f *testing.F) {
+	f.Add(makePlayReadyObject(`<WRMHEADER version="4.0.0.0"><DATA><KID>AAAAAAAAAAAAAAAAAAAAAA==</KID><LA_URL>http://example.com/</LA_URL></DATA></WRMHEADER>`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ParsePlayReadyObject(data)
+		ParsePlayReadyHeader(data)
+	})
+}
@@ -0,0 +1,85 @@
+package mpd
+
+/**
+ * An XML attribute kept verbatim.
+ */
+type RawAttribute struct {
+	/** @type {string} */
+	Namespace string
+
+	/** @type {string} */
+	Name string
+
+	/** @type {string} */
+	Value string
+}
+
+/**
+ * An XML element kept verbatim, for elements whose contents are defined
+ * outside of the DASH schema, e.g., the children of a ContentProtection.
+ */
+type RawElement struct {
+	/**
+	 * The namespace URI, e.g., "urn:mpeg:cenc:2013".
+	 * @type {string}
+	 */
+	Namespace string
+
+	/**
+	 * The local name, e.g., "pssh".
+	 * @type {string}
+	 */
+	Name string
+
+	/** @type {!Array.<!RawAttribute>} */
+	Attributes []RawAttribute
+
+	/**
+	 * The text the element starts with.
+	 * @type {string}
+	 */
+	Text string
+
+	/** @type {!Array.<!RawElement>} */
+	Children []*RawElement
+}
+
+/**
+ * Gets the value of the attribute with the given namespace URI and local name.
+ */
+func (rawElement *RawElement) Attribute(namespace string, name string) (string, bool) {
+	for _, attribute := range rawElement.Attributes {
+		if attribute.Namespace == namespace && attribute.Name == name {
+			return attribute.Value, true
+		}
+	}
+	return "", false
+}
+
+/**
+ * Copies an element, and all of its descendants, into a RawElement.
+ */
+func newRawElement(elem element) *RawElement {
+	rawElement := &RawElement{
+		Namespace:  elem.Namespace(),
+		Name:       elem.Name(),
+		Attributes: make([]RawAttribute, 0),
+		Children:   make([]*RawElement, 0),
+	}
+
+	rawElement.Text, _ = elem.Text()
+
+	for _, attribute := range elem.Attributes() {
+		rawElement.Attributes = append(rawElement.Attributes, RawAttribute{
+			Namespace: attribute.namespace,
+			Name:      attribute.name,
+			Value:     attribute.value,
+		})
+	}
+
+	for _, child := range elem.Children() {
+		rawElement.Children = append(rawElement.Children, newRawElement(child))
+	}
+
+	return rawElement
+}
@@ -1,9 +1,5 @@
 package mpd
 
-import (
-	"github.com/moovweb/gokogiri/xml"
-)
-
 type Representation struct {
 	/** @type {?string} */
 	Id string
@@ -36,8 +32,11 @@ type Representation struct {
 	/** @type {?string} */
 	Codecs string
 
-	/** @type {*BaseUrl} */
-	BaseUrl *BaseUrl
+	/**
+	 * The BaseURLs, in document order, or the parent's if there are none.
+	 * @type {!Array.<!BaseUrl>}
+	 */
+	BaseUrls []*BaseUrl
 
 	/** @type {SegmentBase} */
 	SegmentBase *SegmentBase
@@ -51,8 +50,47 @@ type Representation struct {
 	/** @type {!Array.<ContentProtection>} */
 	ContentProtections []*ContentProtection
 
-	/** @type {boolean} */
+	/**
+	 * True if one of the Roles is "main".
+	 * @type {boolean}
+	 */
 	Main bool
+
+	/**
+	 * The Representation's Roles, or the AdaptationSet's if it has none. The
+	 * same goes for Accessibilities, Ratings and Viewpoints.
+	 * @type {!Array.<!Descriptor>}
+	 */
+	Roles []*Descriptor
+
+	/** @type {!Array.<!Descriptor>} */
+	Accessibilities []*Descriptor
+
+	/** @type {!Array.<!Descriptor>} */
+	Ratings []*Descriptor
+
+	/** @type {!Array.<!Descriptor>} */
+	Viewpoints []*Descriptor
+
+	/**
+	 * The Representation's own EssentialProperties. Those of the AdaptationSet
+	 * also apply, but are not copied.
+	 * @type {!Array.<!Descriptor>}
+	 */
+	EssentialProperties []*Descriptor
+
+	/**
+	 * The Representation's own SupplementalProperties.
+	 * @type {!Array.<!Descriptor>}
+	 */
+	SupplementalProperties []*Descriptor
+
+	/**
+	 * The Representation's own InbandEventStreams. Those of the AdaptationSet
+	 * also apply, but are not copied.
+	 * @type {!Array.<!EventStream>}
+	 */
+	InbandEventStreams []*EventStream
 }
 
 /**
@@ -60,9 +98,14 @@ type Representation struct {
  * @param {!AdaptationSet} parent The parent AdaptationSet.
  * @param {!Node} elem The Representation XML element.
  */
-func (representation *Representation) Parse(parent Node, elem xml.Node) {
+func (representation *Representation) Parse(state *parseState, parent Node, elem element) error {
 	var err error
-	var p *AdaptationSet = (parent).(*AdaptationSet)
+	var child Node
+
+	p, ok := parent.(*AdaptationSet)
+	if !ok {
+		return state.errorf("a Representation must be a child of an AdaptationSet")
+	}
 
 	// Parse attributes.
 	representation.Id, _ = parseAttrAsString(elem, "id")
@@ -87,41 +130,106 @@ func (representation *Representation) Parse(parent Node, elem xml.Node) {
 	representation.Lang = p.Lang
 
 	// Parse simple child elements.
-	ok := false
-	if representation.BaseUrl, ok = parseChild(representation, elem, BaseUrl_TAG_NAME).(*BaseUrl); ok == false {
-		representation.BaseUrl = p.BaseUrl
+	if representation.BaseUrls, err = parseBaseUrls(state, representation, elem, p.BaseUrls); err != nil {
+		return err
 	}
 
-	// representation.ContentProtections = parseChildren(representation, elem, ContentProtection_TAG_NAME)
+	if representation.Roles, err = parseDescriptors(state, representation, elem, Role_TAG_NAME); err != nil {
+		return err
+	}
+	if representation.Accessibilities, err = parseDescriptors(state, representation, elem, Accessibility_TAG_NAME); err != nil {
+		return err
+	}
+	if representation.Ratings, err = parseDescriptors(state, representation, elem, Rating_TAG_NAME); err != nil {
+		return err
+	}
+	if representation.Viewpoints, err = parseDescriptors(state, representation, elem, Viewpoint_TAG_NAME); err != nil {
+		return err
+	}
+	if representation.EssentialProperties, err = parseDescriptors(state, representation, elem, EssentialProperty_TAG_NAME); err != nil {
+		return err
+	}
+	if representation.SupplementalProperties, err = parseDescriptors(state, representation, elem, SupplementalProperty_TAG_NAME); err != nil {
+		return err
+	}
+
+	children, err := parseChildren(state, representation, elem, ContentProtection_TAG_NAME)
+	if err != nil {
+		return err
+	}
+	representation.ContentProtections = make([]*ContentProtection, len(children))
+	for i, child := range children {
+		representation.ContentProtections[i] = child.(*ContentProtection)
+	}
+
+	if children, err = parseChildren(state, representation, elem, InbandEventStream_TAG_NAME); err != nil {
+		return err
+	}
+	representation.InbandEventStreams = make([]*EventStream, len(children))
+	for i, child := range children {
+		representation.InbandEventStreams[i] = child.(*EventStream)
+	}
 
 	// Parse hierarchical children.
 	if p.SegmentBase != nil {
-		representation.SegmentBase = mergeChild(representation, elem, p.SegmentBase, SegmentBase_TAG_NAME).(*SegmentBase)
+		child, err = mergeChild(state, representation, elem, p.SegmentBase, SegmentBase_TAG_NAME)
+	} else {
+		child, err = parseChild(state, representation, elem, SegmentBase_TAG_NAME)
+	}
+	if err != nil {
+		return err
+	}
+	if representation.SegmentBase, ok = child.(*SegmentBase); ok == false {
+		representation.SegmentBase = nil
 	} else {
-		if representation.SegmentBase, ok = parseChild(representation, elem, SegmentBase_TAG_NAME).(*SegmentBase); ok == false {
-			representation.SegmentBase = nil
-		}
+		representation.SegmentBase.BaseUrls = representation.BaseUrls
 	}
 
 	if p.SegmentList != nil {
-		representation.SegmentList = mergeChild(representation, elem, p.SegmentList, SegmentList_TAG_NAME).(*SegmentList)
+		child, err = mergeChild(state, representation, elem, p.SegmentList, SegmentList_TAG_NAME)
 	} else {
-		if representation.SegmentList, ok = parseChild(representation, elem, SegmentList_TAG_NAME).(*SegmentList); ok == false {
-			representation.SegmentList = nil
-		}
+		child, err = parseChild(state, representation, elem, SegmentList_TAG_NAME)
+	}
+	if err != nil {
+		return err
+	}
+	if representation.SegmentList, ok = child.(*SegmentList); ok == false {
+		representation.SegmentList = nil
+	} else {
+		representation.SegmentList.BaseUrls = representation.BaseUrls
 	}
 
 	if p.SegmentTemplate != nil {
-		representation.SegmentTemplate = mergeChild(representation, elem, p.SegmentTemplate, SegmentTemplate_TAG_NAME).(*SegmentTemplate)
+		child, err = mergeChild(state, representation, elem, p.SegmentTemplate, SegmentTemplate_TAG_NAME)
 	} else {
-		if representation.SegmentTemplate, ok = parseChild(representation, elem, SegmentTemplate_TAG_NAME).(*SegmentTemplate); ok == false {
-			representation.SegmentTemplate = nil
-		}
+		child, err = parseChild(state, representation, elem, SegmentTemplate_TAG_NAME)
+	}
+	if err != nil {
+		return err
+	}
+	if representation.SegmentTemplate, ok = child.(*SegmentTemplate); ok == false {
+		representation.SegmentTemplate = nil
+	}
+
+	if len(representation.ContentProtections) == 0 {
+		representation.ContentProtections = p.ContentProtections
+	}
+
+	if len(representation.Roles) == 0 {
+		representation.Roles = p.Roles
+	}
+	if len(representation.Accessibilities) == 0 {
+		representation.Accessibilities = p.Accessibilities
+	}
+	if len(representation.Ratings) == 0 {
+		representation.Ratings = p.Ratings
+	}
+	if len(representation.Viewpoints) == 0 {
+		representation.Viewpoints = p.Viewpoints
 	}
+	representation.Main = hasMainRole(representation.Roles)
 
-	// if len(representation.ContentProtections) == 0 {
-	// 	representation.ContentProtections = p.ContentProtections
-	// }
+	return nil
 }
 
 func NewRepresentation() Node {
@@ -1,9 +1,5 @@
 package mpd
 
-import (
-	"github.com/moovweb/gokogiri/xml"
-)
-
 type RepresentationIndex struct {
 	/** @type {string} */
 	Url string
@@ -20,15 +16,20 @@ type RepresentationIndex struct {
  * @param {!SegmentBase} parent The parent SegmentBase.
  * @param {!Node} elem The RepresentationIndex XML element.
  */
-func (representationIndex *RepresentationIndex) Parse(parent Node, elem xml.Node) {
+func (representationIndex *RepresentationIndex) Parse(state *parseState, parent Node, elem element) error {
 	var err error
-	var p *SegmentBase = (parent).(*SegmentBase)
+	p, ok := parent.(*SegmentBase)
+	if !ok {
+		return state.errorf("a RepresentationIndex must be a child of a SegmentBase")
+	}
 	// Parse attributes.
 	representationIndex.Url, _ = parseAttrAsString(elem, "sourceURL")
 
 	if representationIndex.Range, err = parseAttrAsRange(elem, "range"); err != nil {
 		representationIndex.Range = p.IndexRange.Clone()
 	}
+
+	return nil
 }
 
 /**
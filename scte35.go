@@ -0,0 +1,723 @@
+package mpd
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SCTE-35 ------------------------------------------------------------------
+const (
+	/**
+	 * Events whose body is a scte35:SpliceInfoSection element.
+	 * @const {string}
+	 */
+	SCTE35_XML_SCHEME = "urn:scte:scte35:2013:xml"
+
+	/**
+	 * Events whose body is a scte35:Signal holding a base64 scte35:Binary.
+	 * @const {string}
+	 */
+	SCTE35_XML_BIN_SCHEME = "urn:scte:scte35:2014:xml+bin"
+
+	/**
+	 * Events whose body is a base64 splice_info_section.
+	 * @const {string}
+	 */
+	SCTE35_BIN_SCHEME = "urn:scte:scte35:2013:bin"
+
+	/**
+	 * The MPEG-2 system clock, in which SCTE-35 times are expressed.
+	 * @const {number}
+	 */
+	SCTE35_TIMESCALE = 90000
+
+	SPLICE_NULL           = 0x00
+	SPLICE_SCHEDULE       = 0x04
+	SPLICE_INSERT         = 0x05
+	TIME_SIGNAL           = 0x06
+	BANDWIDTH_RESERVATION = 0x07
+	PRIVATE_COMMAND       = 0xff
+
+	SEGMENTATION_DESCRIPTOR_TAG = 0x02
+
+	/**
+	 * The identifier of SCTE-35 splice descriptors, "CUEI".
+	 * @const {number}
+	 */
+	SCTE35_IDENTIFIER = 0x43554549
+
+	/**
+	 * Splice times are 33-bit PTS values, so they wrap around.
+	 * @const {number}
+	 */
+	PTS_MODULUS = 1 << 33
+)
+
+/**
+ * A decoded SCTE-35 splice_info_section.
+ * @see ANSI/SCTE 35 section 9.6
+ */
+type SpliceInfoSection struct {
+	/** @type {number} */
+	ProtocolVersion uint8
+
+	/**
+	 * Added to every splice time, in SCTE35_TIMESCALE units.
+	 * @type {number}
+	 */
+	PtsAdjustment uint64
+
+	/** @type {number} */
+	Tier uint16
+
+	/**
+	 * e.g., SPLICE_INSERT or TIME_SIGNAL.
+	 * @type {number}
+	 */
+	SpliceCommandType uint8
+
+	/**
+	 * The splice_insert command, or null for any other command.
+	 * @type {SpliceInsert}
+	 */
+	SpliceInsert *SpliceInsert
+
+	/**
+	 * The time_signal command, or null for any other command.
+	 * @type {TimeSignal}
+	 */
+	TimeSignal *TimeSignal
+
+	/** @type {!Array.<!SegmentationDescriptor>} */
+	SegmentationDescriptors []*SegmentationDescriptor
+}
+
+type SpliceInsert struct {
+	/** @type {number} */
+	SpliceEventId uint32
+
+	/** @type {boolean} */
+	SpliceEventCancelIndicator bool
+
+	/**
+	 * True for a cue out, i.e., the start of an ad avail, and false for a cue
+	 * in.
+	 * @type {boolean}
+	 */
+	OutOfNetworkIndicator bool
+
+	/** @type {boolean} */
+	SpliceImmediateFlag bool
+
+	/**
+	 * The splice time, in SCTE35_TIMESCALE units and without PtsAdjustment,
+	 * or ^uint64(0) if it is not specified.
+	 * @type {number}
+	 */
+	PtsTime uint64
+
+	/**
+	 * The break duration, or null if there is none.
+	 * @type {BreakDuration}
+	 */
+	BreakDuration *BreakDuration
+
+	/** @type {number} */
+	UniqueProgramId uint16
+
+	/** @type {number} */
+	AvailNum uint8
+
+	/** @type {number} */
+	AvailsExpected uint8
+}
+
+type BreakDuration struct {
+	/** @type {boolean} */
+	AutoReturn bool
+
+	/**
+	 * The duration, in SCTE35_TIMESCALE units.
+	 * @type {number}
+	 */
+	Duration uint64
+}
+
+type TimeSignal struct {
+	/**
+	 * The signalled time, in SCTE35_TIMESCALE units and without
+	 * PtsAdjustment, or ^uint64(0) if it is not specified.
+	 * @type {number}
+	 */
+	PtsTime uint64
+}
+
+/**
+ * A segmentation_descriptor, e.g., a provider advertisement start.
+ * @see ANSI/SCTE 35 section 10.3.3
+ */
+type SegmentationDescriptor struct {
+	/** @type {number} */
+	SegmentationEventId uint32
+
+	/** @type {boolean} */
+	SegmentationEventCancelIndicator bool
+
+	/**
+	 * The duration, in SCTE35_TIMESCALE units, or ^uint64(0) if it is not
+	 * specified.
+	 * @type {number}
+	 */
+	SegmentationDuration uint64
+
+	/**
+	 * e.g., 0x0C for an MPU or 0x09 for an ADI identifier.
+	 * @type {number}
+	 */
+	SegmentationUpidType uint8
+
+	/** @type {Uint8Array} */
+	SegmentationUpid []byte
+
+	/**
+	 * e.g., 0x34 for a provider placement opportunity start.
+	 * @type {number}
+	 */
+	SegmentationTypeId uint8
+
+	/** @type {number} */
+	SegmentNum uint8
+
+	/** @type {number} */
+	SegmentsExpected uint8
+
+	/** @type {number} */
+	SubSegmentNum uint8
+
+	/** @type {number} */
+	SubSegmentsExpected uint8
+}
+
+/**
+ * Gets the splice time of the splice_insert or time_signal command, including
+ * PtsAdjustment, in SCTE35_TIMESCALE units. Returns false if the command does
+ * not specify a time, e.g., for an immediate splice.
+ */
+func (spliceInfoSection *SpliceInfoSection) SpliceTime() (uint64, bool) {
+	ptsTime := ^uint64(0)
+	if spliceInfoSection.SpliceInsert != nil {
+		ptsTime = spliceInfoSection.SpliceInsert.PtsTime
+	} else if spliceInfoSection.TimeSignal != nil {
+		ptsTime = spliceInfoSection.TimeSignal.PtsTime
+	}
+
+	if ptsTime == ^uint64(0) {
+		return 0, false
+	}
+	return (ptsTime + spliceInfoSection.PtsAdjustment) % PTS_MODULUS, true
+}
+
+/**
+ * Gets the duration of the cue, in SCTE35_TIMESCALE units, from the
+ * splice_insert's break_duration or else from the first segmentation
+ * descriptor which has one. Returns false if neither specifies a duration.
+ */
+func (spliceInfoSection *SpliceInfoSection) Duration() (uint64, bool) {
+	if spliceInfoSection.SpliceInsert != nil && spliceInfoSection.SpliceInsert.BreakDuration != nil {
+		return spliceInfoSection.SpliceInsert.BreakDuration.Duration, true
+	}
+	for _, segmentationDescriptor := range spliceInfoSection.SegmentationDescriptors {
+		if segmentationDescriptor.SegmentationDuration != ^uint64(0) {
+			return segmentationDescriptor.SegmentationDuration, true
+		}
+	}
+	return 0, false
+}
+
+/**
+ * Checks if |schemeIdUri| identifies an EventStream carrying SCTE-35 cues.
+ */
+func isScte35Scheme(schemeIdUri string) bool {
+	return schemeIdUri == SCTE35_XML_SCHEME || schemeIdUri == SCTE35_XML_BIN_SCHEME || schemeIdUri == SCTE35_BIN_SCHEME
+}
+
+/**
+ * Decodes the SCTE-35 cue of an Event, whichever form it takes.
+ * @param {string} schemeIdUri The EventStream's scheme.
+ * @param {!Node} elem The Event XML element.
+ * @return {SpliceInfoSection}
+ */
+func parseScte35Event(schemeIdUri string, elem element) (*SpliceInfoSection, error) {
+	switch schemeIdUri {
+	case SCTE35_XML_SCHEME:
+		if section := findScte35Element(elem, "SpliceInfoSection"); section != nil {
+			return parseSpliceInfoSectionXml(section)
+		}
+		return nil, errors.New("Event has no SpliceInfoSection")
+
+	case SCTE35_XML_BIN_SCHEME:
+		// Some packagers use the XML form with this scheme too.
+		if binary := findScte35Element(elem, "Binary"); binary != nil {
+			text, _ := binary.Text()
+			return parseSpliceInfoSectionBase64(text)
+		}
+		if section := findScte35Element(elem, "SpliceInfoSection"); section != nil {
+			return parseSpliceInfoSectionXml(section)
+		}
+		return nil, errors.New("Event has no Binary or SpliceInfoSection")
+
+	default:
+		text, _ := elem.Text()
+		return parseSpliceInfoSectionBase64(text)
+	}
+}
+
+/**
+ * Finds the element with the given local name among |elem|'s children, or
+ * among the children of a Signal child.
+ */
+func findScte35Element(elem element, name string) element {
+	for _, child := range elem.Children() {
+		if child.Name() == name {
+			return child
+		}
+		if child.Name() == "Signal" {
+			if found := findScte35Element(child, name); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+func parseSpliceInfoSectionBase64(text string) (*SpliceInfoSection, error) {
+	// Whitespace within base64 content is allowed.
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(text), ""))
+	if err != nil {
+		return nil, fmt.Errorf("splice_info_section is not valid base64: %s", err)
+	}
+	return ParseSpliceInfoSection(data)
+}
+
+/**
+ * Parses a binary splice_info_section, including its CRC_32.
+ * @param {Uint8Array} data
+ * @return {SpliceInfoSection}
+ */
+func ParseSpliceInfoSection(data []byte) (*SpliceInfoSection, error) {
+	reader := newBitReader(data)
+
+	if tableId := reader.read(8); tableId != 0xfc {
+		return nil, fmt.Errorf("table_id is 0x%02x, not 0xfc", tableId)
+	}
+	reader.skip(4) // section_syntax_indicator, private_indicator, sap_type
+	sectionLength := int(reader.read(12))
+	if reader.err != nil || sectionLength != len(data)-3 {
+		return nil, fmt.Errorf("section_length is %d, but %d bytes are available", sectionLength, len(data)-3)
+	}
+	if crc := mpeg2Crc32(data); crc != 0 {
+		return nil, errors.New("splice_info_section CRC_32 mismatch")
+	}
+
+	spliceInfoSection := &SpliceInfoSection{
+		SegmentationDescriptors: make([]*SegmentationDescriptor, 0),
+	}
+	spliceInfoSection.ProtocolVersion = uint8(reader.read(8))
+	if encrypted := reader.readFlag(); encrypted {
+		return nil, errors.New("encrypted splice_info_sections are not supported")
+	}
+	reader.skip(6) // encryption_algorithm
+	spliceInfoSection.PtsAdjustment = reader.read(33)
+	reader.skip(8) // cw_index
+	spliceInfoSection.Tier = uint16(reader.read(12))
+	spliceCommandLength := int(reader.read(12))
+	spliceInfoSection.SpliceCommandType = uint8(reader.read(8))
+
+	commandStart := reader.offset()
+	switch spliceInfoSection.SpliceCommandType {
+	case SPLICE_INSERT:
+		spliceInfoSection.SpliceInsert = parseSpliceInsert(reader)
+	case TIME_SIGNAL:
+		spliceInfoSection.TimeSignal = &TimeSignal{PtsTime: parseSpliceTime(reader)}
+	case SPLICE_NULL, BANDWIDTH_RESERVATION:
+	default:
+		if spliceCommandLength == 0xfff {
+			return nil, fmt.Errorf("splice_command_type 0x%02x has an unknown length", spliceInfoSection.SpliceCommandType)
+		}
+	}
+	// A splice_command_length of 0xfff means the length is not given, in
+	// which case the command has been parsed to its end.
+	if spliceCommandLength != 0xfff {
+		reader.seek(commandStart + spliceCommandLength)
+	}
+
+	descriptorLoopLength := int(reader.read(16))
+	descriptorsEnd := reader.offset() + descriptorLoopLength
+	for reader.err == nil && reader.offset() < descriptorsEnd {
+		tag := uint8(reader.read(8))
+		length := int(reader.read(8))
+		descriptorEnd := reader.offset() + length
+		if tag == SEGMENTATION_DESCRIPTOR_TAG && reader.read(32) == SCTE35_IDENTIFIER {
+			segmentationDescriptor := parseSegmentationDescriptor(reader, descriptorEnd)
+			spliceInfoSection.SegmentationDescriptors = append(spliceInfoSection.SegmentationDescriptors, segmentationDescriptor)
+		}
+		reader.seek(descriptorEnd)
+	}
+
+	if reader.err != nil || reader.offset() > len(data)-4 {
+		return nil, errors.New("splice_info_section is truncated")
+	}
+
+	return spliceInfoSection, nil
+}
+
+func parseSpliceInsert(reader *bitReader) *SpliceInsert {
+	spliceInsert := &SpliceInsert{PtsTime: ^uint64(0)}
+	spliceInsert.SpliceEventId = uint32(reader.read(32))
+	spliceInsert.SpliceEventCancelIndicator = reader.readFlag()
+	reader.skip(7)
+	if spliceInsert.SpliceEventCancelIndicator {
+		return spliceInsert
+	}
+
+	spliceInsert.OutOfNetworkIndicator = reader.readFlag()
+	programSpliceFlag := reader.readFlag()
+	durationFlag := reader.readFlag()
+	spliceInsert.SpliceImmediateFlag = reader.readFlag()
+	reader.skip(4)
+
+	if programSpliceFlag && !spliceInsert.SpliceImmediateFlag {
+		spliceInsert.PtsTime = parseSpliceTime(reader)
+	}
+	if !programSpliceFlag {
+		// Component splices are not mapped onto the timeline, but must be
+		// skipped over.
+		componentCount := int(reader.read(8))
+		for i := 0; i < componentCount; i++ {
+			reader.skip(8) // component_tag
+			if !spliceInsert.SpliceImmediateFlag {
+				parseSpliceTime(reader)
+			}
+		}
+	}
+	if durationFlag {
+		spliceInsert.BreakDuration = &BreakDuration{}
+		spliceInsert.BreakDuration.AutoReturn = reader.readFlag()
+		reader.skip(6)
+		spliceInsert.BreakDuration.Duration = reader.read(33)
+	}
+	spliceInsert.UniqueProgramId = uint16(reader.read(16))
+	spliceInsert.AvailNum = uint8(reader.read(8))
+	spliceInsert.AvailsExpected = uint8(reader.read(8))
+
+	return spliceInsert
+}
+
+/**
+ * Parses a splice_time(), returning ^uint64(0) if the time is not specified.
+ */
+func parseSpliceTime(reader *bitReader) uint64 {
+	if timeSpecified := reader.readFlag(); !timeSpecified {
+		reader.skip(7)
+		return ^uint64(0)
+	}
+	reader.skip(6)
+	return reader.read(33)
+}
+
+func parseSegmentationDescriptor(reader *bitReader, end int) *SegmentationDescriptor {
+	segmentationDescriptor := &SegmentationDescriptor{
+		SegmentationDuration: ^uint64(0),
+		SegmentationUpid:     make([]byte, 0),
+	}
+	segmentationDescriptor.SegmentationEventId = uint32(reader.read(32))
+	segmentationDescriptor.SegmentationEventCancelIndicator = reader.readFlag()
+	reader.skip(7)
+	if segmentationDescriptor.SegmentationEventCancelIndicator {
+		return segmentationDescriptor
+	}
+
+	programSegmentationFlag := reader.readFlag()
+	segmentationDurationFlag := reader.readFlag()
+	reader.skip(6) // delivery_not_restricted_flag and the restrictions
+	if !programSegmentationFlag {
+		componentCount := int(reader.read(8))
+		reader.skip(componentCount * 48) // component_tag, reserved, pts_offset
+	}
+	if segmentationDurationFlag {
+		segmentationDescriptor.SegmentationDuration = reader.read(40)
+	}
+	segmentationDescriptor.SegmentationUpidType = uint8(reader.read(8))
+	upidLength := int(reader.read(8))
+	segmentationDescriptor.SegmentationUpid = reader.readBytes(upidLength)
+	segmentationDescriptor.SegmentationTypeId = uint8(reader.read(8))
+	segmentationDescriptor.SegmentNum = uint8(reader.read(8))
+	segmentationDescriptor.SegmentsExpected = uint8(reader.read(8))
+
+	// Older versions of SCTE-35 do not have the sub-segment fields.
+	if reader.offset()+2 <= end {
+		switch segmentationDescriptor.SegmentationTypeId {
+		case 0x34, 0x36, 0x38, 0x3a, 0x44, 0x46:
+			segmentationDescriptor.SubSegmentNum = uint8(reader.read(8))
+			segmentationDescriptor.SubSegmentsExpected = uint8(reader.read(8))
+		}
+	}
+
+	return segmentationDescriptor
+}
+
+/**
+ * Parses the XML form of a splice_info_section.
+ * @param {!Node} elem The scte35:SpliceInfoSection XML element.
+ * @return {SpliceInfoSection}
+ */
+func parseSpliceInfoSectionXml(elem element) (*SpliceInfoSection, error) {
+	var err error
+	scte35Attr := scte35AttrParser{elem: elem}
+
+	spliceInfoSection := &SpliceInfoSection{
+		SegmentationDescriptors: make([]*SegmentationDescriptor, 0),
+	}
+	spliceInfoSection.ProtocolVersion = uint8(scte35Attr.parseUint("protocolVersion", 8, 0))
+	spliceInfoSection.PtsAdjustment = scte35Attr.parseUint("ptsAdjustment", 33, 0)
+	spliceInfoSection.Tier = uint16(scte35Attr.parseUint("tier", 12, 0xfff))
+
+	for _, child := range elem.Children() {
+		switch child.Name() {
+		case "SpliceNull":
+			spliceInfoSection.SpliceCommandType = SPLICE_NULL
+		case "SpliceInsert":
+			spliceInfoSection.SpliceCommandType = SPLICE_INSERT
+			spliceInfoSection.SpliceInsert, err = parseSpliceInsertXml(child)
+		case "TimeSignal":
+			spliceInfoSection.SpliceCommandType = TIME_SIGNAL
+			spliceInfoSection.TimeSignal = &TimeSignal{PtsTime: ^uint64(0)}
+			if spliceTime := findScte35Element(child, "SpliceTime"); spliceTime != nil {
+				spliceInfoSection.TimeSignal.PtsTime, err = scte35AttrParser{elem: spliceTime}.ptsTime()
+			}
+		case "SegmentationDescriptor":
+			var segmentationDescriptor *SegmentationDescriptor
+			if segmentationDescriptor, err = parseSegmentationDescriptorXml(child); err == nil {
+				spliceInfoSection.SegmentationDescriptors = append(spliceInfoSection.SegmentationDescriptors, segmentationDescriptor)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if scte35Attr.err != nil {
+		return nil, scte35Attr.err
+	}
+	return spliceInfoSection, nil
+}
+
+func parseSpliceInsertXml(elem element) (*SpliceInsert, error) {
+	var err error
+	scte35Attr := scte35AttrParser{elem: elem}
+
+	spliceInsert := &SpliceInsert{PtsTime: ^uint64(0)}
+	spliceInsert.SpliceEventId = uint32(scte35Attr.parseUint("spliceEventId", 32, 0))
+	spliceInsert.SpliceEventCancelIndicator = scte35Attr.parseBool("spliceEventCancelIndicator")
+	spliceInsert.OutOfNetworkIndicator = scte35Attr.parseBool("outOfNetworkIndicator")
+	spliceInsert.SpliceImmediateFlag = scte35Attr.parseBool("spliceImmediateFlag")
+	spliceInsert.UniqueProgramId = uint16(scte35Attr.parseUint("uniqueProgramId", 16, 0))
+	spliceInsert.AvailNum = uint8(scte35Attr.parseUint("availNum", 8, 0))
+	spliceInsert.AvailsExpected = uint8(scte35Attr.parseUint("availsExpected", 8, 0))
+
+	for _, child := range elem.Children() {
+		switch child.Name() {
+		case "Program":
+			if spliceTime := findScte35Element(child, "SpliceTime"); spliceTime != nil {
+				spliceInsert.PtsTime, err = scte35AttrParser{elem: spliceTime}.ptsTime()
+			}
+		case "BreakDuration":
+			breakDurationAttr := scte35AttrParser{elem: child}
+			spliceInsert.BreakDuration = &BreakDuration{
+				AutoReturn: breakDurationAttr.parseBool("autoReturn"),
+				Duration:   breakDurationAttr.parseUint("duration", 33, 0),
+			}
+			err = breakDurationAttr.err
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if scte35Attr.err != nil {
+		return nil, scte35Attr.err
+	}
+	return spliceInsert, nil
+}
+
+func parseSegmentationDescriptorXml(elem element) (*SegmentationDescriptor, error) {
+	scte35Attr := scte35AttrParser{elem: elem}
+
+	segmentationDescriptor := &SegmentationDescriptor{
+		SegmentationUpid: make([]byte, 0),
+	}
+	segmentationDescriptor.SegmentationEventId = uint32(scte35Attr.parseUint("segmentationEventId", 32, 0))
+	segmentationDescriptor.SegmentationEventCancelIndicator = scte35Attr.parseBool("segmentationEventCancelIndicator")
+	segmentationDescriptor.SegmentationDuration = scte35Attr.parseUint("segmentationDuration", 40, ^uint64(0))
+	segmentationDescriptor.SegmentationTypeId = uint8(scte35Attr.parseUint("segmentationTypeId", 8, 0))
+	segmentationDescriptor.SegmentNum = uint8(scte35Attr.parseUint("segmentNum", 8, 0))
+	segmentationDescriptor.SegmentsExpected = uint8(scte35Attr.parseUint("segmentsExpected", 8, 0))
+	segmentationDescriptor.SubSegmentNum = uint8(scte35Attr.parseUint("subSegmentNum", 8, 0))
+	segmentationDescriptor.SubSegmentsExpected = uint8(scte35Attr.parseUint("subSegmentsExpected", 8, 0))
+
+	if upid := findScte35Element(elem, "SegmentationUpid"); upid != nil {
+		upidAttr := scte35AttrParser{elem: upid}
+		segmentationDescriptor.SegmentationUpidType = uint8(upidAttr.parseUint("segmentationUpidType", 8, 0))
+		if upidAttr.err != nil {
+			return nil, upidAttr.err
+		}
+
+		text, _ := upid.Text()
+		text = strings.TrimSpace(text)
+		format, _ := upid.Attribute("segmentationUpidFormat")
+
+		var err error
+		switch format {
+		case "text":
+			segmentationDescriptor.SegmentationUpid = []byte(text)
+		case "base-64":
+			segmentationDescriptor.SegmentationUpid, err = base64.StdEncoding.DecodeString(text)
+		default:
+			segmentationDescriptor.SegmentationUpid, err = hex.DecodeString(text)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("SegmentationUpid %q is not valid %s", text, format)
+		}
+	}
+
+	if scte35Attr.err != nil {
+		return nil, scte35Attr.err
+	}
+	return segmentationDescriptor, nil
+}
+
+/**
+ * Parses the attributes of an SCTE-35 XML element, keeping the first error.
+ */
+type scte35AttrParser struct {
+	elem element
+
+	err error
+}
+
+func (scte35Attr *scte35AttrParser) parseUint(name string, bits int, defaultValue uint64) uint64 {
+	value, ok := scte35Attr.elem.Attribute(name)
+	if !ok {
+		return defaultValue
+	}
+	n, err := strconv.ParseUint(strings.TrimSpace(value), 10, bits)
+	if err != nil && scte35Attr.err == nil {
+		scte35Attr.err = fmt.Errorf("%s@%s %q is not a %d-bit unsigned integer", scte35Attr.elem.Name(), name, value, bits)
+	}
+	return n
+}
+
+func (scte35Attr *scte35AttrParser) parseBool(name string) bool {
+	value, _ := scte35Attr.elem.Attribute(name)
+	value = strings.TrimSpace(value)
+	return value == "true" || value == "1"
+}
+
+func (scte35Attr scte35AttrParser) ptsTime() (uint64, error) {
+	ptsTime := scte35Attr.parseUint("ptsTime", 33, ^uint64(0))
+	return ptsTime, scte35Attr.err
+}
+
+/**
+ * Reads big-endian bit fields. Reading past the end sets err and returns 0.
+ */
+type bitReader struct {
+	data []byte
+
+	/** The position, in bits. */
+	position int
+
+	err error
+}
+
+func newBitReader(data []byte) *bitReader {
+	return &bitReader{data: data}
+}
+
+func (reader *bitReader) read(bits int) uint64 {
+	if reader.position+bits > 8*len(reader.data) {
+		reader.err = errors.New("unexpected end of data")
+		reader.position = 8 * len(reader.data)
+		return 0
+	}
+
+	value := uint64(0)
+	for i := 0; i < bits; i++ {
+		bit := (reader.data[reader.position/8] >> uint(7-reader.position%8)) & 1
+		value = value<<1 | uint64(bit)
+		reader.position++
+	}
+	return value
+}
+
+func (reader *bitReader) readFlag() bool {
+	return reader.read(1) == 1
+}
+
+func (reader *bitReader) readBytes(n int) []byte {
+	if reader.position%8 != 0 || reader.offset()+n > len(reader.data) {
+		reader.err = errors.New("unexpected end of data")
+		reader.position = 8 * len(reader.data)
+		return make([]byte, 0)
+	}
+	b := reader.data[reader.offset() : reader.offset()+n]
+	reader.position += 8 * n
+	return b
+}
+
+func (reader *bitReader) skip(bits int) {
+	if reader.position+bits > 8*len(reader.data) {
+		reader.err = errors.New("unexpected end of data")
+		reader.position = 8 * len(reader.data)
+		return
+	}
+	reader.position += bits
+}
+
+/**
+ * The position, in whole bytes.
+ */
+func (reader *bitReader) offset() int {
+	return reader.position / 8
+}
+
+func (reader *bitReader) seek(offset int) {
+	if offset > len(reader.data) {
+		reader.err = errors.New("unexpected end of data")
+		offset = len(reader.data)
+	}
+	reader.position = 8 * offset
+}
+
+/**
+ * Computes the MPEG-2 CRC-32 of |data|, which is 0 if |data| ends with its
+ * own valid CRC.
+ */
+func mpeg2Crc32(data []byte) uint32 {
+	crc := uint32(0xffffffff)
+	for _, b := range data {
+		crc ^= uint32(b) << 24
+		for i := 0; i < 8; i++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
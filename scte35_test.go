@@ -0,0 +1,40 @@
+package mpd
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"testing"
+)
+
+func FuzzParseSpliceInfoSection(f *testing.F) {
+	for _, section := range []string{
+		"/DAlAAAAAABkAP/wFAUAAAABf+/+AA247P4AKTLgAAEAAAAAjGpnZw==",
+		"/DAyAAAAAAAAAP/wBQb+AAQesAAcAhpDVUVJAAAAB3//AAAUmXAMBEFCQ0Q0AQIAAB0SBuA=",
+	} {
+		data, err := base64.StdEncoding.DecodeString(section)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		parse := func(data []byte) {
+			if spliceInfoSection, err := ParseSpliceInfoSection(data); err == nil {
+				spliceInfoSection.SpliceTime()
+				spliceInfoSection.Duration()
+			}
+		}
+		parse(data)
+
+		// Fix section_length and CRC_32 so that the splice commands and
+		// descriptors are reached.
+		if len(data) >= 7 {
+			data = append([]byte(nil), data...)
+			sectionLength := uint16(len(data)-3) & 0x0fff
+			binary.BigEndian.PutUint16(data[1:], binary.BigEndian.Uint16(data[1:])&0xf000|sectionLength)
+			binary.BigEndian.PutUint32(data[len(data)-4:], mpeg2Crc32(data[:len(data)-4]))
+			parse(data)
+		}
+	})
+}
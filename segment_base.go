@@ -1,22 +1,19 @@
 package mpd
 
-import (
-	"github.com/moovweb/gokogiri/xml"
-)
-
 type SegmentBase struct {
 	/**
 	 * This not an actual XML attribute of SegmentBase. It is inherited from the
-	 * SegmentBase's parent Representation.
-	 * @type {*BaseUrl}
+	 * Representation the SegmentBase applies to, even when the SegmentBase is declared
+	 * on an AdaptationSet or Period.
+	 * @type {!Array.<!BaseUrl>}
 	 */
-	BaseUrl *BaseUrl
+	BaseUrls []*BaseUrl
 
 	/** @type {?number} */
 	Timescale int
 
-	/** @type {?number} */
-	PresentationTimeOffset int
+	/** @type {number} */
+	PresentationTimeOffset uint64 // xs:unsignedLong
 
 	/** @type {Range} */
 	IndexRange *Range
@@ -33,15 +30,7 @@ type SegmentBase struct {
  * @param {*} parent The parent object.
  * @param {!Node} elem The SegmentBase XML element.
  */
-func (segmentBase *SegmentBase) Parse(parent Node, elem xml.Node) {
-
-	switch p := parent.(type) {
-	case *AdaptationSet:
-	case *Period:
-	case *Representation:
-		segmentBase.BaseUrl = p.BaseUrl
-	}
-
+func (segmentBase *SegmentBase) Parse(state *parseState, parent Node, elem element) error {
 	var err error
 
 	// When parsing attributes and child elements fallback to |this| to provide
@@ -49,10 +38,12 @@ func (segmentBase *SegmentBase) Parse(parent Node, elem xml.Node) {
 	// default values from its constructor, and if |this| was cloned from a
 	// higher level SegmentBase then |this| will have values from that
 	// SegmentBase.
-	segmentBase.Timescale, _ = parseAttrAsPositiveInt(elem, "timescale")
+	if timescale, err := parseAttrAsPositiveInt(elem, "timescale"); err == nil {
+		segmentBase.Timescale = timescale
+	}
 
-	if segmentBase.PresentationTimeOffset, err = parseAttrAsNonNegativeInt(elem, "presentationTimeOffset"); err != nil {
-		segmentBase.PresentationTimeOffset = -1
+	if presentationTimeOffset, err := parseAttrAsUnsignedLong(elem, "presentationTimeOffset"); err == nil {
+		segmentBase.PresentationTimeOffset = presentationTimeOffset
 	}
 
 	// Parse attributes.
@@ -60,13 +51,22 @@ func (segmentBase *SegmentBase) Parse(parent Node, elem xml.Node) {
 
 	// Parse simple child elements.
 	ok := false
-	if segmentBase.RepresentationIndex, ok = parseChild(segmentBase, elem, RepresentationIndex_TAG_NAME).(*RepresentationIndex); ok == false {
+	var child Node
+	if child, err = parseChild(state, segmentBase, elem, RepresentationIndex_TAG_NAME); err != nil {
+		return err
+	}
+	if segmentBase.RepresentationIndex, ok = child.(*RepresentationIndex); ok == false {
 		segmentBase.RepresentationIndex = nil
 	}
 
-	if segmentBase.Initialization, ok = parseChild(segmentBase, elem, Initialization_TAG_NAME).(*Initialization); ok == false {
+	if child, err = parseChild(state, segmentBase, elem, Initialization_TAG_NAME); err != nil {
+		return err
+	}
+	if segmentBase.Initialization, ok = child.(*Initialization); ok == false {
 		segmentBase.Initialization = nil
 	}
+
+	return nil
 }
 
 /**
@@ -86,7 +86,7 @@ func (segmentBase SegmentBase) Clone() Node {
 	}
 
 	clone := &SegmentBase{
-		BaseUrl:                segmentBase.BaseUrl,
+		BaseUrls:               segmentBase.BaseUrls,
 		Timescale:              segmentBase.Timescale,
 		PresentationTimeOffset: segmentBase.PresentationTimeOffset,
 		IndexRange:             segmentBase.IndexRange.Clone(),
@@ -98,5 +98,7 @@ func (segmentBase SegmentBase) Clone() Node {
 }
 
 func NewSegmentBase() Node {
-	return &SegmentBase{}
+	return &SegmentBase{
+		Timescale: 1,
+	}
 }
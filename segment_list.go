@@ -1,16 +1,13 @@
 package mpd
 
-import (
-	"github.com/moovweb/gokogiri/xml"
-)
-
 type SegmentList struct {
 	/**
 	 * This not an actual XML attribute of SegmentList. It is inherited from the
-	 * SegmentList's parent Representation.
-	 * @type {BaseUrl}
+	 * Representation the SegmentList applies to, even when the SegmentList is declared
+	 * on an AdaptationSet or Period.
+	 * @type {!Array.<!BaseUrl>}
 	 */
-	BaseUrl *BaseUrl
+	BaseUrls []*BaseUrl
 
 	/** @type {?number} */
 	Timescale uint32 // xs:unsignedInt
@@ -42,18 +39,13 @@ type SegmentList struct {
  * @param {*} parent The parent object.
  * @param {!Node} elem The SegmentList XML element.
  */
-func (segmentList *SegmentList) Parse(parent Node, elem xml.Node) {
+func (segmentList *SegmentList) Parse(state *parseState, parent Node, elem element) error {
 	var err error
 
-	switch p := parent.(type) {
-	case *AdaptationSet:
-	case *Period:
-	case *Representation:
-		segmentList.BaseUrl = p.BaseUrl
-	}
-
 	// Parse attributes.
-	segmentList.Timescale, _ = parseAttrAsUnsignedInt(elem, "timescale")
+	if timescale, err := parseAttrAsUnsignedInt(elem, "timescale"); err == nil && timescale > 0 {
+		segmentList.Timescale = timescale
+	}
 
 	segmentList.PresentationTimeOffset, _ = parseAttrAsUnsignedLong(elem, "presentationTimeOffset")
 
@@ -66,13 +58,25 @@ func (segmentList *SegmentList) Parse(parent Node, elem xml.Node) {
 	}
 
 	// Parse simple children
-	segmentList.Initialization = parseChild(segmentList, elem, Initialization_TAG_NAME).(*Initialization)
+	ok := false
+	var child Node
+	if child, err = parseChild(state, segmentList, elem, Initialization_TAG_NAME); err != nil {
+		return err
+	}
+	if segmentList.Initialization, ok = child.(*Initialization); ok == false {
+		segmentList.Initialization = nil
+	}
 
-	children := parseChildren(segmentList, elem, SegmentUrl_TAG_NAME)
+	children, err := parseChildren(state, segmentList, elem, SegmentUrl_TAG_NAME)
+	if err != nil {
+		return err
+	}
 	segmentList.SegmentUrls = make([]*SegmentUrl, len(children))
 	for i, child := range children {
 		segmentList.SegmentUrls[i] = child.(*SegmentUrl)
 	}
+
+	return nil
 }
 
 /**
@@ -82,12 +86,14 @@ func (segmentList *SegmentList) Parse(parent Node, elem xml.Node) {
 func (segmentList SegmentList) Clone() Node {
 	clone := &SegmentList{}
 
-	clone.BaseUrl = segmentList.BaseUrl
+	clone.BaseUrls = segmentList.BaseUrls
 	clone.Timescale = segmentList.Timescale
 	clone.PresentationTimeOffset = segmentList.PresentationTimeOffset
 	clone.SegmentDuration = segmentList.SegmentDuration
 	clone.StartNumber = segmentList.StartNumber
-	clone.Initialization = segmentList.Initialization.Clone().(*Initialization)
+	if segmentList.Initialization != nil {
+		clone.Initialization = segmentList.Initialization.Clone().(*Initialization)
+	}
 
 	for _, segmentUrl := range segmentList.SegmentUrls {
 		clone.SegmentUrls = append(clone.SegmentUrls, segmentUrl.Clone().(*SegmentUrl))
@@ -97,5 +103,7 @@ func (segmentList SegmentList) Clone() Node {
 }
 
 func NewSegmentList() Node {
-	return &SegmentList{}
+	return &SegmentList{
+		Timescale: 1,
+	}
 }
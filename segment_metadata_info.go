@@ -1,7 +1,11 @@
 package mpd
 
 type SegmentMetadataInfo struct {
-	Url string
+	/**
+	 * The candidate URLs, one per BaseURL, in DVB order.
+	 * @type {!Array.<string>}
+	 */
+	Urls []string
 
 	StartByte int
 
@@ -10,7 +14,7 @@ type SegmentMetadataInfo struct {
 
 func NewSegmentMetadataInfo() SegmentMetadataInfo {
 	return SegmentMetadataInfo{
-		Url:       "",
+		Urls:      make([]string, 0),
 		StartByte: 0,
 		EndByte:   -1,
 	}
@@ -1,26 +1,39 @@
 package mpd
 
+import (
+	"math"
+	"time"
+)
+
+/**
+ * The EndTime of a segment which continues to the end of the stream. No real
+ * time is this late, so such a segment sorts after every other time.
+ * @const {time.Duration}
+ */
+const UNBOUNDED_END_TIME time.Duration = math.MaxInt64
+
 type SegmentReference struct {
 
 	/**
-	 * The segment's ID.
+	 * The segment's ID, i.e., its start time in timescale units.
 	 * @const {number}
 	 */
 	Id uint64
 
 	/**
-	 * The time, in seconds, that the segment begins.
-	 * @const {number}
+	 * The time that the segment begins, in media time, i.e., before the
+	 * StreamInfo's TimestampOffset is applied.
+	 * @const {time.Duration}
 	 */
-	StartTime uint64
+	StartTime time.Duration
 
 	/**
-	 * The time, in seconds, that the segment ends. The segment ends immediately
-	 * before this time. A null value indicates that the segment continues to the
-	 * end of the stream.
-	 * @const {?number}
+	 * The time that the segment ends. The segment ends immediately before this
+	 * time. UNBOUNDED_END_TIME indicates that the segment continues to the end
+	 * of the stream.
+	 * @const {?time.Duration}
 	 */
-	EndTime uint64
+	EndTime time.Duration
 
 	/**
 	 * The position of the segment's first byte.
@@ -36,16 +49,13 @@ type SegmentReference struct {
 	EndByte int
 
 	/**
-	 * The segment's location.
-	 * @const {!string}
+	 * The segment's candidate locations, one per BaseURL, in DVB order.
+	 * @const {!Array.<string>}
 	 */
-	Url string
+	Urls []string
 }
 
-func NewSegmentReference(id, startTime, endTime uint64, startByte, endByte int, url string) SegmentReference {
-	// assert((endTime == -1) || (startTime <= endTime), "startTime should be <= endTime")
-	assert((endTime == 0) || (startTime <= endTime))
-
+func NewSegmentReference(id uint64, startTime, endTime time.Duration, startByte, endByte int, urls []string) SegmentReference {
 	return SegmentReference{
 		Id: id,
 
@@ -57,6 +67,6 @@ func NewSegmentReference(id, startTime, endTime uint64, startByte, endByte int,
 
 		EndByte: endByte,
 
-		Url: url,
+		Urls: urls,
 	}
 }
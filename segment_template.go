@@ -1,15 +1,13 @@
 package mpd
 
-import (
-	"github.com/moovweb/gokogiri/xml"
-)
+import "time"
 
 type SegmentTemplate struct {
 	/** @type {?number} */
 	Timescale uint32 // xs:unsignedInt
 
-	/** @type {?number} */
-	PresentationTimeOffset int // xs:unsignedLong
+	/** @type {number} */
+	PresentationTimeOffset uint64 // xs:unsignedLong
 
 	/**
 	 * Each segment's duration. This value is never zero.
@@ -32,6 +30,13 @@ type SegmentTemplate struct {
 	/** @type {?string} */
 	InitializationUrlTemplate string
 
+	/**
+	 * How much earlier than announced by the MPD segments are available, or
+	 * math.MaxInt64 for "INF".
+	 * @type {time.Duration}
+	 */
+	AvailabilityTimeOffset time.Duration
+
 	/** @type {SegmentTimeline} */
 	Timeline *SegmentTimeline
 }
@@ -41,14 +46,16 @@ type SegmentTemplate struct {
  * @param {*} parent The parent object.
  * @param {!Node} elem The SegmentTemplate XML element.
  */
-func (segmentTemplate *SegmentTemplate) Parse(parent Node, elem xml.Node) {
+func (segmentTemplate *SegmentTemplate) Parse(state *parseState, parent Node, elem element) error {
 	var err error
 
 	// Parse attributes.
-	segmentTemplate.Timescale, _ = parseAttrAsUnsignedInt(elem, "timescale")
+	if timescale, err := parseAttrAsUnsignedInt(elem, "timescale"); err == nil && timescale > 0 {
+		segmentTemplate.Timescale = timescale
+	}
 
-	if segmentTemplate.PresentationTimeOffset, err = parseAttrAsNonNegativeInt(elem, "presentationTimeOffset"); err != nil {
-		segmentTemplate.PresentationTimeOffset = -1
+	if presentationTimeOffset, err := parseAttrAsUnsignedLong(elem, "presentationTimeOffset"); err == nil {
+		segmentTemplate.PresentationTimeOffset = presentationTimeOffset
 	}
 
 	if segmentTemplate.SegmentDuration, err = parseAttrAsPositiveInt(elem, "duration"); err != nil {
@@ -71,11 +78,21 @@ func (segmentTemplate *SegmentTemplate) Parse(parent Node, elem xml.Node) {
 		segmentTemplate.InitializationUrlTemplate = ""
 	}
 
+	if availabilityTimeOffset, err := parseAttrAsAvailabilityTimeOffset(elem); err == nil {
+		segmentTemplate.AvailabilityTimeOffset = availabilityTimeOffset
+	}
+
 	// Parse hierarchical children.
 	ok := false
-	if segmentTemplate.Timeline, ok = parseChild(segmentTemplate, elem, SegmentTimeline_TAG_NAME).(*SegmentTimeline); ok == false {
+	var child Node
+	if child, err = parseChild(state, segmentTemplate, elem, SegmentTimeline_TAG_NAME); err != nil {
+		return err
+	}
+	if segmentTemplate.Timeline, ok = child.(*SegmentTimeline); ok == false {
 		segmentTemplate.Timeline = nil
 	}
+
+	return nil
 }
 
 func (segmentTemplate SegmentTemplate) Clone() Node {
@@ -93,10 +110,13 @@ func (segmentTemplate SegmentTemplate) Clone() Node {
 		MediaUrlTemplate:          segmentTemplate.MediaUrlTemplate,
 		IndexUrlTemplate:          segmentTemplate.IndexUrlTemplate,
 		InitializationUrlTemplate: segmentTemplate.InitializationUrlTemplate,
+		AvailabilityTimeOffset:    segmentTemplate.AvailabilityTimeOffset,
 		Timeline:                  timeLine,
 	}
 }
 
 func NewSegmentTemplate() Node {
-	return &SegmentTemplate{}
+	return &SegmentTemplate{
+		Timescale: 1,
+	}
 }
@@ -1,11 +1,5 @@
 package mpd
 
-import (
-	"fmt"
-
-	"github.com/moovweb/gokogiri/xml"
-)
-
 type SegmentTimePoint struct {
 	/**
 	 * The start time of the media segment, in seconds, relative to the beginning
@@ -26,12 +20,11 @@ type SegmentTimePoint struct {
  * @param {!SegmentTimeline} parent The parent SegmentTimeline.
  * @param {!Node} elem The SegmentTimePoint XML element.
  */
-func (segmentTimePoint *SegmentTimePoint) Parse(parent Node, elem xml.Node) {
+func (segmentTimePoint *SegmentTimePoint) Parse(state *parseState, parent Node, elem element) error {
 	var err error
 	// Parse attributes.
 	if segmentTimePoint.StartTime, err = parseAttrAsUnsignedLong(elem, "t"); err != nil {
 		segmentTimePoint.StartTime = ^uint64(0)
-		fmt.Printf("%s\r\n", err.Error())
 	}
 
 	if segmentTimePoint.Duration, err = parseAttrAsUnsignedLong(elem, "d"); err != nil {
@@ -40,6 +33,8 @@ func (segmentTimePoint *SegmentTimePoint) Parse(parent Node, elem xml.Node) {
 	if segmentTimePoint.Repeat, err = parseAttrAsNonNegativeInt(elem, "r"); err != nil {
 		segmentTimePoint.Repeat = -1
 	}
+
+	return nil
 }
 
 /**
@@ -1,9 +1,5 @@
 package mpd
 
-import (
-	"github.com/moovweb/gokogiri/xml"
-)
-
 type SegmentTimeline struct {
 	/** @type {!Array.<!SegmentTimePoint>} */
 	TimePoints []*SegmentTimePoint
@@ -14,13 +10,18 @@ type SegmentTimeline struct {
  * @param {!SegmentTemplate} parent The parent SegmentTemplate.
  * @param {!Node} elem The SegmentTimeline XML element.
  */
-func (segmentTimeline *SegmentTimeline) Parse(parent Node, elem xml.Node) {
-	children := parseChildren(segmentTimeline, elem, SegmentTimePoint_TAG_NAME)
+func (segmentTimeline *SegmentTimeline) Parse(state *parseState, parent Node, elem element) error {
+	children, err := parseChildren(state, segmentTimeline, elem, SegmentTimePoint_TAG_NAME)
+	if err != nil {
+		return err
+	}
 	segmentTimeline.TimePoints = make([]*SegmentTimePoint, len(children))
 
 	for i, child := range children {
 		segmentTimeline.TimePoints[i] = (child).(*SegmentTimePoint)
 	}
+
+	return nil
 }
 
 /**
@@ -1,9 +1,5 @@
 package mpd
 
-import (
-	"github.com/moovweb/gokogiri/xml"
-)
-
 type SegmentUrl struct {
 	/** @type {string} */
 	MediaUrl string
@@ -17,12 +13,14 @@ type SegmentUrl struct {
  * @param {!SegmentList} parent The parent SegmentList.
  * @param {!Node} elem The SegmentUrl XML element.
  */
-func (segmentUrl *SegmentUrl) Parse(parent Node, elem xml.Node) {
+func (segmentUrl *SegmentUrl) Parse(state *parseState, parent Node, elem element) error {
 
 	// Parse attributes.
 	segmentUrl.MediaUrl, _ = parseAttrAsString(elem, "media")
 
 	segmentUrl.MediaRange, _ = parseAttrAsRange(elem, "mediaRange")
+
+	return nil
 }
 
 /**
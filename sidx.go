@@ -0,0 +1,419 @@
+package mpd
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	/**
+	 * @const {string}
+	 */
+	SIDX_BOX_TYPE = "sidx"
+
+	/**
+	 * How deeply sidx boxes may refer to other sidx boxes.
+	 * @const {number}
+	 */
+	MAX_SIDX_DEPTH = 8
+
+	/**
+	 * How many sidx boxes a hierarchy of sidx boxes may refer to in all. Each
+	 * may have to be downloaded, and several may refer to the same bytes.
+	 * @const {number}
+	 */
+	MAX_SIDX_BOXES = 1000
+)
+
+/**
+ * A Segment Index box.
+ * @see ISO/IEC 14496-12:2012 section 8.16.3
+ */
+type Sidx struct {
+	/** @type {number} */
+	Version uint8
+
+	/** @type {number} */
+	Flags uint32
+
+	/** @type {number} */
+	ReferenceId uint32
+
+	/** @type {number} */
+	Timescale uint32
+
+	/**
+	 * The presentation time of the first referenced subsegment, in Timescale
+	 * units.
+	 * @type {number}
+	 */
+	EarliestPresentationTime uint64
+
+	/**
+	 * The distance from the first byte after the box to the first byte of the
+	 * first referenced subsegment.
+	 * @type {number}
+	 */
+	FirstOffset uint64
+
+	/** @type {!Array.<!SidxReference>} */
+	References []SidxReference
+}
+
+type SidxReference struct {
+	/**
+	 * True if the reference is to another sidx box, false if it is to media.
+	 * @type {boolean}
+	 */
+	ReferencesSidx bool
+
+	/** @type {number} */
+	ReferencedSize uint32
+
+	/**
+	 * The subsegment's duration, in Timescale units.
+	 * @type {number}
+	 */
+	SubsegmentDuration uint32
+
+	/** @type {boolean} */
+	StartsWithSap bool
+
+	/** @type {number} */
+	SapType uint8
+
+	/** @type {number} */
+	SapDeltaTime uint32
+}
+
+/**
+ * Parses a single sidx box, including its header.
+ * @param {Uint8Array} box
+ * @return {Sidx}
+ */
+func ParseSidx(box []byte) (*Sidx, error) {
+	headerSize, size, err := parseBoxHeader(box)
+	if err != nil {
+		return nil, err
+	}
+	if size != uint64(len(box)) {
+		return nil, fmt.Errorf("sidx box size is %d, but %d bytes are available", size, len(box))
+	}
+	if string(box[4:8]) != SIDX_BOX_TYPE {
+		return nil, fmt.Errorf("box type is %q, not %q", box[4:8], SIDX_BOX_TYPE)
+	}
+
+	// version (1) + flags (3) + reference_ID (4) + timescale (4)
+	offset := headerSize
+	if len(box) < offset+12 {
+		return nil, errors.New("sidx box is truncated")
+	}
+
+	sidx := &Sidx{
+		Version:     box[offset],
+		Flags:       uint32(box[offset+1])<<16 | uint32(box[offset+2])<<8 | uint32(box[offset+3]),
+		ReferenceId: binary.BigEndian.Uint32(box[offset+4:]),
+		Timescale:   binary.BigEndian.Uint32(box[offset+8:]),
+		References:  make([]SidxReference, 0),
+	}
+	offset += 12
+
+	if sidx.Version > 1 {
+		return nil, fmt.Errorf("sidx box version %d is not supported", sidx.Version)
+	}
+	if sidx.Timescale == 0 {
+		return nil, errors.New("sidx timescale is 0")
+	}
+
+	// earliest_presentation_time and first_offset are 64 bits wide in version
+	// 1 boxes, then reserved (2) + reference_count (2).
+	if sidx.Version == 0 {
+		if len(box) < offset+12 {
+			return nil, errors.New("sidx box is truncated")
+		}
+		sidx.EarliestPresentationTime = uint64(binary.BigEndian.Uint32(box[offset:]))
+		sidx.FirstOffset = uint64(binary.BigEndian.Uint32(box[offset+4:]))
+		offset += 8
+	} else {
+		if len(box) < offset+20 {
+			return nil, errors.New("sidx box is truncated")
+		}
+		sidx.EarliestPresentationTime = binary.BigEndian.Uint64(box[offset:])
+		sidx.FirstOffset = binary.BigEndian.Uint64(box[offset+8:])
+		offset += 16
+	}
+
+	count := int(binary.BigEndian.Uint16(box[offset+2:]))
+	offset += 4
+
+	if count*12 != len(box)-offset {
+		return nil, fmt.Errorf("sidx box has %d references, but %d bytes are available", count, len(box)-offset)
+	}
+	for i := 0; i < count; i++ {
+		typeAndSize := binary.BigEndian.Uint32(box[offset:])
+		sap := binary.BigEndian.Uint32(box[offset+8:])
+		sidx.References = append(sidx.References, SidxReference{
+			ReferencesSidx:     typeAndSize>>31 == 1,
+			ReferencedSize:     typeAndSize & 0x7fffffff,
+			SubsegmentDuration: binary.BigEndian.Uint32(box[offset+4:]),
+			StartsWithSap:      sap>>31 == 1,
+			SapType:            uint8(sap >> 28 & 0x07),
+			SapDeltaTime:       sap & 0x0fffffff,
+		})
+		offset += 12
+	}
+
+	return sidx, nil
+}
+
+/**
+ * Parses the size of an ISO BMFF box.
+ * @param {Uint8Array} data The box, followed by anything.
+ * @return {number} The size of the box's header.
+ * @return {number} The size of the box, which may exceed len(|data|).
+ */
+func parseBoxHeader(data []byte) (int, uint64, error) {
+	if len(data) < 8 {
+		return 0, 0, errors.New("box header is truncated")
+	}
+
+	size := uint64(binary.BigEndian.Uint32(data))
+	headerSize := 8
+	switch size {
+	case 0:
+		// The box extends to the end of the file.
+		size = uint64(len(data))
+	case 1:
+		if len(data) < 16 {
+			return 0, 0, errors.New("box header is truncated")
+		}
+		size = binary.BigEndian.Uint64(data[8:])
+		headerSize = 16
+	}
+
+	if size < uint64(headerSize) {
+		return 0, 0, fmt.Errorf("box size %d is too small", size)
+	}
+	return headerSize, size, nil
+}
+
+/**
+ * Finds the first top-level box of the given type.
+ * @param {Uint8Array} data
+ * @param {string} boxType
+ * @return {number} The box's offset within |data|.
+ * @return {Uint8Array} The box, including its header.
+ */
+func findBox(data []byte, boxType string) (int, []byte, error) {
+	offset := 0
+	for offset < len(data) {
+		_, size, err := parseBoxHeader(data[offset:])
+		if err != nil {
+			return 0, nil, err
+		}
+		if size > uint64(len(data)-offset) {
+			return 0, nil, fmt.Errorf("%q box is truncated", data[offset+4:offset+8])
+		}
+
+		if string(data[offset+4:offset+8]) == boxType {
+			return offset, data[offset : offset+int(size)], nil
+		}
+		offset += int(size)
+	}
+
+	return 0, nil, fmt.Errorf("no %q box", boxType)
+}
+
+/**
+ * Fetches the stream's segment index, as given by SegmentIndexInfo, and
+ * replaces its SegmentIndex with the subsegments the sidx box refers to.
+ * sidx boxes which refer to other sidx boxes are followed. Used for
+ * SegmentBase streams, whose SegmentIndex the MpdProcessor cannot create.
+ * @param {Fetcher} fetcher
+ */
+func (streamInfo *StreamInfo) LoadSegmentIndex(ctx context.Context, fetcher Fetcher) error {
+	segmentIndexInfo := streamInfo.SegmentIndexInfo
+	if segmentIndexInfo == nil {
+		return errors.New("stream has no segment index info")
+	}
+
+	var byteRange *Range
+	if segmentIndexInfo.EndByte >= 0 {
+		byteRange = newRange(segmentIndexInfo.StartByte, segmentIndexInfo.EndByte)
+	}
+
+	res, err := fetchAny(ctx, fetcher, segmentIndexInfo.Urls, byteRange)
+	if err != nil {
+		return fmt.Errorf("failed to download segment index: %s", err)
+	}
+
+	loader := &sidxLoader{
+		ctx:        ctx,
+		fetcher:    fetcher,
+		indexUrl:   res.Url,
+		mediaUrls:  streamInfo.MediaUrls,
+		references: make([]*SegmentReference, 0),
+	}
+	if byteRange == nil {
+		byteRange = newRange(0, len(res.Data)-1)
+	}
+	if err := loader.load(res.Data, uint64(byteRange.Begin), 0); err != nil {
+		return err
+	}
+
+	segmentIndex := NewSegmentIndex(loader.references)
+	if err := segmentIndex.Validate(); err != nil {
+		return err
+	}
+
+	streamInfo.SegmentIndexData = res.Data
+	streamInfo.SegmentIndex = &segmentIndex
+	return nil
+}
+
+/**
+ * Collects the subsegments of a hierarchy of sidx boxes.
+ */
+type sidxLoader struct {
+	/** @type {context.Context} */
+	ctx context.Context
+
+	/** @type {Fetcher} */
+	fetcher Fetcher
+
+	/**
+	 * The URL of the file the sidx boxes are in.
+	 * @type {string}
+	 */
+	indexUrl string
+
+	/** @type {!Array.<string>} */
+	mediaUrls []string
+
+	/** @type {!Array.<!SegmentReference>} */
+	references []*SegmentReference
+
+	/**
+	 * How many references, to subsegments or to sidx boxes, have been visited.
+	 * @type {number}
+	 */
+	visited int
+
+	/**
+	 * How many sidx boxes have been referred to.
+	 * @type {number}
+	 */
+	sidxBoxes int
+}
+
+/**
+ * Appends the subsegments of the first sidx box in |data|.
+ * @param {Uint8Array} data
+ * @param {number} dataOffset The position of |data| within the file.
+ * @param {number} depth How many sidx boxes refer to this one.
+ */
+func (loader *sidxLoader) load(data []byte, dataOffset uint64, depth int) error {
+	if depth > MAX_SIDX_DEPTH {
+		return fmt.Errorf("more than %d nested sidx boxes", MAX_SIDX_DEPTH)
+	}
+
+	boxOffset, box, err := findBox(data, SIDX_BOX_TYPE)
+	if err != nil {
+		return err
+	}
+	sidx, err := ParseSidx(box)
+	if err != nil {
+		return err
+	}
+
+	// The references are contiguous, starting at the anchor point, i.e., the
+	// first byte after the sidx box, plus first_offset. Every position must fit
+	// in an int, and the checks are written so that they cannot overflow.
+	const maxOffset = uint64(math.MaxInt)
+	anchor := dataOffset + uint64(boxOffset) + uint64(len(box))
+	if anchor > maxOffset || sidx.FirstOffset > maxOffset-anchor {
+		return fmt.Errorf("sidx first_offset %d is out of range", sidx.FirstOffset)
+	}
+	offset := anchor + sidx.FirstOffset
+	presentationTime := sidx.EarliestPresentationTime
+
+	for _, reference := range sidx.References {
+		// Every reference costs work, even one to a sidx box which adds no
+		// subsegments.
+		if loader.visited >= MAX_SEGMENT_REFERENCES {
+			return fmt.Errorf("more than %d sidx references", MAX_SEGMENT_REFERENCES)
+		}
+		loader.visited++
+
+		if reference.ReferencedSize == 0 {
+			return errors.New("sidx reference has no size")
+		}
+		if offset > maxOffset || uint64(reference.ReferencedSize)-1 > maxOffset-offset {
+			return fmt.Errorf("sidx reference at offset %d is out of range", offset)
+		}
+		end := offset + uint64(reference.ReferencedSize) - 1
+
+		if reference.ReferencesSidx {
+			if loader.sidxBoxes >= MAX_SIDX_BOXES {
+				return fmt.Errorf("more than %d sidx boxes", MAX_SIDX_BOXES)
+			}
+			loader.sidxBoxes++
+
+			var child []byte
+			if offset >= dataOffset && end-dataOffset < uint64(len(data)) {
+				// The referenced sidx box was retrieved along with this one.
+				child = data[offset-dataOffset : end-dataOffset+1]
+			} else {
+				res, err := loader.fetcher.Fetch(loader.ctx, loader.indexUrl, newRange(int(offset), int(end)))
+				if err != nil {
+					return fmt.Errorf("failed to download sidx box: %s", err)
+				}
+				child = res.Data
+			}
+
+			if err := loader.load(child, offset, depth+1); err != nil {
+				return err
+			}
+		} else {
+			segmentReference := NewSegmentReference(
+				presentationTime,
+				scaleTime(presentationTime, sidx.Timescale),
+				scaleTime(presentationTime+uint64(reference.SubsegmentDuration), sidx.Timescale),
+				int(offset),
+				int(end),
+				loader.mediaUrls)
+			loader.references = append(loader.references, &segmentReference)
+		}
+
+		offset = end + 1
+		presentationTime += uint64(reference.SubsegmentDuration)
+	}
+
+	return nil
+}
+
+/**
+ * Loads the SegmentIndex of every StreamInfo of ManifestInfo which has a
+ * segment index URL but no SegmentIndex, i.e., of the SegmentBase streams.
+ * Must be called after Process.
+ * @param {Fetcher} fetcher
+ * @return {!Array.<Diagnostic>} The streams whose SegmentIndex could not be
+ *     loaded.
+ */
+func (mpdProcessor *MpdProcessor) LoadSegmentIndexes(ctx context.Context, fetcher Fetcher) []Diagnostic {
+	mpdProcessor.reporter = newDiagnosticReporter(mpdProcessor.Logger)
+
+	forEachStreamInfo(mpdProcessor.ManifestInfo, func(path string, periodInfo PeriodInfo, streamInfo *StreamInfo) {
+		if streamInfo.SegmentIndex != nil || streamInfo.SegmentIndexInfo == nil {
+			return
+		}
+		if err := streamInfo.LoadSegmentIndex(ctx, fetcher); err != nil {
+			mpdProcessor.report(SEVERITY_ERROR, DIAGNOSTIC_INVALID_SIDX, path, "%s", err)
+		}
+	})
+
+	return mpdProcessor.reporter.diagnostics
+}
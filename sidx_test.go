@@ -0,0 +1,207 @@
+package mpd
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testBox builds an ISO BMFF box.
+func testBox(boxType string, payload ...[]byte) []byte {
+	size := 8
+	for _, p := range payload {
+		size += len(p)
+	}
+	b := make([]byte, 8, size)
+	binary.BigEndian.PutUint32(b, uint32(size))
+	copy(b[4:], boxType)
+	for _, p := range payload {
+		b = append(b, p...)
+	}
+	return b
+}
+
+func TestLoadSegmentIndex(t *testing.T) {
+	// A sidx box with one reference per entry of |sizes|; negative sizes are
+	// references to sidx boxes.
+	sidx := func(version uint8, earliestPresentationTime uint64, sizes []int) []byte {
+		payload := []byte{version, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0x03, 0xe8}
+		if version == 0 {
+			payload = append(payload, make([]byte, 8)...)
+			binary.BigEndian.PutUint32(payload[12:], uint32(earliestPresentationTime))
+		} else {
+			payload = append(payload, make([]byte, 16)...)
+			binary.BigEndian.PutUint64(payload[12:], earliestPresentationTime)
+		}
+		payload = append(payload, 0, 0, 0, byte(len(sizes)))
+		for _, size := range sizes {
+			reference := make([]byte, 12)
+			if size < 0 {
+				binary.BigEndian.PutUint32(reference, 1<<31|uint32(-size))
+			} else {
+				binary.BigEndian.PutUint32(reference, uint32(size))
+			}
+			binary.BigEndian.PutUint32(reference[4:], 2000)
+			binary.BigEndian.PutUint32(reference[8:], 1<<31|1<<28)
+			payload = append(payload, reference...)
+		}
+		return testBox("sidx", payload)
+	}
+
+	// ftyp | moov | sidx -> (sidx | mdat | mdat), (sidx | mdat | mdat)
+	mdats := [][]byte{testBox("mdat", make([]byte, 100)), testBox("mdat", make([]byte, 200)), testBox("mdat", make([]byte, 300)), testBox("mdat", make([]byte, 400))}
+	child1 := sidx(0, 0, []int{len(mdats[0]), len(mdats[1])})
+	child2 := sidx(0, 4000, []int{len(mdats[2]), len(mdats[3])})
+	top := sidx(1, 0, []int{-(len(child1) + len(mdats[0]) + len(mdats[1])), -(len(child2) + len(mdats[2]) + len(mdats[3]))})
+
+	file := append(testBox("ftyp", []byte("isom")), testBox("moov")...)
+	indexStart := len(file)
+	file = append(file, top...)
+	mediaStart := len(file) + len(child1)
+	for i, child := range [][]byte{child1, child2} {
+		file = append(file, child...)
+		file = append(file, mdats[2*i]...)
+		file = append(file, mdats[2*i+1]...)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "video.mp4", time.Time{}, strings.NewReader(string(file)))
+	}))
+	defer server.Close()
+
+	content := fmt.Sprintf(`<MPD type="static" mediaPresentationDuration="PT8S">
+  <Period>
+    <AdaptationSet mimeType="video/mp4">
+      <Representation id="video" bandwidth="1000000">
+        <BaseURL>video.mp4</BaseURL>
+        <SegmentBase indexRange="%d-%d">
+          <Initialization range="0-%d"/>
+        </SegmentBase>
+      </Representation>
+    </AdaptationSet>
+  </Period>
+</MPD>`, indexStart, indexStart+len(top)-1, indexStart-1)
+
+	mpd, _, err := ParseMpdBytes([]byte(content), server.URL+"/manifest.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mpdProcessor := NewMpdProcessor()
+	mpdProcessor.Process(mpd)
+	if diagnostics := mpdProcessor.LoadSegmentIndexes(context.Background(), NewHttpFetcher(server.Client())); len(diagnostics) != 0 {
+		t.Fatalf("unexpected diagnostics %v", diagnostics)
+	}
+
+	streamInfo := mpdProcessor.ManifestInfo.PeriodInfos[0].StreamSetInfos[0].StreamInfos[0]
+	if streamInfo.SegmentIndex == nil || streamInfo.SegmentIndex.Length() != 4 {
+		t.Fatalf("expecting 4 segment references, got %v", streamInfo.SegmentIndex)
+	}
+	if string(streamInfo.SegmentIndexData) != string(top) {
+		t.Errorf("SegmentIndexData is not the sidx box")
+	}
+
+	startByte := mediaStart
+	for i, reference := range streamInfo.SegmentIndex.References {
+		if reference.StartTime != time.Duration(i)*2*time.Second || reference.EndTime != time.Duration(i+1)*2*time.Second {
+			t.Errorf("reference %d: unexpected times %s-%s", i, reference.StartTime, reference.EndTime)
+		}
+		if i == 2 {
+			startByte += len(child2)
+		}
+		if reference.StartByte != startByte || reference.EndByte != startByte+len(mdats[i])-1 {
+			t.Errorf("reference %d: expecting bytes %d-%d, got %d-%d", i, startByte, startByte+len(mdats[i])-1, reference.StartByte, reference.EndByte)
+		}
+		if len(reference.Urls) != 1 || reference.Urls[0] != server.URL+"/video.mp4" {
+			t.Errorf("reference %d: unexpected urls %v", i, reference.Urls)
+		}
+		startByte += len(mdats[i])
+	}
+
+	if _, err := ParseSidx(top[:len(top)-1]); err == nil {
+		t.Errorf("expecting an error for a truncated sidx box")
+	}
+
+	// A first_offset which wraps the offsets around is rejected.
+	hostile := sidx(1, 0, []int{-10})
+	binary.BigEndian.PutUint64(hostile[28:], -uint64(len(hostile))-5)
+	hostileStreamInfo := NewStreamInfo()
+	hostileStreamInfo.SegmentIndexInfo = &SegmentMetadataInfo{Urls: []string{"http://example.com/video.mp4"}, StartByte: 0, EndByte: len(hostile) - 1}
+	if err := hostileStreamInfo.LoadSegmentIndex(context.Background(), testFetcher{"http://example.com/video.mp4": hostile}); err == nil {
+		t.Errorf("expecting an error for an out of range first_offset")
+	}
+
+	// Every copy of a sidx box at one level refers to all copies at the next
+	// level, down to empty sidx boxes, so that MAX_SIDX_DEPTH levels would
+	// take 8^8 downloads without adding a single subsegment.
+	const copies = 8
+	refersTo := func(box []byte) []int {
+		sizes := make([]int, copies)
+		for i := range sizes {
+			sizes[i] = -len(box)
+		}
+		return sizes
+	}
+	leaf := sidx(1, 0, nil)
+	toLeaf := sidx(1, 0, refersTo(leaf))
+	toNode := sidx(1, 0, refersTo(toLeaf))
+	fanOut := append([]byte(nil), toNode...)
+	for level := 1; level <= MAX_SIDX_DEPTH; level++ {
+		for j := 0; j < copies; j++ {
+			var box []byte
+			switch {
+			case level == MAX_SIDX_DEPTH:
+				box = leaf
+			case level == MAX_SIDX_DEPTH-1:
+				box = append([]byte(nil), toLeaf...)
+			default:
+				box = append([]byte(nil), toNode...)
+			}
+			if level < MAX_SIDX_DEPTH {
+				// The next level starts after the remaining copies of this one.
+				binary.BigEndian.PutUint64(box[28:], uint64((copies-1-j)*len(box)))
+			}
+			fanOut = append(fanOut, box...)
+		}
+	}
+	fanOutFetcher := &countingFetcher{Fetcher: testFetcher{"http://example.com/video.mp4": fanOut}}
+	fanOutStreamInfo := NewStreamInfo()
+	fanOutStreamInfo.SegmentIndexInfo = &SegmentMetadataInfo{Urls: []string{"http://example.com/video.mp4"}, StartByte: 0, EndByte: len(toNode) - 1}
+	if err := fanOutStreamInfo.LoadSegmentIndex(context.Background(), fanOutFetcher); err == nil {
+		t.Errorf("expecting an error for too many sidx boxes")
+	}
+	if fanOutFetcher.count > MAX_SIDX_BOXES+1 {
+		t.Errorf("expecting at most %d downloads, got %d", MAX_SIDX_BOXES+1, fanOutFetcher.count)
+	}
+}
+
+// countingFetcher counts the downloads of another Fetcher.
+type countingFetcher struct {
+	Fetcher
+	count int
+}
+
+func (fetcher *countingFetcher) Fetch(ctx context.Context, url string, byteRange *Range) (*FetchResponse, error) {
+	fetcher.count++
+	return fetcher.Fetcher.Fetch(ctx, url, byteRange)
+}
+
+func FuzzParseSidx(f *testing.F) {
+	// A version 0 and a version 1 sidx box, each with one reference.
+	reference := []byte{0, 0, 0, 100, 0, 0, 0x07, 0xd0, 0x90, 0, 0, 0}
+	f.Add(testBox("sidx", []byte{0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0x03, 0xe8}, make([]byte, 8), []byte{0, 0, 0, 1}, reference))
+	f.Add(testBox("sidx", []byte{1, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0x03, 0xe8}, make([]byte, 16), []byte{0, 0, 0, 1}, reference))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ParseSidx(data)
+
+		// Nested sidx boxes are looked up in the same data.
+		streamInfo := NewStreamInfo()
+		streamInfo.SegmentIndexInfo = &SegmentMetadataInfo{Urls: []string{"http://example.com/video.mp4"}, StartByte: 0, EndByte: -1}
+		streamInfo.LoadSegmentIndex(context.Background(), testFetcher{"http://example.com/video.mp4": data})
+	})
+}
@@ -1,5 +1,7 @@
 package mpd
 
+import "time"
+
 /**
  * The next unique ID to assign to a StreamSetInfo.
  */
@@ -11,17 +13,17 @@ type StreamInfo struct {
 	Id string
 
 	/**
-	 * An offset, in seconds, to apply to each timestamp within each media
-	 * segment that's put in buffer.
+	 * An offset to apply to each timestamp within each media segment that's put
+	 * in buffer.
 	 */
-	TimestampOffset int
+	TimestampOffset time.Duration
 
 	/**
 	 * Indicates the stream's current segment's start time, i.e., its live-edge.
 	 * This value is non-null if the stream is both live and available;
 	 * otherwise, this value is null.
 	 */
-	CurrentSegmentStartTime uint64
+	CurrentSegmentStartTime time.Duration
 
 	/**
 	 * Bandwidth required, in bits per second, to assure uninterrupted playback,
@@ -38,10 +40,55 @@ type StreamInfo struct {
 
 	Codecs string
 
-	MediaUrl string
+	/**
+	 * The candidate media URLs, one per BaseURL, in DVB order.
+	 * @type {!Array.<string>}
+	 */
+	MediaUrls []string
 
 	Enabled bool
 
+	/**
+	 * The Representation's ContentProtection elements, including those it
+	 * inherits from its AdaptationSet.
+	 * @type {!Array.<!ContentProtection>}
+	 */
+	ContentProtections []*ContentProtection
+
+	/**
+	 * The Representation's Roles, including those it inherits from its
+	 * AdaptationSet. The same goes for Accessibilities, Ratings and
+	 * Viewpoints.
+	 * @type {!Array.<!Descriptor>}
+	 */
+	Roles []*Descriptor
+
+	/** @type {!Array.<!Descriptor>} */
+	Accessibilities []*Descriptor
+
+	/** @type {!Array.<!Descriptor>} */
+	Ratings []*Descriptor
+
+	/** @type {!Array.<!Descriptor>} */
+	Viewpoints []*Descriptor
+
+	/**
+	 * The EssentialProperties of the AdaptationSet followed by those of the
+	 * Representation. The same goes for SupplementalProperties.
+	 * @type {!Array.<!Descriptor>}
+	 */
+	EssentialProperties []*Descriptor
+
+	/** @type {!Array.<!Descriptor>} */
+	SupplementalProperties []*Descriptor
+
+	/**
+	 * The InbandEventStreams of the AdaptationSet followed by those of the
+	 * Representation.
+	 * @type {!Array.<!EventStream>}
+	 */
+	InbandEventStreams []*EventStream
+
 	/**
 	 * The stream's SegmentIndex metadata.
 	 * @see {StreamInfo.isAvailable}
@@ -64,6 +111,13 @@ type StreamInfo struct {
 	/** @type {ArrayBuffer} */
 	SegmentInitializationData []byte
 
+	/**
+	 * What the initialization segment says about the stream.
+	 * @see StreamInfo.LoadInitInfo
+	 * @type {InitInfo}
+	 */
+	InitInfo *InitInfo
+
 	/** @private {ArrayBuffer} */
 	SegmentIndexData []byte
 }
@@ -81,12 +135,21 @@ func NewStreamInfo() StreamInfo {
 		Height:                    -1,
 		MimeType:                  "",
 		Codecs:                    "",
-		MediaUrl:                  "",
+		MediaUrls:                 make([]string, 0),
 		Enabled:                   true,
+		ContentProtections:        make([]*ContentProtection, 0),
+		Roles:                     make([]*Descriptor, 0),
+		Accessibilities:           make([]*Descriptor, 0),
+		Ratings:                   make([]*Descriptor, 0),
+		Viewpoints:                make([]*Descriptor, 0),
+		EssentialProperties:       make([]*Descriptor, 0),
+		SupplementalProperties:    make([]*Descriptor, 0),
+		InbandEventStreams:        make([]*EventStream, 0),
 		SegmentIndexInfo:          nil,
 		SegmentInitializationInfo: nil,
 		SegmentIndex:              nil,
 		SegmentInitializationData: nil,
+		InitInfo:                  nil,
 		SegmentIndexData:          nil,
 	}
 }
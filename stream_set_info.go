@@ -22,14 +22,45 @@ type StreamSetInfo struct {
 	/** @type {!Array.<!StreamInfo>} */
 	StreamInfos []*StreamInfo
 
-	/** @type {!Array.<!DrmSchemeInfo>} */
-	// DrmSchemes = DrmSchemeInfo[]
+	/**
+	 * The DRM schemes common to all of the StreamInfos. Empty if the streams
+	 * are unencrypted or if they have no scheme in common.
+	 * @type {!Array.<!DrmSchemeInfo>}
+	 */
+	DrmSchemes []DrmSchemeInfo
 
 	/** @type {string} */
 	Lang string
 
 	/** @type {boolean} */
 	Main bool
+
+	/**
+	 * The AdaptationSet's descriptors.
+	 * @type {!Array.<!Descriptor>}
+	 */
+	Roles []*Descriptor
+
+	/** @type {!Array.<!Descriptor>} */
+	Accessibilities []*Descriptor
+
+	/** @type {!Array.<!Descriptor>} */
+	Ratings []*Descriptor
+
+	/** @type {!Array.<!Descriptor>} */
+	Viewpoints []*Descriptor
+
+	/** @type {!Array.<!Descriptor>} */
+	EssentialProperties []*Descriptor
+
+	/** @type {!Array.<!Descriptor>} */
+	SupplementalProperties []*Descriptor
+
+	/**
+	 * The AdaptationSet's InbandEventStreams.
+	 * @type {!Array.<!EventStream>}
+	 */
+	InbandEventStreams []*EventStream
 }
 
 func NewStreamSetInfo() StreamSetInfo {
@@ -41,10 +72,18 @@ func NewStreamSetInfo() StreamSetInfo {
 		ContentType: mapset.NewSet(),
 		StreamInfos: make([]*StreamInfo, 0),
 
-		/** @type {!Array.<!DrmSchemeInfo>} */
-		// DrmSchemes: make(DrmSchemeInfo[], 0),
+		DrmSchemes: make([]DrmSchemeInfo, 0),
 
 		Lang: "",
 		Main: false,
+
+		Roles:                  make([]*Descriptor, 0),
+		Accessibilities:        make([]*Descriptor, 0),
+		Ratings:                make([]*Descriptor, 0),
+		Viewpoints:             make([]*Descriptor, 0),
+		EssentialProperties:    make([]*Descriptor, 0),
+		SupplementalProperties: make([]*Descriptor, 0),
+
+		InbandEventStreams: make([]*EventStream, 0),
 	}
 }
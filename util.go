@@ -1,5 +1,7 @@
 package mpd
 
+import "time"
+
 func Max(a, b int) int {
 	if a >= b {
 		return a
@@ -20,3 +22,36 @@ func Abs(a int) int {
 	}
 	return a
 }
+
+/**
+ * Converts |value|, in units of 1/|timescale| seconds, to a time.Duration.
+ * The conversion is exact to the nanosecond, so times computed from the
+ * unscaled values do not drift.
+ * @param {number} value
+ * @param {number} timescale Must be positive.
+ * @return {time.Duration}
+ */
+func scaleTime(value uint64, timescale uint32) time.Duration {
+	seconds := value / uint64(timescale)
+	remainder := value % uint64(timescale)
+
+	// |remainder| < 2^32, so the product cannot overflow.
+	return time.Duration(seconds)*time.Second + time.Duration(remainder*uint64(time.Second)/uint64(timescale))
+}
+
+/**
+ * Converts |value| from units of 1/|timescale| seconds to units of
+ * 1/|newTimescale| seconds, rounding down.
+ * @param {number} value
+ * @param {number} timescale Must be positive.
+ * @param {number} newTimescale
+ * @return {number}
+ */
+func rescaleTime(value uint64, timescale uint32, newTimescale uint32) uint64 {
+	seconds := value / uint64(timescale)
+	remainder := value % uint64(timescale)
+
+	// |remainder| and |newTimescale| are both < 2^32, so the product cannot
+	// overflow.
+	return seconds*uint64(newTimescale) + remainder*uint64(newTimescale)/uint64(timescale)
+}
@@ -0,0 +1,414 @@
+package mpd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	/**
+	 * The namespace of the xlink:href and xlink:actuate attributes.
+	 * @const {string}
+	 */
+	XLINK_NAMESPACE = "http://www.w3.org/1999/xlink"
+
+	/**
+	 * An xlink:href which removes the element instead of fetching anything.
+	 * @const {string}
+	 */
+	XLINK_RESOLVE_TO_ZERO = "urn:mpeg:dash:resolve-to-zero:2013"
+
+	/**
+	 * The xlink:actuate values. onRequest is the default.
+	 * @const {string}
+	 */
+	XLINK_ACTUATE_ON_LOAD    = "onLoad"
+	XLINK_ACTUATE_ON_REQUEST = "onRequest"
+
+	/**
+	 * How many remote documents may be chained, i.e., how deeply remote
+	 * elements may themselves refer to remote elements.
+	 * @const {number}
+	 */
+	DEFAULT_XLINK_MAX_DEPTH = 5
+
+	/**
+	 * How many remote documents may be downloaded in all. Each remote document
+	 * may refer to several more, so limiting the depth alone still allows
+	 * exponentially many downloads.
+	 * @const {number}
+	 */
+	DEFAULT_XLINK_MAX_FETCHES = 100
+)
+
+/**
+ * The remote element an MPD element refers to.
+ * @see ISO/IEC 23009-1:2014 section 5.5
+ */
+type Xlink struct {
+	/**
+	 * The remote element's URL, resolved against the document the element
+	 * was found in, or XLINK_RESOLVE_TO_ZERO.
+	 * @type {string}
+	 */
+	Href string
+
+	/**
+	 * XLINK_ACTUATE_ON_LOAD or XLINK_ACTUATE_ON_REQUEST.
+	 * @type {string}
+	 */
+	Actuate string
+}
+
+/**
+ * Parses the xlink attributes of an element. The xlinkResolver has already
+ * made xlink:href absolute.
+ * @param {!Node} elem The XML element.
+ * @return {Xlink} The Xlink, or nil if the element has no xlink:href.
+ */
+func parseXlink(elem element) *Xlink {
+	href, ok := elem.AttributeNS(XLINK_NAMESPACE, "href")
+	if !ok {
+		return nil
+	}
+
+	xlink := &Xlink{
+		Href:    strings.TrimSpace(href),
+		Actuate: XLINK_ACTUATE_ON_REQUEST,
+	}
+	if actuate, ok := elem.AttributeNS(XLINK_NAMESPACE, "actuate"); ok && strings.TrimSpace(actuate) == XLINK_ACTUATE_ON_LOAD {
+		xlink.Actuate = XLINK_ACTUATE_ON_LOAD
+	}
+
+	return xlink
+}
+
+/**
+ * @param {string} name A tag name.
+ * @return {boolean} True if elements with the given tag name may be remote.
+ */
+func isXlinkElement(name string) bool {
+	return name == Period_TAG_NAME || name == AdaptationSet_TAG_NAME || name == EventStream_TAG_NAME
+}
+
+/**
+ * Replaces remote elements in an XML tree with the elements they refer to.
+ */
+type xlinkResolver struct {
+	/** @type {context.Context} */
+	ctx context.Context
+
+	/** @type {Fetcher} */
+	fetcher Fetcher
+
+	/** @type {*parseState} */
+	state *parseState
+
+	/** @type {number} */
+	maxDepth int
+
+	/** @type {number} */
+	maxFetches int
+
+	/**
+	 * How many remote documents have been downloaded.
+	 * @type {number}
+	 */
+	fetches int
+}
+
+/**
+ * @param {*parseState} state
+ * @return {!xlinkResolver} A resolver which downloads through the
+ *     MpdParser's Fetcher.
+ */
+func (mpdParser *MpdParser) newXlinkResolver(ctx context.Context, state *parseState) *xlinkResolver {
+	maxDepth := mpdParser.MaxXlinkDepth
+	if maxDepth <= 0 {
+		maxDepth = DEFAULT_XLINK_MAX_DEPTH
+	}
+
+	maxFetches := mpdParser.MaxXlinkFetches
+	if maxFetches <= 0 {
+		maxFetches = DEFAULT_XLINK_MAX_FETCHES
+	}
+
+	return &xlinkResolver{
+		ctx:        ctx,
+		fetcher:    mpdParser.Fetcher,
+		state:      state,
+		maxDepth:   maxDepth,
+		maxFetches: maxFetches,
+	}
+}
+
+/**
+ * Resolves, recursively, the children of |elem| which have
+ * xlink:actuate="onLoad". An element which cannot be resolved is kept, and
+ * a diagnostic is reported. The xlink:href of every element which is kept is
+ * made absolute, so that it can be resolved later.
+ * @param {!Node} elem The XML element.
+ * @param {string} documentUrl The location of the document |elem| is in.
+ * @param {!Array.<string>} chain The remote documents |elem| is nested in.
+ */
+func (resolver *xlinkResolver) resolveChildren(elem element, documentUrl string, chain []string) {
+	parent, ok := elem.(*xmlElement)
+	if !ok {
+		return
+	}
+
+	children := make([]element, 0, len(parent.children))
+	indexes := make(map[string]int)
+	for _, child := range parent.children {
+		name := child.Name()
+		id, _ := parseAttrAsString(child, "id")
+		resolver.state.push(pathSegment(name, indexes[name], id))
+		indexes[name]++
+
+		href, hasHref := child.AttributeNS(XLINK_NAMESPACE, "href")
+		href = strings.TrimSpace(href)
+		if hasHref && href != XLINK_RESOLVE_TO_ZERO {
+			href = resolveUrl(documentUrl, href)
+			setAttributeNS(child, XLINK_NAMESPACE, "href", href)
+		}
+
+		actuate, _ := child.AttributeNS(XLINK_NAMESPACE, "actuate")
+		if !hasHref || !isXlinkElement(name) || strings.TrimSpace(actuate) != XLINK_ACTUATE_ON_LOAD {
+			resolver.resolveChildren(child, documentUrl, chain)
+			children = append(children, child)
+			resolver.state.pop()
+			continue
+		}
+
+		if resolver.fetcher == nil && href != XLINK_RESOLVE_TO_ZERO {
+			// Remote resolution is disabled. The element keeps its Xlink, so it
+			// can be resolved on demand.
+			resolver.state.report(SEVERITY_INFO, DIAGNOSTIC_XLINK_FAILED, "not resolving xlink:href %s without a fetcher", href)
+			resolver.resolveChildren(child, documentUrl, chain)
+			children = append(children, child)
+			resolver.state.pop()
+			continue
+		}
+
+		remote, err := resolver.resolve(name, href, chain)
+		if err != nil {
+			resolver.state.report(SEVERITY_WARNING, DIAGNOSTIC_XLINK_FAILED, "failed to resolve xlink:href %s: %s", href, err)
+			resolver.resolveChildren(child, documentUrl, chain)
+			children = append(children, child)
+		} else {
+			children = append(children, remote...)
+		}
+		resolver.state.pop()
+	}
+
+	parent.children = children
+}
+
+/**
+ * Replaces the value of an existing attribute.
+ */
+func setAttributeNS(elem element, namespace string, name string, value string) {
+	if elem, ok := elem.(*xmlElement); ok {
+		for i := range elem.attributes {
+			if elem.attributes[i].namespace == namespace && elem.attributes[i].name == name {
+				elem.attributes[i].value = value
+			}
+		}
+	}
+}
+
+/**
+ * Fetches the remote elements at |href| and resolves their own onLoad
+ * xlinks.
+ * @param {string} name The tag name of the referring element. Remote
+ *     elements with a different tag name are ignored.
+ * @param {string} href The absolute URL of the remote elements.
+ * @param {!Array.<string>} chain The remote documents the referring element
+ *     is nested in.
+ * @return {!Array.<!Node>} The remote XML elements, which are empty for
+ *     XLINK_RESOLVE_TO_ZERO.
+ */
+func (resolver *xlinkResolver) resolve(name string, href string, chain []string) ([]element, error) {
+	if href == XLINK_RESOLVE_TO_ZERO {
+		return make([]element, 0), nil
+	}
+
+	for _, url := range chain {
+		if url == href {
+			return nil, errors.New("xlink cycle")
+		}
+	}
+	if len(chain) >= resolver.maxDepth {
+		return nil, fmt.Errorf("more than %d nested xlinks", resolver.maxDepth)
+	}
+	if resolver.fetcher == nil {
+		return nil, errors.New("no fetcher")
+	}
+	if resolver.fetches >= resolver.maxFetches {
+		return nil, fmt.Errorf("more than %d xlink downloads", resolver.maxFetches)
+	}
+	resolver.fetches++
+
+	res, err := resolver.fetcher.Fetch(resolver.ctx, href, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapper, err := parseXmlFragment(res.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	chain = append(chain[:len(chain):len(chain)], href)
+	resolver.resolveChildren(wrapper, res.Url, chain)
+
+	remote := make([]element, 0, len(wrapper.Children()))
+	for _, child := range wrapper.Children() {
+		if child.Name() != name {
+			resolver.state.report(SEVERITY_WARNING, DIAGNOSTIC_XLINK_FAILED, "ignoring remote %s element in place of a %s", child.Name(), name)
+			continue
+		}
+		remote = append(remote, child)
+	}
+	return remote, nil
+}
+
+/**
+ * Parses a remote element document, which may hold any number of top-level
+ * elements.
+ * @param {ArrayBuffer} data
+ * @return {!Node} A wrapper XML element whose children are the top-level
+ *     elements.
+ */
+func parseXmlFragment(data []byte) (element, error) {
+	text := strings.TrimPrefix(string(data), "\uFEFF")
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "<?xml") {
+		if end := strings.Index(text, "?>"); end >= 0 {
+			text = text[end+2:]
+		}
+	}
+
+	return parseXml([]byte("<xlink>" + text + "</xlink>"))
+}
+
+/**
+ * Resolves a remote element which has xlink:actuate="onRequest". |node|, a
+ * Period, an AdaptationSet or a Period's EventStream of |mpd|, is replaced in
+ * |mpd| by the Nodes it refers to, which may be none.
+ * @param {!Mpd} mpd
+ * @param {!Node} node
+ * @return {!Array.<!Node>} The Nodes which replaced |node|.
+ */
+func (mpdParser *MpdParser) ResolveXlink(ctx context.Context, mpd *Mpd, node Node) ([]Node, []Diagnostic, error) {
+	var xlink *Xlink
+	var name string
+	var parent Node
+
+	// The path of |parent| and of |node| within |mpd|, for reporting.
+	var parentPath []string
+	var nodeSegment string
+
+	switch obj := node.(type) {
+	case *Period:
+		xlink, name, parent = obj.Xlink, Period_TAG_NAME, mpd
+		for i, period := range mpd.Periods {
+			if period == obj {
+				nodeSegment = pathSegment(Period_TAG_NAME, i, period.Id)
+			}
+		}
+	case *AdaptationSet:
+		xlink, name = obj.Xlink, AdaptationSet_TAG_NAME
+		for i, period := range mpd.Periods {
+			for j, adaptationSet := range period.AdaptationSets {
+				if adaptationSet == obj {
+					parent = period
+					parentPath = []string{pathSegment(Period_TAG_NAME, i, period.Id)}
+					nodeSegment = pathSegment(AdaptationSet_TAG_NAME, j, adaptationSet.Id)
+				}
+			}
+		}
+	case *EventStream:
+		xlink, name = obj.Xlink, EventStream_TAG_NAME
+		for i, period := range mpd.Periods {
+			for j, eventStream := range period.EventStreams {
+				if eventStream == obj {
+					parent = period
+					parentPath = []string{pathSegment(Period_TAG_NAME, i, period.Id)}
+					nodeSegment = pathSegment(EventStream_TAG_NAME, j, "")
+				}
+			}
+		}
+	}
+
+	if xlink == nil {
+		return nil, nil, errors.New("node has no xlink:href")
+	}
+	if parent == nil {
+		return nil, nil, errors.New("node is not part of the mpd")
+	}
+
+	state := newParseState(mpdParser.Logger)
+	for _, segment := range parentPath {
+		state.push(segment)
+	}
+	resolver := mpdParser.newXlinkResolver(ctx, state)
+
+	state.push(nodeSegment)
+	remote, err := resolver.resolve(name, xlink.Href, nil)
+	if err != nil {
+		state.report(SEVERITY_ERROR, DIAGNOSTIC_XLINK_FAILED, "failed to resolve xlink:href %s: %s", xlink.Href, err)
+		return nil, state.reporter.diagnostics, err
+	}
+	state.pop()
+
+	nodes, err := parseChildren(state, parent, &xmlElement{children: remote}, name)
+	if err != nil {
+		return nil, state.reporter.diagnostics, err
+	}
+
+	switch obj := node.(type) {
+	case *Period:
+		periods := make([]*Period, 0, len(mpd.Periods)+len(nodes))
+		for _, period := range mpd.Periods {
+			if period != obj {
+				periods = append(periods, period)
+				continue
+			}
+			for _, child := range nodes {
+				periods = append(periods, child.(*Period))
+			}
+		}
+		mpd.Periods = periods
+	case *AdaptationSet:
+		period := parent.(*Period)
+		adaptationSets := make([]*AdaptationSet, 0, len(period.AdaptationSets)+len(nodes))
+		for _, adaptationSet := range period.AdaptationSets {
+			if adaptationSet != obj {
+				adaptationSets = append(adaptationSets, adaptationSet)
+				continue
+			}
+			for _, child := range nodes {
+				adaptationSets = append(adaptationSets, child.(*AdaptationSet))
+			}
+		}
+		period.AdaptationSets = adaptationSets
+	case *EventStream:
+		period := parent.(*Period)
+		eventStreams := make([]*EventStream, 0, len(period.EventStreams)+len(nodes))
+		for _, eventStream := range period.EventStreams {
+			if eventStream != obj {
+				eventStreams = append(eventStreams, eventStream)
+				continue
+			}
+			for _, child := range nodes {
+				eventStreams = append(eventStreams, child.(*EventStream))
+			}
+		}
+		period.EventStreams = eventStreams
+	}
+
+	return nodes, state.reporter.diagnostics, nil
+}
@@ -0,0 +1,175 @@
+package mpd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseXlink(t *testing.T) {
+	documents := map[string]string{
+		"/manifest.mpd": `<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" xmlns:xlink="http://www.w3.org/1999/xlink" type="static" mediaPresentationDuration="PT30S">
+  <Period id="remote" xlink:href="remote/period.xml" xlink:actuate="onLoad"/>
+  <Period id="zero" xlink:href="urn:mpeg:dash:resolve-to-zero:2013" xlink:actuate="onLoad"/>
+  <Period id="cycle" xlink:href="cycle.xml" xlink:actuate="onLoad"/>
+  <Period id="lazy" xlink:href="lazy.xml"/>
+</MPD>`,
+		"/remote/period.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<Period xmlns="urn:mpeg:dash:schema:mpd:2011" xmlns:xlink="http://www.w3.org/1999/xlink" id="ad1" duration="PT10S">
+  <AdaptationSet xlink:href="adaptation-set.xml" xlink:actuate="onLoad"/>
+</Period>
+<Period xmlns="urn:mpeg:dash:schema:mpd:2011" id="ad2" duration="PT10S"/>`,
+		"/remote/adaptation-set.xml": `<AdaptationSet xmlns="urn:mpeg:dash:schema:mpd:2011" id="remote-video" mimeType="video/mp4"/>`,
+		"/cycle.xml":                 `<Period xmlns="urn:mpeg:dash:schema:mpd:2011" xmlns:xlink="http://www.w3.org/1999/xlink" id="cycle" xlink:href="cycle.xml" xlink:actuate="onLoad"/>`,
+		"/lazy.xml":                  `<Period xmlns="urn:mpeg:dash:schema:mpd:2011" id="resolved" duration="PT10S"/>`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		document, ok := documents[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(document))
+	}))
+	defer server.Close()
+
+	mpdParser := NewMpdParser()
+	mpd, diagnostics, err := mpdParser.ParseMpd(context.Background(), server.URL+"/manifest.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := make([]string, 0)
+	for _, period := range mpd.Periods {
+		ids = append(ids, period.Id)
+	}
+	if !reflect.DeepEqual(ids, []string{"ad1", "ad2", "cycle", "lazy"}) {
+		t.Fatalf("unexpected periods %v", ids)
+	}
+
+	if adaptationSets := mpd.Periods[0].AdaptationSets; len(adaptationSets) != 1 || adaptationSets[0].Id != "remote-video" || adaptationSets[0].Xlink != nil {
+		t.Errorf("expecting the nested remote adaptation set to be resolved")
+	}
+
+	found := false
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Code == DIAGNOSTIC_XLINK_FAILED && diagnostic.Path == "Period[id=cycle]/Period[id=cycle]" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expecting the cycle to be reported, got %v", diagnostics)
+	}
+
+	lazy := mpd.Periods[3]
+	if lazy.Xlink == nil || lazy.Xlink.Href != server.URL+"/lazy.xml" || lazy.Xlink.Actuate != XLINK_ACTUATE_ON_REQUEST {
+		t.Fatalf("unexpected xlink %+v", lazy.Xlink)
+	}
+
+	nodes, _, err := mpdParser.ResolveXlink(context.Background(), mpd, lazy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 || len(mpd.Periods) != 4 || mpd.Periods[3].Id != "resolved" || mpd.Periods[3].Duration != 10*time.Second {
+		t.Errorf("expecting the lazy period to be replaced")
+	}
+
+	// A zero MaxXlinkDepth is the default depth.
+	mpdParser = MpdParser{Fetcher: NewHttpFetcher(server.Client())}
+	if mpd, _, err = mpdParser.ParseMpd(context.Background(), server.URL+"/manifest.mpd"); err != nil {
+		t.Fatal(err)
+	}
+	if len(mpd.Periods) != 4 || mpd.Periods[0].Id != "ad1" {
+		t.Errorf("expecting the remote period to be resolved with the default depth")
+	}
+
+	// Without a Fetcher, onLoad xlinks are kept.
+	mpdParser = MpdParser{}
+	if mpd, _, err = mpdParser.ParseMpdBytes([]byte(documents["/manifest.mpd"]), server.URL+"/manifest.mpd"); err != nil {
+		t.Fatal(err)
+	}
+	if len(mpd.Periods) != 3 || mpd.Periods[0].Xlink == nil || mpd.Periods[0].Xlink.Actuate != XLINK_ACTUATE_ON_LOAD {
+		t.Errorf("expecting the remote period to be kept, with its xlink")
+	}
+	if _, _, err := mpdParser.ParseMpd(context.Background(), server.URL+"/manifest.mpd"); err == nil {
+		t.Error("expecting ParseMpd without a fetcher to fail, got nil")
+	}
+
+	// Neither do the package-level helpers for in-memory manifests.
+	if mpd, _, err = ParseMpdBytes([]byte(documents["/manifest.mpd"]), server.URL+"/manifest.mpd"); err != nil {
+		t.Fatal(err)
+	}
+	if len(mpd.Periods) != 3 || mpd.Periods[0].Xlink == nil {
+		t.Errorf("expecting ParseMpdBytes to keep the remote period, with its xlink")
+	}
+}
+
+func TestXlinkFetchLimit(t *testing.T) {
+	// Every remote Period refers to three more, so resolving five levels
+	// would take hundreds of downloads.
+	var mutex sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests++
+		mutex.Unlock()
+
+		if r.URL.Path == "/missing.xml" {
+			http.NotFound(w, r)
+			return
+		}
+		for _, suffix := range []string{"a", "b", "c"} {
+			fmt.Fprintf(w, `<Period xmlns="urn:mpeg:dash:schema:mpd:2011" xmlns:xlink="http://www.w3.org/1999/xlink" xlink:href="%s%s.xml" xlink:actuate="onLoad"/>`,
+				strings.TrimSuffix(r.URL.Path, ".xml"), suffix)
+		}
+	}))
+	defer server.Close()
+
+	content := `<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" xmlns:xlink="http://www.w3.org/1999/xlink" type="static">
+  <Period id="remote" xlink:href="p.xml" xlink:actuate="onLoad"/>
+  <Period id="local">
+    <AdaptationSet id="lazy" xlink:href="missing.xml"/>
+  </Period>
+</MPD>`
+
+	mpdParser := MpdParser{Fetcher: NewHttpFetcher(server.Client()), MaxXlinkFetches: 10}
+	mpd, diagnostics, err := mpdParser.ParseMpdBytes([]byte(content), server.URL+"/manifest.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mutex.Lock()
+	if requests != 10 {
+		t.Errorf("expecting 10 requests, got %d", requests)
+	}
+	mutex.Unlock()
+
+	found := false
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Code == DIAGNOSTIC_XLINK_FAILED && strings.Contains(diagnostic.Message, "more than 10 xlink downloads") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expecting the download limit to be reported, got %v", diagnostics)
+	}
+
+	// Diagnostics of on-demand resolution name the element's position.
+	local := mpd.Periods[len(mpd.Periods)-1]
+	if local.Id != "local" {
+		t.Fatalf("expecting the local period to be last, got %s", local.Id)
+	}
+	_, diagnostics, err = mpdParser.ResolveXlink(context.Background(), mpd, local.AdaptationSets[0])
+	if err == nil {
+		t.Fatal("expecting an error for a missing remote element, got nil")
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Path != "Period[id=local]/AdaptationSet[id=lazy]" {
+		t.Errorf("expecting one diagnostic for Period[id=local]/AdaptationSet[id=lazy], got %v", diagnostics)
+	}
+}
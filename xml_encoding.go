@@ -0,0 +1,145 @@
+//go:build !gokogiri
+// +build !gokogiri
+
+package mpd
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+/**
+ * Parses an XML document with encoding/xml and returns its root element.
+ */
+func parseXml(data []byte) (element, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.CharsetReader = charsetReader
+
+	var root *xmlElement
+	stack := make([]*xmlElement, 0)
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			elem := &xmlElement{
+				name:       token.Name.Local,
+				namespace:  token.Name.Space,
+				attributes: make([]xmlAttribute, 0, len(token.Attr)),
+				children:   make([]element, 0),
+			}
+			for _, attr := range token.Attr {
+				if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+					continue
+				}
+				elem.attributes = append(elem.attributes, xmlAttribute{
+					namespace: attr.Name.Space,
+					name:      attr.Name.Local,
+					value:     attr.Value,
+				})
+			}
+
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, elem)
+			} else if root != nil {
+				return nil, errors.New("xml document has more than one root element")
+			} else {
+				root = elem
+			}
+			stack = append(stack, elem)
+
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+
+		case xml.CharData:
+			// Only the text preceding the first child element is kept.
+			if len(stack) > 0 {
+				elem := stack[len(stack)-1]
+				if len(elem.children) == 0 {
+					elem.text += string(token)
+					elem.hasText = true
+				}
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, errors.New("xml document has no root element")
+	}
+
+	return root, nil
+}
+
+/**
+ * The characters windows-1252 assigns to the bytes 0x80 to 0x9F. Every other
+ * byte is the ISO-8859-1 character with the same value. The five bytes
+ * windows-1252 leaves undefined keep their ISO-8859-1 control character.
+ * @const {!Array.<rune>}
+ */
+var windows1252HighBytes = [32]rune{
+	'\u20AC', '\u0081', '\u201A', '\u0192', '\u201E', '\u2026', '\u2020', '\u2021',
+	'\u02C6', '\u2030', '\u0160', '\u2039', '\u0152', '\u008D', '\u017D', '\u008F',
+	'\u0090', '\u2018', '\u2019', '\u201C', '\u201D', '\u2022', '\u2013', '\u2014',
+	'\u02DC', '\u2122', '\u0161', '\u203A', '\u0153', '\u009D', '\u017E', '\u0178',
+}
+
+/**
+ * Decodes the document from the encoding named by its XML declaration.
+ * UTF-8, US-ASCII, ISO-8859-1 and windows-1252 are supported; documents in
+ * any other encoding, which the libxml2 backend may still decode, fail to
+ * parse.
+ *
+ * @param {string} charset
+ * @param {io.Reader} input
+ * @return {io.Reader} The document as UTF-8.
+ */
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "utf-8", "utf8", "us-ascii", "ascii":
+		return input, nil
+	case "iso-8859-1", "iso8859-1", "iso_8859-1", "latin1", "l1":
+		return decodeSingleByte(input, false)
+	case "windows-1252", "cp1252":
+		return decodeSingleByte(input, true)
+	default:
+		return nil, fmt.Errorf("unsupported xml encoding %s", charset)
+	}
+}
+
+/**
+ * Converts ISO-8859-1, or windows-1252 if |windows1252| is true, to UTF-8.
+ *
+ * @param {io.Reader} input
+ * @param {boolean} windows1252
+ * @return {io.Reader}
+ */
+func decodeSingleByte(input io.Reader, windows1252 bool) (io.Reader, error) {
+	data, err := ioutil.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	buffer.Grow(len(data))
+	for _, b := range data {
+		if windows1252 && b >= 0x80 && b <= 0x9F {
+			buffer.WriteRune(windows1252HighBytes[b-0x80])
+		} else {
+			buffer.WriteRune(rune(b))
+		}
+	}
+	return &buffer, nil
+}
@@ -0,0 +1,68 @@
+//go:build gokogiri
+// +build gokogiri
+
+package mpd
+
+import (
+	"errors"
+
+	"github.com/moovweb/gokogiri"
+	"github.com/moovweb/gokogiri/xml"
+)
+
+/**
+ * Parses an XML document with libxml2 and returns its root element. The
+ * document is copied into xmlElements so that it can be freed immediately.
+ */
+func parseXml(data []byte) (element, error) {
+	doc, err := gokogiri.ParseXml(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// free the resources when done
+	defer doc.Free()
+
+	for child := doc.FirstChild(); child != nil; child = child.NextSibling() {
+		if child.NodeType() == xml.XML_ELEMENT_NODE {
+			return convertNode(child), nil
+		}
+	}
+
+	return nil, errors.New("xml document has no root element")
+}
+
+func convertNode(node xml.Node) *xmlElement {
+	elem := &xmlElement{
+		name:       node.Name(),
+		namespace:  node.Namespace(),
+		attributes: make([]xmlAttribute, 0),
+		children:   make([]element, 0),
+	}
+
+	for _, attribute := range node.AttributeList() {
+		elem.attributes = append(elem.attributes, xmlAttribute{
+			namespace: attribute.Namespace(),
+			name:      attribute.Name(),
+			value:     attribute.Value(),
+		})
+	}
+
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		switch child.NodeType() {
+		case xml.XML_ELEMENT_NODE:
+			elem.children = append(elem.children, convertNode(child))
+
+		case xml.XML_TEXT_NODE, xml.XML_CDATA_SECTION_NODE:
+			// As with encoding/xml, only the text preceding the first child
+			// element is kept. Text split by comments or CDATA sections is
+			// concatenated.
+			if len(elem.children) == 0 {
+				elem.text += child.Content()
+				elem.hasText = true
+			}
+		}
+	}
+
+	return elem
+}